@@ -1,10 +1,100 @@
 package config
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Unlimited as a ClientConfig.Limit allows every request without counting it.
+// Any negative limit is treated the same; a limit of 0 blocks all requests.
+const Unlimited = -1
 
 type ClientConfig struct {
 	Limit  int
 	Window time.Duration
+	// MaxConcurrent caps in-flight requests for the client; 0 disables the cap.
+	MaxConcurrent int
+	// SoftLimit flags requests beyond it as throttled while still allowing
+	// them up to Limit; 0 disables it.
+	SoftLimit int
+	// Burst lets the client exceed Limit by up to Burst units per window.
+	Burst int
+	// RetryMessage replaces the generic error message in 429 bodies, e.g. to
+	// point free-tier clients to an upgrade page.
+	RetryMessage string
+	// Algorithm names the algorithm counting the client's budget, such as
+	// "sliding_window" (see limiter.ParseAlgorithm); empty uses the
+	// limiter's.
+	Algorithm string
+}
+
+// QPS is the average request rate the config allows, in requests per second.
+// Unlimited configs report +Inf and configs without a positive window 0.
+func (c ClientConfig) QPS() float64 {
+	if c.Limit < 0 {
+		return math.Inf(1)
+	}
+	if c.Window <= 0 {
+		return 0
+	}
+	return float64(c.Limit) / c.Window.Seconds()
+}
+
+// String renders the limit for logs, e.g. "5 req / 60s (~0.08 req/s)".
+func (c ClientConfig) String() string {
+	if c.Limit < 0 {
+		return "unlimited"
+	}
+	window := strconv.FormatFloat(c.Window.Seconds(), 'f', -1, 64) + "s"
+	return fmt.Sprintf("%d req / %s (~%.2f req/s)", c.Limit, window, c.QPS())
+}
+
+// Validate reports settings that can only be mistakes: a limited config
+// without a positive window, a zero limit (which silently blocks the client),
+// negative Burst, MaxConcurrent or SoftLimit, and a SoftLimit above Limit.
+func (c ClientConfig) Validate() error {
+	var errs []error
+	if c.Limit == 0 {
+		errs = append(errs, errors.New("limit is 0, blocking every request"))
+	}
+	if c.Limit > 0 && c.Window <= 0 {
+		errs = append(errs, fmt.Errorf("window %v is not positive", c.Window))
+	}
+	if c.Burst < 0 {
+		errs = append(errs, fmt.Errorf("burst %d is negative", c.Burst))
+	}
+	if c.MaxConcurrent < 0 {
+		errs = append(errs, fmt.Errorf("max concurrent %d is negative", c.MaxConcurrent))
+	}
+	if c.SoftLimit < 0 {
+		errs = append(errs, fmt.Errorf("soft limit %d is negative", c.SoftLimit))
+	}
+	if c.Limit > 0 && c.SoftLimit > c.Limit {
+		errs = append(errs, fmt.Errorf("soft limit %d exceeds limit %d", c.SoftLimit, c.Limit))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks every client config, returning one error per invalid
+// client, prefixed with its name and sorted by it.
+func Validate(clients map[string]ClientConfig) error {
+	names := make([]string, 0, len(clients))
+	for name := range clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := clients[name].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("client %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 var DefaultConfig = ClientConfig{
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestClientConfigQPS(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ClientConfig
+		want float64
+	}{
+		{"per minute", ClientConfig{Limit: 5, Window: 60 * time.Second}, 5.0 / 60},
+		{"per second", ClientConfig{Limit: 10, Window: time.Second}, 10},
+		{"per hour", ClientConfig{Limit: 3600, Window: time.Hour}, 1},
+		{"sub-second", ClientConfig{Limit: 5, Window: 100 * time.Millisecond}, 50},
+		{"sub-millisecond", ClientConfig{Limit: 1, Window: 500 * time.Microsecond}, 2000},
+		{"blocked", ClientConfig{Limit: 0, Window: time.Minute}, 0},
+		{"zero window", ClientConfig{Limit: 5}, 0},
+		{"unlimited", ClientConfig{Limit: Unlimited, Window: time.Minute}, math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.QPS(); math.Abs(got-tt.want) > 1e-9 && got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClientConfigString(t *testing.T) {
+	tests := []struct {
+		cfg  ClientConfig
+		want string
+	}{
+		{ClientConfig{Limit: 5, Window: 60 * time.Second}, "5 req / 60s (~0.08 req/s)"},
+		{ClientConfig{Limit: 100, Window: time.Minute}, "100 req / 60s (~1.67 req/s)"},
+		{ClientConfig{Limit: 5, Window: 500 * time.Millisecond}, "5 req / 0.5s (~10.00 req/s)"},
+		{ClientConfig{Limit: 1, Window: 250 * time.Millisecond}, "1 req / 0.25s (~4.00 req/s)"},
+		{ClientConfig{Limit: 0, Window: time.Minute}, "0 req / 60s (~0.00 req/s)"},
+		{ClientConfig{Limit: Unlimited, Window: time.Minute}, "unlimited"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cfg.String(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestClientConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ClientConfig
+		wantErr bool
+	}{
+		{"valid", ClientConfig{Limit: 5, Window: time.Minute, Burst: 2, SoftLimit: 4, MaxConcurrent: 1}, false},
+		{"unlimited without window", ClientConfig{Limit: Unlimited}, false},
+		{"zero window", ClientConfig{Limit: 5}, true},
+		{"negative window", ClientConfig{Limit: 5, Window: -time.Second}, true},
+		{"zero limit", ClientConfig{Window: time.Minute}, true},
+		{"negative burst", ClientConfig{Limit: 5, Window: time.Minute, Burst: -1}, true},
+		{"negative max concurrent", ClientConfig{Limit: 5, Window: time.Minute, MaxConcurrent: -1}, true},
+		{"soft limit above limit", ClientConfig{Limit: 5, Window: time.Minute, SoftLimit: 6}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(Clients); err != nil {
+		t.Fatalf("expected built-in clients to be valid, got %v", err)
+	}
+
+	err := Validate(map[string]ClientConfig{
+		"ok":    {Limit: 5, Window: time.Minute},
+		"b-bad": {Limit: 5},
+		"a-bad": {Window: time.Minute},
+	})
+	want := `client "a-bad": limit is 0, blocking every request` + "\n" + `client "b-bad": window 0s is not positive`
+	if err == nil || err.Error() != want {
+		t.Fatalf("expected %q, got %v", want, err)
+	}
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/simulator"
+)
+
+// configView renders a ClientConfig with the same keys the Consul KV entries
+// use and the window as a duration string.
+type configView struct {
+	Limit         int    `json:"limit"`
+	Window        string `json:"window"`
+	MaxConcurrent int    `json:"max_concurrent"`
+	SoftLimit     int    `json:"soft_limit"`
+	Burst         int    `json:"burst"`
+	RetryMessage  string `json:"retry_message,omitempty"`
+	Algorithm     string `json:"algorithm,omitempty"`
+}
+
+func newConfigView(cfg config.ClientConfig) configView {
+	return configView{
+		Limit:         cfg.Limit,
+		Window:        cfg.Window.String(),
+		MaxConcurrent: cfg.MaxConcurrent,
+		SoftLimit:     cfg.SoftLimit,
+		Burst:         cfg.Burst,
+		RetryMessage:  cfg.RetryMessage,
+		Algorithm:     cfg.Algorithm,
+	}
+}
+
+type setLimitRequest struct {
+	Client string `json:"client"`
+	Limit  int    `json:"limit"`
+	Window string `json:"window"`
+}
+
+// SetLimitHandler lets operators change a client's limit and window live with
+// a PUT authenticated by "Authorization: Bearer <token>". Other settings of
+// the client's effective config, such as Burst, are kept. An empty token
+// rejects every request.
+func SetLimitHandler(l *limiter.Limiter, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.Header().Set("Allow", http.MethodPut)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !validAdminToken(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req setLimitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if req.Client == "" {
+			http.Error(w, "missing client", http.StatusBadRequest)
+			return
+		}
+		if req.Limit <= 0 {
+			http.Error(w, "limit must be positive", http.StatusBadRequest)
+			return
+		}
+		window, err := time.ParseDuration(req.Window)
+		if err != nil {
+			http.Error(w, "invalid window: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if window <= 0 {
+			http.Error(w, "window must be positive", http.StatusBadRequest)
+			return
+		}
+
+		cfg := l.ConfigFor(req.Client)
+		cfg.Limit, cfg.Window = req.Limit, window
+		l.SetLimit(req.Client, cfg)
+
+		response := struct {
+			ClientID string `json:"client_id"`
+			configView
+		}{req.Client, newConfigView(l.ConfigFor(req.Client))}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// ConfigHandler dumps the effective limits, the default and every per-client
+// override including runtime changes, behind the same bearer token as
+// SetLimitHandler.
+func ConfigHandler(l *limiter.Limiter, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validAdminToken(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		clients, def := l.ExportConfig()
+		views := make(map[string]configView, len(clients))
+		for client, cfg := range clients {
+			views[client] = newConfigView(cfg)
+		}
+
+		response := map[string]interface{}{
+			"default": newConfigView(def),
+			"clients": views,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// maxSimulatedRequests bounds the work a single simulation can ask for.
+const maxSimulatedRequests = 100000
+
+type simulateRequest struct {
+	Limit    int    `json:"limit"`
+	Window   string `json:"window"`
+	Burst    int    `json:"burst"`
+	Requests int    `json:"requests"`
+	Spacing  string `json:"spacing"`
+}
+
+// SimulateHandler answers how a candidate limit would treat evenly spaced
+// traffic, replaying it against a private store so live counters are not
+// touched. It accepts a POST behind the same bearer token as SetLimitHandler.
+func SimulateHandler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !validAdminToken(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req simulateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if req.Limit <= 0 {
+			http.Error(w, "limit must be positive", http.StatusBadRequest)
+			return
+		}
+		if req.Burst < 0 {
+			http.Error(w, "burst must not be negative", http.StatusBadRequest)
+			return
+		}
+		if req.Requests <= 0 || req.Requests > maxSimulatedRequests {
+			http.Error(w, "requests must be between 1 and "+strconv.Itoa(maxSimulatedRequests), http.StatusBadRequest)
+			return
+		}
+		window, err := time.ParseDuration(req.Window)
+		if err != nil {
+			http.Error(w, "invalid window: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if window <= 0 {
+			http.Error(w, "window must be positive", http.StatusBadRequest)
+			return
+		}
+		var spacing time.Duration
+		if req.Spacing != "" {
+			if spacing, err = time.ParseDuration(req.Spacing); err != nil {
+				http.Error(w, "invalid spacing: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if spacing < 0 {
+				http.Error(w, "spacing must not be negative", http.StatusBadRequest)
+				return
+			}
+		}
+
+		cfg := config.ClientConfig{Limit: req.Limit, Window: window, Burst: req.Burst}
+		sum := simulator.Run(cfg, simulator.Pattern{Requests: req.Requests, Spacing: spacing})
+
+		response := map[string]interface{}{
+			"allowed": sum.Allowed,
+			"denied":  sum.Denied,
+		}
+		if sum.Denied > 0 {
+			response["first_denial_at"] = sum.FirstDenialAt.String()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DebugVarsHandler serves the expvar JSON to callers holding the admin token.
+func DebugVarsHandler(token string) http.HandlerFunc {
+	vars := expvar.Handler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validAdminToken(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		vars.ServeHTTP(w, r)
+	}
+}
+
+func validAdminToken(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func putLimit(l *limiter.Limiter, token, auth, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("PUT", "/admin/limits", strings.NewReader(body))
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	rec := httptest.NewRecorder()
+	SetLimitHandler(l, token)(rec, req)
+	return rec
+}
+
+func TestSetLimitHandler(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"client-1": {Limit: 1, Window: time.Minute, Burst: 2}}
+	l := limiter.New(memory.NewMemoryStore(), limiter.WithConfigs(cfgs))
+
+	rec := putLimit(l, "secret", "Bearer secret", `{"client":"client-1","limit":50,"window":"30s"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response["client_id"] != "client-1" || response["limit"] != float64(50) || response["window"] != "30s" || response["burst"] != float64(2) {
+		t.Errorf("unexpected response: %v", response)
+	}
+
+	want := config.ClientConfig{Limit: 50, Window: 30 * time.Second, Burst: 2}
+	if got := l.ConfigFor("client-1"); got != want {
+		t.Errorf("expected config %+v, got %+v", want, got)
+	}
+}
+
+func TestSetLimitHandlerAuth(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore())
+	body := `{"client":"client-1","limit":50,"window":"30s"}`
+
+	tests := []struct {
+		name  string
+		token string
+		auth  string
+	}{
+		{"missing header", "secret", ""},
+		{"wrong token", "secret", "Bearer nope"},
+		{"not bearer", "secret", "secret"},
+		{"no token configured", "", "Bearer "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := putLimit(l, tt.token, tt.auth, body); rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected status 401, got %d", rec.Code)
+			}
+		})
+	}
+
+	if got := l.ConfigFor("client-1"); got != config.DefaultConfig {
+		t.Errorf("expected config unchanged, got %+v", got)
+	}
+}
+
+func TestSetLimitHandlerValidation(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore())
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"malformed JSON", `{"client":`},
+		{"missing client", `{"limit":5,"window":"1m"}`},
+		{"zero limit", `{"client":"c","limit":0,"window":"1m"}`},
+		{"negative limit", `{"client":"c","limit":-1,"window":"1m"}`},
+		{"missing window", `{"client":"c","limit":5}`},
+		{"unparseable window", `{"client":"c","limit":5,"window":"soon"}`},
+		{"negative window", `{"client":"c","limit":5,"window":"-1m"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := putLimit(l, "secret", "Bearer secret", tt.body); rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("POST", "/admin/limits", nil)
+	rec := httptest.NewRecorder()
+	SetLimitHandler(l, "secret")(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for POST, got %d", rec.Code)
+	}
+}
+
+func TestConfigHandler(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"client-1": {Limit: 5, Window: time.Minute, RetryMessage: "Upgrade for more"}}
+	l := limiter.New(memory.NewMemoryStore(), limiter.WithConfigs(cfgs))
+	l.SetLimit("client-2", config.ClientConfig{Limit: 7, Window: 90 * time.Second, Burst: 3})
+
+	req := httptest.NewRequest("GET", "/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	ConfigHandler(l, "secret")(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var response struct {
+		Default configView            `json:"default"`
+		Clients map[string]configView `json:"clients"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.Default != (configView{Limit: 100, Window: "1m0s"}) {
+		t.Errorf("unexpected default: %+v", response.Default)
+	}
+	if got := response.Clients["client-1"]; got != (configView{Limit: 5, Window: "1m0s", RetryMessage: "Upgrade for more"}) {
+		t.Errorf("unexpected client-1: %+v", got)
+	}
+	if got := response.Clients["client-2"]; got != (configView{Limit: 7, Window: "1m30s", Burst: 3}) {
+		t.Errorf("expected runtime override in export, got %+v", got)
+	}
+}
+
+func TestConfigHandlerAuth(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore())
+
+	req := httptest.NewRequest("GET", "/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer nope")
+	rec := httptest.NewRecorder()
+	ConfigHandler(l, "secret")(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
+	}
+}
+
+func TestDebugVarsHandler(t *testing.T) {
+	for _, tc := range []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer nope", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/debug/vars", nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		rec := httptest.NewRecorder()
+		DebugVarsHandler("secret")(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("auth %q: expected status %d, got %d", tc.auth, tc.want, rec.Code)
+		}
+	}
+}
+
+func simulate(token, auth, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/admin/simulate", strings.NewReader(body))
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	rec := httptest.NewRecorder()
+	SimulateHandler(token)(rec, req)
+	return rec
+}
+
+func TestSimulateHandler(t *testing.T) {
+	rec := simulate("secret", "Bearer secret", `{"limit":5,"window":"1s","requests":20,"spacing":"100ms"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response["allowed"] != float64(10) || response["denied"] != float64(10) || response["first_denial_at"] != "500ms" {
+		t.Errorf("unexpected response: %v", response)
+	}
+
+	rec = simulate("secret", "Bearer secret", `{"limit":5,"window":"1m","requests":5}`)
+	response = nil
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := response["first_denial_at"]; ok || response["allowed"] != float64(5) {
+		t.Errorf("expected all allowed and no first_denial_at, got %v", response)
+	}
+}
+
+func TestSimulateHandlerValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		auth string
+		body string
+		want int
+	}{
+		{"wrong token", "Bearer nope", `{"limit":5,"window":"1m","requests":5}`, http.StatusUnauthorized},
+		{"malformed JSON", "Bearer secret", `{"limit":`, http.StatusBadRequest},
+		{"zero limit", "Bearer secret", `{"limit":0,"window":"1m","requests":5}`, http.StatusBadRequest},
+		{"negative burst", "Bearer secret", `{"limit":5,"burst":-1,"window":"1m","requests":5}`, http.StatusBadRequest},
+		{"missing window", "Bearer secret", `{"limit":5,"requests":5}`, http.StatusBadRequest},
+		{"zero requests", "Bearer secret", `{"limit":5,"window":"1m"}`, http.StatusBadRequest},
+		{"too many requests", "Bearer secret", `{"limit":5,"window":"1m","requests":100001}`, http.StatusBadRequest},
+		{"unparseable spacing", "Bearer secret", `{"limit":5,"window":"1m","requests":5,"spacing":"often"}`, http.StatusBadRequest},
+		{"negative spacing", "Bearer secret", `{"limit":5,"window":"1m","requests":5,"spacing":"-1s"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := simulate("secret", tt.auth, tt.body); rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
 )
 
 func HelloHandler(w http.ResponseWriter, r *http.Request) {
@@ -33,3 +35,30 @@ func StatusHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
+
+// HistoryHandler returns a client's recent decisions to requests
+// authenticated by "Authorization: Bearer <token>". An empty token rejects
+// every request.
+func HistoryHandler(l *limiter.Limiter, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validAdminToken(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		clientID := r.URL.Query().Get("client")
+		if clientID == "" {
+			http.Error(w, "missing client query parameter", http.StatusBadRequest)
+			return
+		}
+
+		response := map[string]interface{}{
+			"client_id": clientID,
+			"decisions": l.History(clientID),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -5,6 +5,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
 )
 
 func TestHelloHandler(t *testing.T) {
@@ -95,3 +100,67 @@ func TestStatusHandler(t *testing.T) {
 		t.Error("expected time to be set")
 	}
 }
+
+func TestHistoryHandler(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"client-1": {Limit: 1, Window: time.Minute}}
+	l := limiter.New(memory.NewMemoryStore(), limiter.WithConfigs(cfgs), limiter.WithHistory(5))
+	l.Allow("client-1")
+	l.Allow("client-1")
+
+	t.Run("returns recorded decisions", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/admin/history?client=client-1", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+
+		HistoryHandler(l, "secret")(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+
+		var response struct {
+			ClientID  string             `json:"client_id"`
+			Decisions []limiter.Decision `json:"decisions"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.ClientID != "client-1" {
+			t.Errorf("expected client_id client-1, got %s", response.ClientID)
+		}
+		if len(response.Decisions) != 2 {
+			t.Fatalf("expected 2 decisions, got %d", len(response.Decisions))
+		}
+		if !response.Decisions[0].Allowed || response.Decisions[1].Allowed {
+			t.Errorf("expected allowed then denied, got %+v", response.Decisions)
+		}
+	})
+
+	t.Run("missing client", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/admin/history", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+
+		HistoryHandler(l, "secret")(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		for _, token := range []string{"", "wrong"} {
+			req := httptest.NewRequest("GET", "/admin/history?client=client-1", nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+
+			HistoryHandler(l, "secret")(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("token %q: expected status 401, got %d", token, rec.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package kvconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ConsulClient reads a key prefix through the Consul HTTP KV API using
+// blocking queries.
+type ConsulClient struct {
+	Addr  string // e.g. http://localhost:8500
+	Token string
+	Wait  time.Duration
+	HTTP  *http.Client
+}
+
+type consulPair struct {
+	Key   string
+	Value []byte // base64 in the API response, decoded by encoding/json
+}
+
+func (c *ConsulClient) List(ctx context.Context, prefix string, waitIndex uint64) ([]KVPair, uint64, error) {
+	q := url.Values{"recurse": {"true"}}
+	if waitIndex > 0 {
+		q.Set("index", strconv.FormatUint(waitIndex, 10))
+		wait := c.Wait
+		if wait <= 0 {
+			wait = 5 * time.Minute
+		}
+		q.Set("wait", wait.String())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Addr+"/v1/kv/"+prefix+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Token)
+	}
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, index, nil
+	default:
+		return nil, 0, fmt.Errorf("consul kv: unexpected status %d", resp.StatusCode)
+	}
+
+	var raw []consulPair
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, 0, fmt.Errorf("consul kv: %w", err)
+	}
+
+	pairs := make([]KVPair, len(raw))
+	for i, p := range raw {
+		pairs[i] = KVPair{Key: p.Key, Value: p.Value}
+	}
+	return pairs, index, nil
+}
@@ -0,0 +1,172 @@
+package kvconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+type KVPair struct {
+	Key   string
+	Value []byte
+}
+
+// KVClient lists the pairs under a prefix. When waitIndex is non-zero the call
+// blocks until the prefix changes past that index (or a backend timeout), in
+// the style of Consul blocking queries. The returned index is passed back on
+// the next call.
+type KVClient interface {
+	List(ctx context.Context, prefix string, waitIndex uint64) ([]KVPair, uint64, error)
+}
+
+// entry is the JSON stored under <prefix><client>, e.g.
+// {"limit":5,"window":"1m","max_concurrent":2,"soft_limit":3}. Algorithm is
+// optional and takes the names of limiter.ParseAlgorithm.
+type entry struct {
+	Limit         int    `json:"limit"`
+	Window        string `json:"window"`
+	MaxConcurrent int    `json:"max_concurrent"`
+	SoftLimit     int    `json:"soft_limit"`
+	Burst         int    `json:"burst"`
+	RetryMessage  string `json:"retry_message"`
+	Algorithm     string `json:"algorithm"`
+}
+
+const defaultRetryDelay = 5 * time.Second
+
+// Provider watches a KV prefix and pushes every change to the limiter with
+// SetConfigs, layered over the static configs and under runtime overrides
+// from /admin/limits. An update containing an entry that fails to parse is rejected
+// as a whole, keeping the last good config.
+type Provider struct {
+	client     KVClient
+	prefix     string
+	limiter    *limiter.Limiter
+	logger     *slog.Logger
+	retryDelay time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewProvider starts watching prefix in a background goroutine until Close.
+func NewProvider(client KVClient, prefix string, l *limiter.Limiter, logger *slog.Logger) *Provider {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Provider{
+		client:     client,
+		prefix:     prefix,
+		limiter:    l,
+		logger:     logger,
+		retryDelay: defaultRetryDelay,
+		cancel:     cancel,
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.watch(ctx)
+	}()
+	return p
+}
+
+// Close stops the watch and waits for the goroutine to exit.
+func (p *Provider) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+func (p *Provider) watch(ctx context.Context) {
+	var index uint64
+	for {
+		pairs, next, err := p.client.List(ctx, p.prefix, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			p.logger.Warn("kv config watch failed, retrying", "error", err, "prefix", p.prefix)
+			if !sleep(ctx, p.retryDelay) {
+				return
+			}
+			continue
+		}
+
+		if next == index && index != 0 {
+			continue
+		}
+		switch {
+		case next < index:
+			// The KV store was reset; start over rather than blocking on an
+			// index that may never be reached.
+			index = 0
+		case next == 0:
+			index = 1
+		default:
+			index = next
+		}
+
+		cfgs, err := p.parse(pairs)
+		if err != nil {
+			p.logger.Error("invalid kv config, keeping last good config", "error", err, "prefix", p.prefix)
+			continue
+		}
+		p.limiter.SetConfigs(cfgs)
+		p.logger.Info("kv config applied", "prefix", p.prefix, "clients", len(cfgs))
+	}
+}
+
+func (p *Provider) parse(pairs []KVPair) (map[string]config.ClientConfig, error) {
+	cfgs := make(map[string]config.ClientConfig, len(pairs))
+	for _, pair := range pairs {
+		client := strings.TrimPrefix(pair.Key, p.prefix)
+		if client == "" || strings.HasSuffix(client, "/") {
+			continue
+		}
+
+		var e entry
+		if err := json.Unmarshal(pair.Value, &e); err != nil {
+			return nil, fmt.Errorf("%s: %w", pair.Key, err)
+		}
+		window, err := time.ParseDuration(e.Window)
+		if err != nil {
+			return nil, fmt.Errorf("%s: window: %w", pair.Key, err)
+		}
+		if window <= 0 {
+			return nil, fmt.Errorf("%s: window must be positive", pair.Key)
+		}
+		if e.Algorithm != "" {
+			if _, err := limiter.ParseAlgorithm(e.Algorithm); err != nil {
+				return nil, fmt.Errorf("%s: %w", pair.Key, err)
+			}
+		}
+
+		cfgs[client] = config.ClientConfig{
+			Limit:         e.Limit,
+			Window:        window,
+			MaxConcurrent: e.MaxConcurrent,
+			SoftLimit:     e.SoftLimit,
+			Burst:         e.Burst,
+			RetryMessage:  e.RetryMessage,
+			Algorithm:     e.Algorithm,
+		}
+	}
+	return cfgs, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,178 @@
+package kvconfig
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// mockKV serves the current pairs immediately for index 0 and otherwise blocks
+// until the next update.
+type mockKV struct {
+	mu      sync.Mutex
+	index   uint64
+	pairs   []KVPair
+	changed chan struct{}
+}
+
+func newMockKV(pairs ...KVPair) *mockKV {
+	return &mockKV{index: 1, pairs: pairs, changed: make(chan struct{})}
+}
+
+func (m *mockKV) List(ctx context.Context, prefix string, waitIndex uint64) ([]KVPair, uint64, error) {
+	m.mu.Lock()
+	if waitIndex < m.index {
+		defer m.mu.Unlock()
+		return m.pairs, m.index, nil
+	}
+	changed := m.changed
+	m.mu.Unlock()
+
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pairs, m.index, nil
+}
+
+func (m *mockKV) update(pairs ...KVPair) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pairs = pairs
+	m.index++
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+func waitForLimit(t *testing.T, l *limiter.Limiter, client string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for l.ConfigFor(client).Limit != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s limit %d, got %d", client, want, l.ConfigFor(client).Limit)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	l := limiter.New(memory.NewMemoryStore(), limiter.WithLogger(logger))
+	kv := newMockKV(KVPair{Key: "ratelimit/c1", Value: []byte(`{"limit":5,"window":"1m"}`)})
+
+	p := NewProvider(kv, "ratelimit/", l, logger)
+	defer p.Close()
+
+	waitForLimit(t, l, "c1", 5)
+	if got := l.ConfigFor("c1").Window; got != time.Minute {
+		t.Fatalf("expected window 1m, got %v", got)
+	}
+
+	kv.update(
+		KVPair{Key: "ratelimit/c1", Value: []byte(`{"limit":10,"window":"30s"}`)},
+		KVPair{Key: "ratelimit/c2", Value: []byte(`{"limit":2,"window":"1m","soft_limit":1,"retry_message":"upgrade","algorithm":"token_bucket"}`)},
+	)
+	waitForLimit(t, l, "c1", 10)
+	waitForLimit(t, l, "c2", 2)
+	if got := l.ConfigFor("c2").RetryMessage; got != "upgrade" {
+		t.Fatalf("expected retry message from KV, got %q", got)
+	}
+	if got := l.ConfigFor("c2").Algorithm; got != "token_bucket" {
+		t.Fatalf("expected algorithm from KV, got %q", got)
+	}
+
+	kv.update(
+		KVPair{Key: "ratelimit/c1", Value: []byte(`{"limit":1,"window":"1m"}`)},
+		KVPair{Key: "ratelimit/c2", Value: []byte(`not json`)},
+	)
+	kv.update(KVPair{Key: "ratelimit/c2", Value: []byte(`{"limit":3,"window":"-1s"}`)})
+	kv.update(KVPair{Key: "ratelimit/c2", Value: []byte(`{"limit":3,"window":"1m","algorithm":"sliding"}`)})
+	time.Sleep(50 * time.Millisecond)
+	if got := l.ConfigFor("c1").Limit; got != 10 {
+		t.Fatalf("expected last good config to be kept, got limit %d", got)
+	}
+
+	kv.update(KVPair{Key: "ratelimit/c2", Value: []byte(`{"limit":4,"window":"1m"}`)})
+	waitForLimit(t, l, "c2", 4)
+	if got := l.ConfigFor("c1"); got != config.DefaultConfig {
+		t.Fatalf("expected removed client to fall back to default, got %+v", got)
+	}
+}
+
+func TestProviderKeepsStaticConfigs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	l := limiter.New(memory.NewMemoryStore(), limiter.WithLogger(logger), limiter.WithConfigs(map[string]config.ClientConfig{
+		"static": {Limit: 7, Window: time.Minute},
+	}))
+	l.SetLimit("admin", config.ClientConfig{Limit: 9, Window: time.Minute})
+	kv := newMockKV(KVPair{Key: "ratelimit/kv", Value: []byte(`{"limit":5,"window":"1m"}`)})
+
+	p := NewProvider(kv, "ratelimit/", l, logger)
+	defer p.Close()
+
+	waitForLimit(t, l, "kv", 5)
+	kv.update()
+	waitForLimit(t, l, "kv", config.DefaultConfig.Limit)
+	if got := l.ConfigFor("static").Limit; got != 7 {
+		t.Fatalf("expected static client to survive an empty KV listing, got limit %d", got)
+	}
+	if got := l.ConfigFor("admin").Limit; got != 9 {
+		t.Fatalf("expected runtime override to survive an empty KV listing, got limit %d", got)
+	}
+}
+
+func TestProviderClose(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	l := limiter.New(memory.NewMemoryStore(), limiter.WithLogger(logger))
+	p := NewProvider(newMockKV(), "ratelimit/", l, logger)
+
+	done := make(chan struct{})
+	go func() {
+		p.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not stop the watch")
+	}
+}
+
+func TestConsulClientList(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/ratelimit/" || r.URL.Query().Get("recurse") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.URL.Query().Get("index") != "7" {
+			t.Errorf("expected blocking query on index 7, got %q", r.URL.Query().Get("index"))
+		}
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			t.Errorf("expected token header")
+		}
+		w.Header().Set("X-Consul-Index", "8")
+		w.Write([]byte(`[{"Key":"ratelimit/c1","Value":"eyJsaW1pdCI6NX0="}]`))
+	}))
+	defer srv.Close()
+
+	c := &ConsulClient{Addr: srv.URL, Token: "secret"}
+	pairs, index, err := c.List(context.Background(), "ratelimit/", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index != 8 || len(pairs) != 1 || pairs[0].Key != "ratelimit/c1" || string(pairs[0].Value) != `{"limit":5}` {
+		t.Fatalf("unexpected result index=%d pairs=%+v", index, pairs)
+	}
+}
@@ -0,0 +1,127 @@
+package limiter
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// Algorithm selects how a client's own budget is counted.
+type Algorithm int
+
+const (
+	// AlgorithmFixedWindow counts requests in a window starting at the
+	// client's first request. A client can send up to twice its limit in a
+	// short burst straddling two windows.
+	AlgorithmFixedWindow Algorithm = iota
+	// AlgorithmSlidingWindow counts requests in windows aligned to the epoch
+	// and adds the previous window's count weighted by how much of it still
+	// overlaps the last Window, which smooths out the boundary burst.
+	AlgorithmSlidingWindow
+	// AlgorithmSlidingLog records the time of every admitted request and
+	// counts those within the last Window exactly. It needs a LogStore and
+	// memory proportional to each client's limit.
+	AlgorithmSlidingLog
+	// AlgorithmTokenBucket gives each client a bucket of Limit+Burst tokens
+	// refilled at Limit per Window, so Burst bounds how far a client can run
+	// ahead of the steady rate. It needs a BucketStore.
+	AlgorithmTokenBucket
+	// AlgorithmLeakyBucket queues up to Limit+Burst units per client and
+	// drains them at Limit per Window. Admitted requests report in
+	// Result.Delay how long they wait in the queue; callers that sleep for it
+	// serve the client at the constant drain rate. Result.Count is the queue
+	// depth. It keeps the same state as the token bucket, so it also needs a
+	// BucketStore.
+	AlgorithmLeakyBucket
+	// AlgorithmGCRA is the generic cell rate algorithm: requests are spaced
+	// Window/Limit apart and a client may run up to Limit+Burst requests
+	// ahead. It stores one timestamp per client whatever the limit, where the
+	// sliding log stores one per request. It needs a GCRAStore.
+	AlgorithmGCRA
+)
+
+// algorithmNames are the names used by ParseAlgorithm, String and
+// config.ClientConfig.Algorithm.
+var algorithmNames = map[Algorithm]string{
+	AlgorithmFixedWindow:   "fixed_window",
+	AlgorithmSlidingWindow: "sliding_window",
+	AlgorithmSlidingLog:    "sliding_log",
+	AlgorithmTokenBucket:   "token_bucket",
+	AlgorithmLeakyBucket:   "leaky_bucket",
+	AlgorithmGCRA:          "gcra",
+}
+
+func (a Algorithm) String() string {
+	if name, ok := algorithmNames[a]; ok {
+		return name
+	}
+	return "Algorithm(" + strconv.Itoa(int(a)) + ")"
+}
+
+// algorithmsByName inverts algorithmNames so configs resolve their algorithm
+// with one lookup per decision.
+var algorithmsByName = func() map[string]Algorithm {
+	byName := make(map[string]Algorithm, len(algorithmNames))
+	for a, name := range algorithmNames {
+		byName[name] = a
+	}
+	return byName
+}()
+
+// ParseAlgorithm returns the algorithm named name, such as "sliding_window".
+func ParseAlgorithm(name string) (Algorithm, error) {
+	if a, ok := algorithmsByName[name]; ok {
+		return a, nil
+	}
+	return 0, fmt.Errorf("unknown algorithm %q", name)
+}
+
+// WithAlgorithm selects the counting algorithm for client budgets. The
+// default is AlgorithmFixedWindow. A config naming its own Algorithm
+// overrides it for that client. Configs naming an unknown algorithm are
+// logged and use this one instead when they are set; validate names with
+// ParseAlgorithm where configs are loaded to reject them earlier. Group pools
+// always use a fixed window.
+//
+// The sliding window keeps two counters per budget, "<key>:sw<n>" for the
+// current and previous window, through plain Increment and Get, so store
+// decision scripts are not used. Like the fixed window it also counts denied
+// requests, so a client that keeps retrying stays limited. The sliding log
+// only records admitted requests, and its logs have no count for Snapshot's
+// Top; the same goes for token buckets and GCRA timestamps.
+func WithAlgorithm(a Algorithm) Option {
+	return func(l *Limiter) {
+		l.algorithm = a
+	}
+}
+
+// algorithmFor is the algorithm counting cfg's budget.
+func (l *Limiter) algorithmFor(cfg config.ClientConfig) Algorithm {
+	if a, ok := algorithmsByName[cfg.Algorithm]; ok {
+		return a
+	}
+	return l.algorithm
+}
+
+// checkAlgorithm warns about and clears an unknown cfg.Algorithm, so a typo
+// shows up once when the config is set rather than silently on every
+// decision. scope names the config in the log, e.g. the client ID.
+func (l *Limiter) checkAlgorithm(scope string, cfg config.ClientConfig) config.ClientConfig {
+	if _, ok := algorithmsByName[cfg.Algorithm]; cfg.Algorithm != "" && !ok {
+		l.logger.Warn("unknown algorithm in config, using the default",
+			"config", scope, "algorithm", cfg.Algorithm, "default", l.algorithm.String())
+		cfg.Algorithm = ""
+	}
+	return cfg
+}
+
+// countsDenials reports whether denied requests add to cfg's count, as they
+// do for the fixed and sliding windows.
+func (l *Limiter) countsDenials(cfg config.ClientConfig) bool {
+	return !l.gcraConfigured(cfg) && !l.bucketConfigured(cfg) && !l.logConfigured(cfg)
+}
+
+func (l *Limiter) sliding(cfg config.ClientConfig) bool {
+	return l.algorithmFor(cfg) == AlgorithmSlidingWindow
+}
@@ -0,0 +1,81 @@
+package limiter
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestParseAlgorithm(t *testing.T) {
+	for a := range algorithmNames {
+		got, err := ParseAlgorithm(a.String())
+		if err != nil || got != a {
+			t.Fatalf("expected %v to round-trip, got %v, %v", a, got, err)
+		}
+	}
+	if _, err := ParseAlgorithm("sliding"); err == nil {
+		t.Fatal("expected an unknown name to fail")
+	}
+	if got := Algorithm(99).String(); got != "Algorithm(99)" {
+		t.Fatalf("unexpected name for an unknown algorithm: %q", got)
+	}
+}
+
+func TestPerClientAlgorithm(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newClockedLimiter(clock)
+	l.SetLimit("fixed", config.ClientConfig{Limit: 10, Window: 10 * time.Second})
+	l.SetLimit("bucket", config.ClientConfig{Limit: 10, Window: 10 * time.Second, Algorithm: "token_bucket"})
+	l.SetLimit("typo", config.ClientConfig{Limit: 10, Window: 10 * time.Second, Algorithm: "bucket"})
+
+	for _, client := range []string{"fixed", "bucket", "typo"} {
+		for i := 0; i < 10; i++ {
+			l.AllowResult(client)
+		}
+	}
+
+	// One second in, only the bucket has refilled a token.
+	clock.now = clock.now.Add(time.Second)
+	want := map[string]bool{"fixed": false, "bucket": true, "typo": false}
+	for client, allowed := range want {
+		res, err := l.AllowResult(client)
+		if err != nil || res.Allowed != allowed {
+			t.Fatalf("%s: expected allowed=%v, got %+v, %v", client, allowed, res, err)
+		}
+	}
+
+	results, err := l.PeekMany([]string{"fixed", "bucket"})
+	if err != nil || results[0].Count != 11 || results[1].Count != 10 {
+		t.Fatalf("expected each client peeked with its own algorithm, got %+v, %v", results, err)
+	}
+}
+
+func TestUnknownAlgorithmWarns(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(memory.NewMemoryStore(),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+		WithAlgorithm(AlgorithmSlidingWindow),
+		WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute, Algorithm: "bucket"}}),
+	)
+	if !strings.Contains(buf.String(), "algorithm=bucket") || !strings.Contains(buf.String(), "config=c1") {
+		t.Fatalf("expected a warning naming the client and algorithm, got %q", buf.String())
+	}
+	if got := l.ConfigFor("c1").Algorithm; got != "" {
+		t.Fatalf("expected the unknown name to be cleared, got %q", got)
+	}
+
+	buf.Reset()
+	l.SetLimit("c2", config.ClientConfig{Limit: 1, Window: time.Minute, Algorithm: "gcrA"})
+	if !strings.Contains(buf.String(), "config=c2") {
+		t.Fatalf("expected SetLimit to warn, got %q", buf.String())
+	}
+	l.SetConfigs(map[string]config.ClientConfig{"c3": {Limit: 1, Window: time.Minute, Algorithm: "gcra"}})
+	if strings.Contains(buf.String(), "config=c3") {
+		t.Fatalf("expected no warning for a known algorithm, got %q", buf.String())
+	}
+}
@@ -0,0 +1,69 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// CardinalityMetrics is implemented by Metrics that also want key growth
+// alerts, e.g. to page on a flood of spoofed client IDs.
+type CardinalityMetrics interface {
+	// KeyGrowthExceeded reports that the store created created keys within
+	// the current interval, past the WithKeyGrowthAlert threshold.
+	KeyGrowthExceeded(created int64)
+}
+
+// WithKeyGrowthAlert warns, once per interval, when more than threshold
+// counter keys are created within interval, a sign of a surge in distinct
+// client IDs bloating the store. Only keys the store reports as created count
+// (see StoreDecision.Created); the fixed window counters of the memory and
+// Redis stores do.
+func WithKeyGrowthAlert(threshold int, interval time.Duration) Option {
+	return func(l *Limiter) {
+		l.keyGrowth = &keyGrowth{threshold: int64(threshold), interval: interval}
+	}
+}
+
+type keyGrowth struct {
+	threshold int64
+	interval  time.Duration
+
+	mu      sync.Mutex
+	start   time.Time
+	created int64
+	alerted bool
+}
+
+// add counts a new key at now, reporting the interval's total when it has
+// just crossed the threshold.
+func (g *keyGrowth) add(now time.Time) (int64, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if now.Sub(g.start) >= g.interval {
+		g.start, g.created, g.alerted = now, 0, false
+	}
+	g.created++
+	if g.alerted || g.created <= g.threshold {
+		return g.created, false
+	}
+	g.alerted = true
+	return g.created, true
+}
+
+func (l *Limiter) keyCreated(now time.Time) {
+	if l.keyGrowth == nil {
+		return
+	}
+	created, exceeded := l.keyGrowth.add(now)
+	if !exceeded {
+		return
+	}
+	l.logger.Warn("rate limit key cardinality spike",
+		"created", created,
+		"threshold", l.keyGrowth.threshold,
+		"interval", l.keyGrowth.interval,
+	)
+	if cm, ok := l.metrics.(CardinalityMetrics); ok {
+		cm.KeyGrowthExceeded(created)
+	}
+}
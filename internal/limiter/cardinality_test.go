@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+type growthMetrics struct {
+	countingMetrics
+	exceeded []int64
+}
+
+func (m *growthMetrics) KeyGrowthExceeded(created int64) { m.exceeded = append(m.exceeded, created) }
+
+func TestKeyGrowthAlert(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Now()
+	clock := func() time.Time { return now }
+	metrics := &growthMetrics{}
+	l := New(memory.NewMemoryStore(memory.WithClock(clock)),
+		WithClock(clock),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+		WithMetrics(metrics),
+		WithKeyGrowthAlert(5, time.Minute),
+	)
+	warnings := func() int { return strings.Count(buf.String(), "key cardinality spike") }
+
+	for i := 0; i < 5; i++ {
+		l.AllowResult(fmt.Sprintf("spoofed-%d", i))
+	}
+	// Repeat requests reuse their window and are not new keys.
+	for i := 0; i < 10; i++ {
+		l.AllowResult("spoofed-0")
+	}
+	if warnings() != 0 || len(metrics.exceeded) != 0 {
+		t.Fatalf("expected no alert at the threshold, got %d warnings: %s", warnings(), buf.String())
+	}
+
+	for i := 5; i < 50; i++ {
+		l.AllowResult(fmt.Sprintf("spoofed-%d", i))
+	}
+	if warnings() != 1 || len(metrics.exceeded) != 1 || metrics.exceeded[0] != 6 {
+		t.Fatalf("expected one alert past the threshold, got %d warnings and %v", warnings(), metrics.exceeded)
+	}
+	if !strings.Contains(buf.String(), "created=6") {
+		t.Fatalf("expected the created count in the warning, got %s", buf.String())
+	}
+
+	now = now.Add(time.Minute)
+	for i := 50; i < 56; i++ {
+		l.AllowResult(fmt.Sprintf("spoofed-%d", i))
+	}
+	if warnings() != 2 || len(metrics.exceeded) != 2 {
+		t.Fatalf("expected a new alert in the next interval, got %d warnings", warnings())
+	}
+}
+
+func TestKeyGrowthIgnoresWindowRollover(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Now()
+	clock := func() time.Time { return now }
+	cfgs := map[string]config.ClientConfig{}
+	for i := 0; i < 5; i++ {
+		cfgs[fmt.Sprintf("c%d", i)] = config.ClientConfig{Limit: 10, Window: time.Second}
+	}
+	l := New(memory.NewMemoryStore(memory.WithClock(clock)),
+		WithClock(clock),
+		WithConfigs(cfgs),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+		WithKeyGrowthAlert(5, time.Hour),
+	)
+
+	// Returning clients open a new window every second but create no keys.
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 5; i++ {
+			l.AllowResult(fmt.Sprintf("c%d", i))
+		}
+		now = now.Add(2 * time.Second)
+	}
+	if strings.Contains(buf.String(), "cardinality") {
+		t.Fatalf("expected window rollovers not to count as new keys, got %s", buf.String())
+	}
+}
+
+func TestKeyGrowthAlertDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(memory.NewMemoryStore(), WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	for i := 0; i < 100; i++ {
+		l.AllowResult(fmt.Sprintf("c%d", i))
+	}
+	if strings.Contains(buf.String(), "cardinality") {
+		t.Fatalf("expected no alert without WithKeyGrowthAlert, got %s", buf.String())
+	}
+}
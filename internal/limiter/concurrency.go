@@ -0,0 +1,78 @@
+package limiter
+
+// Acquire enforces both the client's MaxConcurrent cap and its rate limit in
+// one decision. The concurrency slot is reserved first so a request rejected
+// for concurrency does not consume rate quota. When the request is admitted,
+// the caller must call release once it completes; release is safe to call on
+// denied results and more than once.
+func (l *Limiter) Acquire(client, scope string, n int64) (Result, func(), error) {
+	return l.AcquireRequest(Request{Client: client, Scope: scope, Cost: n})
+}
+
+// AcquireRequest is Acquire for a full Request.
+func (l *Limiter) AcquireRequest(req Request) (Result, func(), error) {
+	client := req.Client
+	cfg := l.configForRequest(req)
+	if cfg.MaxConcurrent <= 0 {
+		res, err := l.AllowRequest(req)
+		return res, func() {}, err
+	}
+
+	if !l.reserveSlot(client, cfg.MaxConcurrent) {
+		res, err := l.CheckRequest(req)
+		res.Allowed = false
+		res.Reason = ReasonConcurrency
+		if err == nil {
+			l.emit(req, res)
+		}
+		return res, func() {}, err
+	}
+
+	res, err := l.AllowRequest(req)
+	if err != nil || !res.Allowed {
+		l.releaseSlot(client)
+		return res, func() {}, err
+	}
+
+	released := false
+	return res, func() {
+		l.inFlightMu.Lock()
+		defer l.inFlightMu.Unlock()
+		if released {
+			return
+		}
+		released = true
+		l.decrementLocked(client)
+	}, nil
+}
+
+// InFlight returns the number of admitted requests for client that have not
+// been released yet.
+func (l *Limiter) InFlight(client string) int {
+	l.inFlightMu.Lock()
+	defer l.inFlightMu.Unlock()
+	return l.inFlight[client]
+}
+
+func (l *Limiter) reserveSlot(client string, max int) bool {
+	l.inFlightMu.Lock()
+	defer l.inFlightMu.Unlock()
+	if l.inFlight[client] >= max {
+		return false
+	}
+	l.inFlight[client]++
+	return true
+}
+
+func (l *Limiter) releaseSlot(client string) {
+	l.inFlightMu.Lock()
+	defer l.inFlightMu.Unlock()
+	l.decrementLocked(client)
+}
+
+func (l *Limiter) decrementLocked(client string) {
+	l.inFlight[client]--
+	if l.inFlight[client] <= 0 {
+		delete(l.inFlight, client)
+	}
+}
@@ -0,0 +1,113 @@
+package limiter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Run("concurrency binds first", func(t *testing.T) {
+		cfgs := map[string]config.ClientConfig{"c1": {Limit: 10, Window: time.Minute, MaxConcurrent: 2}}
+		l := NewLimiter(memory.NewMemoryStore(), cfgs)
+
+		_, release1, _ := l.Acquire("c1", "", 1)
+		_, release2, _ := l.Acquire("c1", "", 1)
+
+		res, _, err := l.Acquire("c1", "", 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Allowed || res.Reason != ReasonConcurrency {
+			t.Fatalf("expected concurrency denial, got %+v", res)
+		}
+		if res.Remaining != 8 {
+			t.Fatalf("expected rate quota untouched by concurrency denial, remaining %d", res.Remaining)
+		}
+
+		release1()
+		release1()
+		if l.InFlight("c1") != 1 {
+			t.Fatalf("expected double release to be ignored, in flight %d", l.InFlight("c1"))
+		}
+
+		res, release3, _ := l.Acquire("c1", "", 1)
+		if !res.Allowed {
+			t.Fatalf("expected admission after release, got %+v", res)
+		}
+		release2()
+		release3()
+		if l.InFlight("c1") != 0 {
+			t.Fatalf("expected no requests in flight, got %d", l.InFlight("c1"))
+		}
+	})
+	t.Run("rate binds first", func(t *testing.T) {
+		cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute, MaxConcurrent: 5}}
+		l := NewLimiter(memory.NewMemoryStore(), cfgs)
+
+		for i := 0; i < 2; i++ {
+			res, release, _ := l.Acquire("c1", "", 1)
+			if !res.Allowed {
+				t.Fatalf("request %d: expected allowed", i)
+			}
+			release()
+		}
+
+		res, _, _ := l.Acquire("c1", "", 1)
+		if res.Allowed || res.Reason != ReasonRateLimit {
+			t.Fatalf("expected rate limit denial, got %+v", res)
+		}
+		if l.InFlight("c1") != 0 {
+			t.Fatalf("expected denied request to free its slot, in flight %d", l.InFlight("c1"))
+		}
+	})
+	t.Run("no concurrency cap", func(t *testing.T) {
+		l := NewLimiter(memory.NewMemoryStore(), map[string]config.ClientConfig{})
+		res, release, _ := l.Acquire("c1", "", 1)
+		release()
+		if !res.Allowed || l.InFlight("c1") != 0 {
+			t.Fatalf("expected plain rate limiting, got %+v", res)
+		}
+	})
+}
+
+func TestAcquireConcurrent(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1000, Window: time.Minute, MaxConcurrent: 3}}
+	l := NewLimiter(memory.NewMemoryStore(), cfgs)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		current int
+		peak    int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, release, _ := l.Acquire("c1", "", 1)
+			if !res.Allowed {
+				return
+			}
+			mu.Lock()
+			current++
+			if current > peak {
+				peak = current
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			current--
+			mu.Unlock()
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak > 3 {
+		t.Fatalf("expected at most 3 concurrent admissions, saw %d", peak)
+	}
+}
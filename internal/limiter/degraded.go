@@ -0,0 +1,57 @@
+package limiter
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// degradedLogInterval spaces out fail-open warnings so a store outage does not
+// log once per request.
+const degradedLogInterval = 10 * time.Second
+
+// Metrics receives limiter events worth graphing.
+type Metrics interface {
+	// FailedOpen reports a request admitted without enforcement because the
+	// store failed under FailOpen.
+	FailedOpen(client string)
+}
+
+// WithMetrics reports limiter events to m.
+func WithMetrics(m Metrics) Option {
+	return func(l *Limiter) {
+		l.metrics = m
+	}
+}
+
+// degradedLog rate-limits the fail-open warning, counting what it suppresses,
+// and remembers when the store last failed.
+type degradedLog struct {
+	lastNanos  atomic.Int64
+	suppressed atomic.Int64
+	lastError  atomic.Int64
+}
+
+// Degraded reports whether the store failed within the last
+// degradedLogInterval, whatever the failure policy made of it.
+func (l *Limiter) Degraded() bool {
+	last := l.degraded.lastError.Load()
+	return last != 0 && l.now().UnixNano()-last < int64(degradedLogInterval)
+}
+
+func (l *Limiter) failedOpen(client string, err error) {
+	if l.metrics != nil {
+		l.metrics.FailedOpen(client)
+	}
+
+	now := l.now().UnixNano()
+	last := l.degraded.lastNanos.Load()
+	if (last != 0 && now-last < int64(degradedLogInterval)) || !l.degraded.lastNanos.CompareAndSwap(last, now) {
+		l.degraded.suppressed.Add(1)
+		return
+	}
+	l.logger.Warn("rate limiter store error, failing open",
+		"error", err,
+		"client", client,
+		"suppressed", l.degraded.suppressed.Swap(0),
+	)
+}
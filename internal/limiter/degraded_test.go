@@ -0,0 +1,89 @@
+package limiter
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingMetrics struct {
+	failedOpen map[string]int
+}
+
+func (m *countingMetrics) FailedOpen(client string) { m.failedOpen[client]++ }
+
+func TestFailOpenMetrics(t *testing.T) {
+	m := &countingMetrics{failedOpen: map[string]int{}}
+	l := New(&mockStoreError{}, WithFailurePolicy(FailOpen), WithMetrics(m))
+
+	for i := 1; i <= 3; i++ {
+		if ok, _, _, err := l.Allow("c1"); !ok || err != nil {
+			t.Fatalf("expected fail open, got ok=%v err=%v", ok, err)
+		}
+		if m.failedOpen["c1"] != i {
+			t.Fatalf("expected %d fail-open decisions, got %d", i, m.failedOpen["c1"])
+		}
+	}
+
+	l.Check("c2")
+	if m.failedOpen["c2"] != 1 {
+		t.Fatalf("expected check to count as fail open, got %d", m.failedOpen["c2"])
+	}
+}
+
+func TestFailOpenMetricsOtherPolicies(t *testing.T) {
+	for _, p := range []FailurePolicy{FailError, FailClosed} {
+		m := &countingMetrics{failedOpen: map[string]int{}}
+		l := New(&mockStoreError{}, WithFailurePolicy(p), WithMetrics(m))
+		l.Allow("c1")
+		if len(m.failedOpen) != 0 {
+			t.Fatalf("policy %d: expected no fail-open metric, got %v", p, m.failedOpen)
+		}
+	}
+}
+
+func TestFailOpenWarningRateLimited(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Now()
+	l := New(&mockStoreError{},
+		WithFailurePolicy(FailOpen),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+		WithClock(func() time.Time { return now }),
+	)
+
+	for i := 0; i < 5; i++ {
+		l.Allow("c1")
+	}
+	if n := strings.Count(buf.String(), "failing open"); n != 1 {
+		t.Fatalf("expected 1 warning within the interval, got %d: %s", n, buf.String())
+	}
+
+	now = now.Add(degradedLogInterval)
+	l.Allow("c1")
+	if n := strings.Count(buf.String(), "failing open"); n != 2 {
+		t.Fatalf("expected a second warning after the interval, got %d", n)
+	}
+	if !strings.Contains(buf.String(), "suppressed=4") {
+		t.Fatalf("expected suppressed count in warning, got %s", buf.String())
+	}
+}
+
+func TestDegraded(t *testing.T) {
+	now := time.Now()
+	store := &mockStoreError{}
+	l := New(store, WithFailurePolicy(FailClosed), WithClock(func() time.Time { return now }))
+
+	if l.Degraded() {
+		t.Fatal("expected a fresh limiter not to be degraded")
+	}
+	l.Allow("c1")
+	if !l.Degraded() {
+		t.Fatal("expected a store error to mark the limiter degraded")
+	}
+	now = now.Add(degradedLogInterval)
+	if l.Degraded() {
+		t.Fatal("expected the degraded state to clear after the interval")
+	}
+}
@@ -0,0 +1,106 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyDimension is one request attribute the limiter key can be composed of.
+type KeyDimension string
+
+const (
+	DimensionClient     KeyDimension = "client"
+	DimensionIP         KeyDimension = "ip"
+	DimensionMethod     KeyDimension = "method"
+	DimensionRouteGroup KeyDimension = "route-group"
+	DimensionTier       KeyDimension = "tier"
+)
+
+// WithKeyDimensions composes request keys from the given dimensions, in
+// order, as "rate:client=<c>:method=<m>:..."; dimensions the request leaves
+// empty are omitted. Requests agreeing on every dimension share a counter, so
+// e.g. ip alone limits per address across clients. Configs are still chosen
+// by client and tier. This replaces the KeyBuilder and the scope and class
+// suffixes for request keys, including the ones Peek and Reset use; group
+// keys are unaffected. Dimensions that split a client's budget (ip, method,
+// or none naming the client) leave Peek, PeekMany, Reset and ResetWindow no
+// single key to act on, so they return ErrSplitBudget.
+func WithKeyDimensions(dims ...KeyDimension) Option {
+	return func(l *Limiter) {
+		l.dimensions = dims
+	}
+}
+
+// ParseKeyDimensions parses a comma-separated list such as
+// "client,method,route-group", rejecting unknown and repeated dimensions.
+func ParseKeyDimensions(s string) ([]KeyDimension, error) {
+	var dims []KeyDimension
+	seen := make(map[KeyDimension]bool)
+	for _, f := range strings.Split(s, ",") {
+		d := KeyDimension(strings.TrimSpace(f))
+		switch d {
+		case DimensionClient, DimensionIP, DimensionMethod, DimensionRouteGroup, DimensionTier:
+		default:
+			return nil, fmt.Errorf("unknown key dimension %q", d)
+		}
+		if seen[d] {
+			return nil, fmt.Errorf("key dimension %q listed twice", d)
+		}
+		seen[d] = true
+		dims = append(dims, d)
+	}
+	return dims, nil
+}
+
+// ErrSplitBudget is returned by the per-client Peek and Reset methods when
+// the key dimensions spread a client's budget over several keys.
+var ErrSplitBudget = errors.New("limiter: key dimensions split the client's budget")
+
+// splitBudget reports whether the key dimensions give a client more than one
+// main budget key.
+func (l *Limiter) splitBudget() bool {
+	if len(l.dimensions) == 0 {
+		return false
+	}
+	client := false
+	for _, d := range l.dimensions {
+		switch d {
+		case DimensionIP, DimensionMethod:
+			return true
+		case DimensionClient:
+			client = true
+		}
+	}
+	return !client
+}
+
+func (req Request) dimension(d KeyDimension) string {
+	switch d {
+	case DimensionClient:
+		return req.Client
+	case DimensionIP:
+		return req.IP
+	case DimensionMethod:
+		return req.Method
+	case DimensionRouteGroup:
+		return req.Scope
+	case DimensionTier:
+		return req.Class
+	}
+	return ""
+}
+
+func dimensionKey(req Request, dims []KeyDimension) string {
+	var b strings.Builder
+	b.WriteString("rate")
+	for _, d := range dims {
+		if v := req.dimension(d); v != "" {
+			b.WriteString(":")
+			b.WriteString(string(d))
+			b.WriteString("=")
+			b.WriteString(escapeKeyPart(v))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestKeyDimensions(t *testing.T) {
+	req := Request{Client: "c1", Scope: "api", Class: "pro", IP: "10.0.0.1", Method: "GET"}
+
+	tests := []struct {
+		dims []KeyDimension
+		want string
+	}{
+		{[]KeyDimension{DimensionClient}, "rate:client=c1"},
+		{[]KeyDimension{DimensionClient, DimensionMethod, DimensionRouteGroup}, "rate:client=c1:method=GET:route-group=api"},
+		{[]KeyDimension{DimensionRouteGroup, DimensionClient}, "rate:route-group=api:client=c1"},
+		{[]KeyDimension{DimensionIP}, "rate:ip=10.0.0.1"},
+		{[]KeyDimension{DimensionTier, DimensionIP}, "rate:tier=pro:ip=10.0.0.1"},
+	}
+	for _, tt := range tests {
+		l := New(memory.NewMemoryStore(), WithKeyDimensions(tt.dims...), WithNamespace("prod"))
+		if got := l.keyForRequest(req, config.ClientConfig{}, time.Now()); got != "prod:"+tt.want {
+			t.Errorf("%v: key = %q, want %q", tt.dims, got, "prod:"+tt.want)
+		}
+	}
+
+	l := New(memory.NewMemoryStore(), WithKeyDimensions(DimensionClient, DimensionRouteGroup))
+	if got := l.keyForRequest(Request{Client: "c1"}, config.ClientConfig{}, time.Now()); got != "rate:client=c1" {
+		t.Errorf("expected empty dimensions omitted, got %q", got)
+	}
+}
+
+func TestKeyDimensionsCounters(t *testing.T) {
+	l := New(memory.NewMemoryStore(),
+		WithDefault(config.ClientConfig{Limit: 1, Window: time.Minute}),
+		WithKeyDimensions(DimensionClient, DimensionMethod),
+	)
+	allow := func(req Request) bool {
+		t.Helper()
+		res, err := l.AllowRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Allowed
+	}
+
+	// Client and method each get a counter; the unlisted scope and IP do not
+	// split it.
+	if !allow(Request{Client: "c1", Method: "GET", Scope: "a", IP: "10.0.0.1"}) {
+		t.Fatal("expected first GET allowed")
+	}
+	if allow(Request{Client: "c1", Method: "GET", Scope: "b", IP: "10.0.0.2"}) {
+		t.Fatal("expected GETs in other scopes and from other IPs to share the counter")
+	}
+	if !allow(Request{Client: "c1", Method: "POST"}) {
+		t.Fatal("expected POST counted separately")
+	}
+	if !allow(Request{Client: "c2", Method: "GET"}) {
+		t.Fatal("expected another client counted separately")
+	}
+
+	perIP := New(memory.NewMemoryStore(),
+		WithDefault(config.ClientConfig{Limit: 1, Window: time.Minute}),
+		WithKeyDimensions(DimensionIP),
+	)
+	if res, _ := perIP.AllowRequest(Request{Client: "c1", IP: "10.0.0.1"}); !res.Allowed {
+		t.Fatal("expected first request from the IP allowed")
+	}
+	if res, _ := perIP.AllowRequest(Request{Client: "c2", IP: "10.0.0.1"}); res.Allowed {
+		t.Fatal("expected clients behind one IP to share the counter")
+	}
+}
+
+func TestKeyDimensionsPeekAndReset(t *testing.T) {
+	l := New(memory.NewMemoryStore(),
+		WithDefault(config.ClientConfig{Limit: 2, Window: time.Minute}),
+		WithKeyDimensions(DimensionClient, DimensionRouteGroup),
+	)
+	l.AllowRequest(Request{Client: "c1"})
+	l.AllowRequest(Request{Client: "c1", Scope: "reports"})
+
+	if res, err := l.Peek("c1"); err != nil || res.Count != 1 {
+		t.Fatalf("expected Peek to read the main budget, got %+v, %v", res, err)
+	}
+	if results, err := l.PeekMany([]string{"c1"}); err != nil || results[0].Count != 1 {
+		t.Fatalf("expected PeekMany to read the main budget, got %+v, %v", results, err)
+	}
+	if err := l.Reset("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := l.Peek("c1"); res.Count != 0 {
+		t.Fatalf("expected Reset to clear the main budget, got %+v", res)
+	}
+	l.AllowRequest(Request{Client: "c1"})
+	if err := l.ResetWindow("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := l.Peek("c1"); res.Count != 0 {
+		t.Fatalf("expected ResetWindow to clear the main budget, got %+v", res)
+	}
+	if res, _ := l.CheckRequest(Request{Client: "c1", Scope: "reports"}); res.Count != 1 {
+		t.Fatalf("expected scoped budgets untouched, got %+v", res)
+	}
+
+	split := New(memory.NewMemoryStore(), WithKeyDimensions(DimensionClient, DimensionIP))
+	if _, err := split.Peek("c1"); err != ErrSplitBudget {
+		t.Fatalf("expected ErrSplitBudget from Peek, got %v", err)
+	}
+	if _, err := split.PeekMany([]string{"c1"}); err != ErrSplitBudget {
+		t.Fatalf("expected ErrSplitBudget from PeekMany, got %v", err)
+	}
+	if err := split.Reset("c1"); err != ErrSplitBudget {
+		t.Fatalf("expected ErrSplitBudget from Reset, got %v", err)
+	}
+	if err := split.ResetWindow("c1"); err != ErrSplitBudget {
+		t.Fatalf("expected ErrSplitBudget from ResetWindow, got %v", err)
+	}
+}
+
+func TestParseKeyDimensions(t *testing.T) {
+	dims, err := ParseKeyDimensions("client, method,route-group")
+	if err != nil || len(dims) != 3 || dims[0] != DimensionClient || dims[2] != DimensionRouteGroup {
+		t.Fatalf("unexpected dimensions %v, %v", dims, err)
+	}
+	for _, s := range []string{"client,path", "client,client", ""} {
+		if _, err := ParseKeyDimensions(s); err == nil {
+			t.Errorf("expected %q rejected", s)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DecisionEvent describes one rate limit decision made by Allow or Acquire.
+type DecisionEvent struct {
+	Time   time.Time
+	Client string
+	Scope  string
+	Result Result
+}
+
+// EventSink receives decision events, e.g. for metrics, log pipelines or
+// auditing. Emit runs on the request path, so it should hand slow work off.
+type EventSink interface {
+	Emit(e DecisionEvent) error
+}
+
+// WithEventSink sends every decision to sink. Emit errors are logged at Warn
+// and never change the decision. Use MultiSink for more than one sink.
+func WithEventSink(sink EventSink) Option {
+	return func(l *Limiter) {
+		l.sink = sink
+	}
+}
+
+func (l *Limiter) emit(req Request, res Result) {
+	if l.sink == nil {
+		return
+	}
+	e := DecisionEvent{Time: l.now(), Client: req.Client, Scope: requestScope(req), Result: res}
+	if err := l.sink.Emit(e); err != nil {
+		l.logger.Warn("decision event sink failed", "error", err, "client", req.Client)
+	}
+}
+
+// MultiSink sends every event to each of its sinks in order. A sink that
+// fails or panics does not stop the others; the failures are joined into the
+// returned error.
+type MultiSink struct {
+	sinks []EventSink
+}
+
+func NewMultiSink(sinks ...EventSink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+func (m *MultiSink) Emit(e DecisionEvent) error {
+	var errs []error
+	for i, sink := range m.sinks {
+		if err := emitIsolated(sink, e); err != nil {
+			errs = append(errs, fmt.Errorf("sink %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func emitIsolated(sink EventSink, e DecisionEvent) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return sink.Emit(e)
+}
+
+// LogSink logs each decision at Info, e.g. as an audit trail.
+type LogSink struct {
+	Logger *slog.Logger
+}
+
+func (s LogSink) Emit(e DecisionEvent) error {
+	s.Logger.Info("rate limit decision",
+		"client", e.Client,
+		"scope", e.Scope,
+		"allowed", e.Result.Allowed,
+		"reason", e.Result.Reason,
+		"count", e.Result.Count,
+		"remaining", e.Result.Remaining,
+	)
+	return nil
+}
@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+type recordingSink struct {
+	events []DecisionEvent
+}
+
+func (s *recordingSink) Emit(e DecisionEvent) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+type failingSink struct{}
+
+func (failingSink) Emit(DecisionEvent) error { return errors.New("audit backend down") }
+
+type panickingSink struct{}
+
+func (panickingSink) Emit(DecisionEvent) error { panic("sink bug") }
+
+func TestMultiSink(t *testing.T) {
+	var buf bytes.Buffer
+	first, last := &recordingSink{}, &recordingSink{}
+	l := New(memory.NewMemoryStore(),
+		WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute, MaxConcurrent: 1}}),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+		WithEventSink(NewMultiSink(first, panickingSink{}, failingSink{}, last)),
+	)
+
+	_, release, err := l.AcquireRequest(Request{Client: "c1", Scope: "reports"})
+	if err != nil {
+		t.Fatalf("expected the decision unaffected by failing sinks, got %v", err)
+	}
+	l.AcquireRequest(Request{Client: "c1", Scope: "reports"})
+	release()
+	l.AllowResult("c1")
+	l.AllowResult("c1")
+
+	for _, sink := range []*recordingSink{first, last} {
+		if len(sink.events) != 4 {
+			t.Fatalf("expected every sink to get all 4 events, got %d", len(sink.events))
+		}
+		e := sink.events[1]
+		if e.Client != "c1" || e.Scope != "reports" || e.Result.Allowed || e.Result.Reason != ReasonConcurrency {
+			t.Fatalf("unexpected concurrency denial event %+v", e)
+		}
+		if e := sink.events[3]; e.Result.Allowed || e.Result.Reason != ReasonRateLimit || e.Time.IsZero() {
+			t.Fatalf("unexpected rate limit denial event %+v", e)
+		}
+	}
+	if n := strings.Count(buf.String(), "decision event sink failed"); n != 4 {
+		t.Fatalf("expected each failing emit logged, got %d: %s", n, buf.String())
+	}
+	if !strings.Contains(buf.String(), "sink 1: panic: sink bug") || !strings.Contains(buf.String(), "sink 2: audit backend down") {
+		t.Fatalf("expected both failures in the log, got %s", buf.String())
+	}
+}
+
+func TestLogSink(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(memory.NewMemoryStore(), WithEventSink(LogSink{Logger: slog.New(slog.NewTextHandler(&buf, nil))}))
+	l.AllowWithConfig("c1", config.ClientConfig{Limit: 5, Window: time.Minute})
+
+	if !strings.Contains(buf.String(), "rate limit decision") || !strings.Contains(buf.String(), "client=c1") ||
+		!strings.Contains(buf.String(), "allowed=true") {
+		t.Fatalf("unexpected log %s", buf.String())
+	}
+}
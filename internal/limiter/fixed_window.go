@@ -0,0 +1,75 @@
+package limiter
+
+import (
+	"math"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// windowCapacity is the most a client may use in one window: its steady limit
+// plus any burst allowance.
+func windowCapacity(cfg config.ClientConfig) int {
+	if cfg.Limit < 0 || cfg.Burst <= 0 {
+		return cfg.Limit
+	}
+	if cfg.Burst > math.MaxInt-cfg.Limit {
+		return math.MaxInt
+	}
+	return cfg.Limit + cfg.Burst
+}
+
+func limitReason(cfg config.ClientConfig) Reason {
+	if cfg.Burst > 0 {
+		return ReasonBurstExhausted
+	}
+	return ReasonRateLimit
+}
+
+// effectiveConfig applies WithUnlimitedAbove, turning limits too high to ever
+// bind into config.Unlimited so they skip the store.
+func (l *Limiter) effectiveConfig(cfg config.ClientConfig) config.ClientConfig {
+	if l.unlimitedAt > 0 && windowCapacity(cfg) >= l.unlimitedAt {
+		cfg.Limit = config.Unlimited
+	}
+	return cfg
+}
+
+// presetResult decides requests that need no counter: unlimited clients are
+// always allowed and clients with no capacity at all are always denied.
+// Unlimited results report config.Unlimited as both limit and remaining.
+func presetResult(cfg config.ClientConfig) (Result, bool) {
+	switch capacity := windowCapacity(cfg); {
+	case capacity < 0:
+		return Result{Allowed: true, Limit: config.Unlimited, Remaining: config.Unlimited}, true
+	case capacity == 0:
+		return Result{Allowed: false, Limit: 0, Reason: ReasonRateLimit}, true
+	}
+	return Result{}, false
+}
+
+// fixedWindowDecision evaluates a window that has counted count units
+// (including the current request) against limit. The request is allowed while
+// count <= limit, so count == limit is the last allowed request and reports
+// zero remaining. The arithmetic stays in int64 and remaining is clamped to
+// [0, limit] before narrowing, so it always fits in an int.
+func fixedWindowDecision(limit int, count int64) (bool, int) {
+	allowed := count <= int64(limit)
+
+	remaining := int64(limit) - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return allowed, int(remaining)
+}
+
+// narrowRemaining clamps a store-reported remaining count to [0, limit] so it
+// always fits in an int, whatever the platform's int size.
+func narrowRemaining(remaining int64, limit int) int {
+	if remaining < 0 {
+		return 0
+	}
+	if remaining > int64(limit) {
+		return limit
+	}
+	return int(remaining)
+}
@@ -0,0 +1,168 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestFixedWindowDecision(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int
+		count         int64
+		wantAllowed   bool
+		wantRemaining int
+	}{
+		{"first request", 5, 1, true, 4},
+		{"one below limit", 5, 4, true, 1},
+		{"count equals limit", 5, 5, true, 0},
+		{"count is limit plus one", 5, 6, false, 0},
+		{"far over limit", 5, 1 << 40, false, 0},
+		{"zero limit", 0, 1, false, 0},
+		{"limit of one at boundary", 1, 1, true, 0},
+		{"limit of one over boundary", 1, 2, false, 0},
+		{"max int32 limit below boundary", math.MaxInt32, math.MaxInt32 - 1, true, 1},
+		{"max int32 limit at boundary", math.MaxInt32, math.MaxInt32, true, 0},
+		{"max int32 limit over boundary", math.MaxInt32, math.MaxInt32 + 1, false, 0},
+		{"count far beyond int32", 10, math.MaxInt32 * 4, false, 0},
+		{"max int32 limit first request", math.MaxInt32, 1, true, math.MaxInt32 - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, remaining := fixedWindowDecision(tt.limit, tt.count)
+			if allowed != tt.wantAllowed {
+				t.Errorf("expected allowed %v, got %v", tt.wantAllowed, allowed)
+			}
+			if remaining != tt.wantRemaining {
+				t.Errorf("expected remaining %d, got %d", tt.wantRemaining, remaining)
+			}
+		})
+	}
+}
+
+func TestBurstAllowance(t *testing.T) {
+	l := NewLimiter(memory.NewMemoryStore(), map[string]config.ClientConfig{
+		"c1": {Limit: 3, Burst: 2, Window: time.Minute},
+		"c2": {Limit: 3, Window: time.Minute},
+	})
+
+	for i := 1; i <= 5; i++ {
+		res, err := l.AllowResult("c1")
+		if err != nil || !res.Allowed {
+			t.Fatalf("request %d: expected allowed, got %+v err=%v", i, res, err)
+		}
+		if want := i > 3; res.UsedBurst != want {
+			t.Fatalf("request %d: expected UsedBurst=%v, got %+v", i, want, res)
+		}
+		if res.Limit != 5 || res.Remaining != 5-i {
+			t.Fatalf("request %d: expected limit 5 remaining %d, got %+v", i, 5-i, res)
+		}
+	}
+	if res, _ := l.AllowResult("c1"); res.Allowed || res.UsedBurst {
+		t.Fatalf("expected denial once burst is used up, got %+v", res)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if res, _ := l.AllowResult("c2"); !res.Allowed || res.UsedBurst {
+			t.Fatalf("request %d: expected steady admit, got %+v", i, res)
+		}
+	}
+	if res, _ := l.AllowResult("c2"); res.Allowed {
+		t.Fatalf("expected denial without burst, got %+v", res)
+	}
+}
+
+func TestResetAtStableWithinWindow(t *testing.T) {
+	l := NewLimiter(memory.NewMemoryStore(), map[string]config.ClientConfig{
+		"c1": {Limit: 10, Window: time.Minute},
+	})
+
+	first, _ := l.AllowResult("c1")
+	for i := 0; i < 3; i++ {
+		time.Sleep(5 * time.Millisecond)
+		res, _ := l.AllowResult("c1")
+		if !res.ResetAt.Equal(first.ResetAt) {
+			t.Fatalf("expected reset time %v to stay constant, got %v", first.ResetAt, res.ResetAt)
+		}
+	}
+}
+
+// windowOnlyStore reports window starts but drifting expiries, like a store
+// deriving expiry from a remaining TTL.
+type windowOnlyStore struct {
+	*memory.MemoryStore
+}
+
+func (s windowOnlyStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	count, _, err := s.MemoryStore.Increment(ctx, key, ttl)
+	return count, time.Now().Add(ttl), err
+}
+
+func TestResetAtFromWindowStart(t *testing.T) {
+	l := NewLimiter(windowOnlyStore{memory.NewMemoryStore()}, map[string]config.ClientConfig{
+		"c1": {Limit: 10, Window: time.Minute},
+	})
+
+	first, _ := l.AllowResult("c1")
+	time.Sleep(5 * time.Millisecond)
+	if res, _ := l.AllowResult("c1"); !res.ResetAt.Equal(first.ResetAt) {
+		t.Fatalf("expected reset derived from window start, got %v then %v", first.ResetAt, res.ResetAt)
+	}
+}
+
+func TestNarrowRemaining(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining int64
+		limit     int
+		want      int
+	}{
+		{"within limit", 3, 5, 3},
+		{"negative", -7, 5, 0},
+		{"above limit", 9, 5, 5},
+		{"beyond int32", math.MaxInt32 * 4, math.MaxInt32, math.MaxInt32},
+		{"max int64", math.MaxInt64, 10, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := narrowRemaining(tt.remaining, tt.limit); got != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWindowCapacityOverflow(t *testing.T) {
+	cfg := config.ClientConfig{Limit: math.MaxInt - 1, Burst: 10}
+	if got := windowCapacity(cfg); got != math.MaxInt {
+		t.Fatalf("expected capacity clamped to MaxInt, got %d", got)
+	}
+	if got := windowCapacity(config.ClientConfig{Limit: 5, Burst: 3}); got != 8 {
+		t.Fatalf("expected capacity 8, got %d", got)
+	}
+}
+
+// hugeDecisionStore reports remaining counts that do not fit in an int32.
+type hugeDecisionStore struct {
+	*memory.MemoryStore
+}
+
+func (h hugeDecisionStore) IncrementWithResult(ctx context.Context, key string, n int64, limit int, ttl time.Duration) (StoreDecision, error) {
+	return StoreDecision{Allowed: true, Count: n, Remaining: math.MaxInt32 * 4, Expiry: time.Now().Add(ttl)}, nil
+}
+
+func TestStoreRemainingClampedToLimit(t *testing.T) {
+	l := NewLimiter(hugeDecisionStore{memory.NewMemoryStore()}, map[string]config.ClientConfig{
+		"c1": {Limit: math.MaxInt32, Window: time.Minute},
+	})
+	res, err := l.AllowResult("c1")
+	if err != nil || res.Remaining != math.MaxInt32 {
+		t.Fatalf("expected remaining clamped to %d, got %+v err=%v", math.MaxInt32, res, err)
+	}
+}
@@ -0,0 +1,109 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// ErrGCRAUnsupported is returned for AlgorithmGCRA decisions when the store
+// does not implement GCRAStore.
+var ErrGCRAUnsupported = errors.New("limiter: store does not support GCRA")
+
+// GCRAStore is implemented by stores that can keep a theoretical arrival time
+// (TAT) per key for AlgorithmGCRA. A missing or past TAT counts as now.
+type GCRAStore interface {
+	// ArriveGCRA advances key's TAT by n intervals and stores it if it stays
+	// within tolerance of now. It returns the TAT after any update and whether
+	// it was stored. Both steps must be atomic. A TAT may be dropped once it
+	// has passed.
+	ArriveGCRA(ctx context.Context, key string, n int64, interval, tolerance time.Duration) (tat time.Time, admitted bool, err error)
+	// GetTAT returns key's TAT, zero when it has none.
+	GetTAT(ctx context.Context, key string) (time.Time, error)
+}
+
+// gcra spaces a client's requests interval apart while letting it run up to
+// capacity requests ahead.
+type gcra struct {
+	capacity  int64
+	interval  time.Duration
+	tolerance time.Duration
+}
+
+// newGCRA spaces requests Window/Limit apart; a config with a burst but no
+// limit gets a whole window per request.
+func newGCRA(cfg config.ClientConfig) gcra {
+	g := gcra{capacity: int64(windowCapacity(cfg)), interval: cfg.Window}
+	if cfg.Limit > 0 {
+		g.interval = max(cfg.Window/time.Duration(cfg.Limit), 1)
+	}
+	g.tolerance = time.Duration(math.MaxInt64)
+	if g.capacity < int64(g.tolerance/g.interval) {
+		g.tolerance = time.Duration(g.capacity) * g.interval
+	}
+	return g
+}
+
+// used is how many requests a client with the given TAT is ahead by.
+func (g gcra) used(tat, now time.Time) int64 {
+	if !tat.After(now) {
+		return 0
+	}
+	return int64(math.Ceil(float64(tat.Sub(now)) / float64(g.interval)))
+}
+
+// gcraArrive decides a request from key's TAT. Only admitted requests advance
+// it. Denials report the count the request would have made and expire when
+// it would fit; admitted requests expire when the client is back to its full
+// capacity.
+func (l *Limiter) gcraArrive(ctx context.Context, key string, n int64, cfg config.ClientConfig, now time.Time) (StoreDecision, error) {
+	gs, ok := l.store.(GCRAStore)
+	if !ok {
+		return StoreDecision{}, ErrGCRAUnsupported
+	}
+	g := newGCRA(cfg)
+	tat, admitted, err := gs.ArriveGCRA(storeContext(ctx), key, n, g.interval, g.tolerance)
+	if err != nil {
+		return StoreDecision{}, err
+	}
+
+	d := StoreDecision{Allowed: admitted, Count: g.used(tat, now)}
+	if admitted {
+		d.Expiry = gcraExpiry(tat, now)
+	} else {
+		d.Count += n
+		d.Expiry = tat.Add(time.Duration(n)*g.interval - g.tolerance)
+	}
+	_, remaining := fixedWindowDecision(int(g.capacity), d.Count)
+	d.Remaining = int64(remaining)
+	return d, nil
+}
+
+// gcraGet is the read-only counterpart of gcraArrive.
+func (l *Limiter) gcraGet(ctx context.Context, key string, cfg config.ClientConfig, now time.Time) (int64, time.Time, error) {
+	gs, ok := l.store.(GCRAStore)
+	if !ok {
+		return 0, time.Time{}, ErrGCRAUnsupported
+	}
+	tat, err := gs.GetTAT(storeContext(ctx), key)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return newGCRA(cfg).used(tat, now), gcraExpiry(tat, now), nil
+}
+
+func gcraExpiry(tat, now time.Time) time.Time {
+	if !tat.After(now) {
+		return time.Time{}
+	}
+	return tat
+}
+
+// gcraConfigured reports whether cfg is decided with GCRA; configs without a
+// positive window never reach it.
+func (l *Limiter) gcraConfigured(cfg config.ClientConfig) bool {
+	return l.algorithmFor(cfg) == AlgorithmGCRA && cfg.Window > 0
+}
@@ -0,0 +1,109 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func newGCRALimiter(clock *slidingTestClock) *Limiter {
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmGCRA))
+	// One request per second, up to fifteen ahead.
+	l.SetLimit("c1", config.ClientConfig{Limit: 10, Window: 10 * time.Second, Burst: 5})
+	return l
+}
+
+func TestGCRABurstAndSpacing(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newGCRALimiter(clock)
+	start := clock.now
+
+	res, err := l.AllowResult("c1")
+	if err != nil || !res.Allowed || res.Count != 1 || res.Remaining != 14 {
+		t.Fatalf("unexpected first decision %+v, %v", res, err)
+	}
+	if want := start.Add(time.Second); !res.ResetAt.Equal(want) {
+		t.Fatalf("expected reset once the request is paid off at %v, got %v", want, res.ResetAt)
+	}
+	if got := allowedCount(t, l, 20); got != 14 {
+		t.Fatalf("expected limit+burst allowed at once, got %d", got+1)
+	}
+	res, err = l.AllowResult("c1")
+	if err != nil || res.Allowed || res.Count != 16 || res.Remaining != 0 {
+		t.Fatalf("unexpected denial %+v, %v", res, err)
+	}
+	if want := start.Add(time.Second); !res.ResetAt.Equal(want) {
+		t.Fatalf("expected reset when the next request fits at %v, got %v", want, res.ResetAt)
+	}
+
+	// Denials did not advance the TAT, so slots free one per interval.
+	clock.now = start.Add(2500 * time.Millisecond)
+	if got := allowedCount(t, l, 5); got != 2 {
+		t.Fatalf("expected 2 slots after 2.5 intervals, got %d", got)
+	}
+	if res, _ := l.AllowN("c1", "", 1); res.Allowed {
+		t.Fatalf("expected the spacing to hold, got %+v", res)
+	}
+}
+
+func TestGCRASteadyRate(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmGCRA))
+	l.SetLimit("c1", config.ClientConfig{Limit: 10, Window: 10 * time.Second})
+
+	allowedCount(t, l, 10)
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		clock.now = clock.now.Add(100 * time.Millisecond)
+		allowed += allowedCount(t, l, 1)
+	}
+	if allowed != 10 {
+		t.Fatalf("expected one request per second over 10s, got %d", allowed)
+	}
+}
+
+func TestGCRAPeekAndReset(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newGCRALimiter(clock)
+	start := clock.now
+
+	if res, err := l.Peek("c1"); err != nil || res.Remaining != 15 || !res.ResetAt.IsZero() {
+		t.Fatalf("expected a fresh client with no reset, got %+v, %v", res, err)
+	}
+
+	l.AllowN("c1", "", 12)
+	clock.now = start.Add(2 * time.Second)
+	res, err := l.Peek("c1")
+	if err != nil || res.Count != 10 || res.Remaining != 5 || !res.Allowed {
+		t.Fatalf("unexpected peek %+v, %v", res, err)
+	}
+	if want := start.Add(12 * time.Second); !res.ResetAt.Equal(want) {
+		t.Fatalf("expected reset at the TAT %v, got %v", want, res.ResetAt)
+	}
+	results, err := l.PeekMany([]string{"c1", "other"})
+	if err != nil || results[0].Remaining != 5 || results[1].Remaining != results[1].Limit {
+		t.Fatalf("unexpected peeks %+v, %v", results, err)
+	}
+
+	if err := l.ResetWindow("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := allowedCount(t, l, 20); got != 15 {
+		t.Fatalf("expected full capacity after ResetWindow, got %d", got)
+	}
+	if err := l.Reset("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := l.Peek("c1"); res.Count != 0 {
+		t.Fatalf("expected Reset to clear the TAT, got %+v", res)
+	}
+}
+
+func TestGCRAUnsupportedStore(t *testing.T) {
+	l := New(&ttlStore{}, WithAlgorithm(AlgorithmGCRA))
+	if _, err := l.AllowResult("c1"); !errors.Is(err, ErrGCRAUnsupported) {
+		t.Fatalf("expected ErrGCRAUnsupported, got %v", err)
+	}
+}
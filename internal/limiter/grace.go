@@ -0,0 +1,96 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// WithStaleGrace keeps a local copy of each counter read from the store and,
+// when the store fails, keeps deciding from that copy for up to grace after
+// the last successful read. Once the copy is older than grace, the failure
+// policy applies as usual.
+func WithStaleGrace(grace time.Duration) Option {
+	return func(l *Limiter) {
+		l.grace = &graceCache{
+			grace:   grace,
+			entries: map[string]*graceEntry{},
+		}
+	}
+}
+
+type graceCache struct {
+	mu      sync.Mutex
+	grace   time.Duration
+	entries map[string]*graceEntry
+	swept   time.Time
+}
+
+type graceEntry struct {
+	count    int64
+	expiry   time.Time
+	window   time.Duration
+	storedAt time.Time
+}
+
+func (g *graceCache) remember(key string, count int64, expiry time.Time, window time.Duration, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.entries[key] = &graceEntry{count: count, expiry: expiry, window: window, storedAt: now}
+	if now.Sub(g.swept) >= g.grace {
+		g.sweepLocked(now)
+	}
+}
+
+// sweepLocked drops entries too old to serve, so keys that are never looked
+// up again do not pile up. It runs at most once per grace.
+func (g *graceCache) sweepLocked(now time.Time) {
+	g.swept = now
+	for key, e := range g.entries {
+		if now.Sub(e.storedAt) > g.grace {
+			delete(g.entries, key)
+		}
+	}
+}
+
+// increment adds n to the cached counter for key if it is fresh enough,
+// returning the new count and its window expiry.
+func (g *graceCache) increment(key string, n int64, now time.Time) (int64, time.Time, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	e, ok := g.lookupLocked(key, now)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	e.count += n
+	return e.count, e.expiry, true
+}
+
+func (g *graceCache) get(key string, now time.Time) (int64, time.Time, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	e, ok := g.lookupLocked(key, now)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	return e.count, e.expiry, true
+}
+
+func (g *graceCache) lookupLocked(key string, now time.Time) (*graceEntry, bool) {
+	e, ok := g.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if now.Sub(e.storedAt) > g.grace {
+		delete(g.entries, key)
+		return nil, false
+	}
+	if !e.expiry.IsZero() && e.expiry.Before(now) {
+		// The cached window is over; a fresh one starts locally. A zero
+		// expiry is an unknown reset, so the count carries on.
+		e.count = 0
+		e.expiry = now.Add(e.window)
+	}
+	return e, true
+}
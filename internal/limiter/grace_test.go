@@ -0,0 +1,117 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// flakyStore wraps a memory store and fails every call while down is set.
+type flakyStore struct {
+	store *memory.MemoryStore
+	down  bool
+}
+
+func (f *flakyStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	if f.down {
+		return 0, time.Time{}, errors.New("store unavailable")
+	}
+	return f.store.Increment(ctx, key, ttl)
+}
+
+func (f *flakyStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	if f.down {
+		return 0, time.Time{}, errors.New("store unavailable")
+	}
+	return f.store.Get(ctx, key)
+}
+
+func (f *flakyStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	if f.down {
+		return false, errors.New("store unavailable")
+	}
+	return f.store.SetIfAbsent(ctx, key, count, ttl)
+}
+
+func TestStaleGrace(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Hour}}
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	newLimiter := func() (*Limiter, *flakyStore) {
+		s := &flakyStore{store: memory.NewMemoryStore()}
+		l := New(s, WithConfigs(cfgs), WithClock(clock), WithStaleGrace(30*time.Second), WithFailurePolicy(FailClosed))
+		return l, s
+	}
+
+	t.Run("outage shorter than grace is served from cache", func(t *testing.T) {
+		l, s := newLimiter()
+		l.AllowResult("c1")
+
+		s.down = true
+		now = now.Add(10 * time.Second)
+
+		res, err := l.AllowResult("c1")
+		if err != nil || !res.Allowed || res.Count != 2 || res.Remaining != 1 {
+			t.Fatalf("expected decision from cache, got %+v err=%v", res, err)
+		}
+		res, _ = l.AllowResult("c1")
+		res, _ = l.AllowResult("c1")
+		if res.Allowed || res.Reason != ReasonRateLimit {
+			t.Fatalf("expected cached counter to enforce the limit, got %+v", res)
+		}
+
+		if peek, err := l.Peek("c1"); err != nil || peek.Count != 4 {
+			t.Fatalf("expected peek from cache, got %+v err=%v", peek, err)
+		}
+	})
+	t.Run("outage longer than grace falls back to policy", func(t *testing.T) {
+		l, s := newLimiter()
+		l.AllowResult("c1")
+
+		s.down = true
+		now = now.Add(31 * time.Second)
+
+		res, err := l.AllowResult("c1")
+		if err != nil || res.Allowed {
+			t.Fatalf("expected fail-closed decision, got %+v err=%v", res, err)
+		}
+	})
+	t.Run("no cached entry falls back to policy", func(t *testing.T) {
+		l, s := newLimiter()
+		s.down = true
+
+		if res, _ := l.AllowResult("c1"); res.Allowed {
+			t.Fatalf("expected fail-closed decision, got %+v", res)
+		}
+	})
+	t.Run("without grace errors surface immediately", func(t *testing.T) {
+		s := &flakyStore{store: memory.NewMemoryStore()}
+		l := NewLimiter(s, cfgs)
+		l.AllowResult("c1")
+		s.down = true
+
+		if _, err := l.AllowResult("c1"); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestStaleGraceSweepsOldEntries(t *testing.T) {
+	now := time.Date(2025, 10, 23, 10, 0, 0, 0, time.UTC)
+	g := &graceCache{grace: time.Minute, entries: map[string]*graceEntry{}}
+
+	for i := 0; i < 100; i++ {
+		g.remember(fmt.Sprintf("rate:spoofed-%d", i), 1, now.Add(time.Minute), time.Minute, now)
+	}
+	now = now.Add(2 * time.Minute)
+	g.remember("rate:c1", 1, now.Add(time.Minute), time.Minute, now)
+	if len(g.entries) != 1 {
+		t.Fatalf("expected stale entries swept, got %d", len(g.entries))
+	}
+}
@@ -0,0 +1,78 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// ReasonGroupLimit is reported when a client is within its own limit but its
+// group's shared pool is exhausted.
+const ReasonGroupLimit Reason = "group_limit"
+
+type group struct {
+	name string
+	cfg  config.ClientConfig
+}
+
+// WithGroup makes clients draw from a shared pool limited by cfg in addition
+// to their own limits. A request is denied if either budget is exhausted. A
+// client belongs to at most one group; a later WithGroup wins.
+func WithGroup(name string, cfg config.ClientConfig, clients ...string) Option {
+	return func(l *Limiter) {
+		if l.groups == nil {
+			l.groups = map[string]group{}
+		}
+		for _, client := range clients {
+			l.groups[client] = group{name: name, cfg: cfg}
+		}
+	}
+}
+
+func (l *Limiter) keyForGroup(name string) string {
+	return l.namespaced("ratepool:" + name)
+}
+
+// allowGroup counts n units against client's group pool. ok is false when
+// the client is not in a group.
+func (l *Limiter) allowGroup(ctx context.Context, client string, n int64) (d StoreDecision, ok bool, err error) {
+	l.configMu.RLock()
+	g, ok := l.groups[client]
+	l.configMu.RUnlock()
+	if !ok {
+		return StoreDecision{}, false, nil
+	}
+
+	cfg := l.withSafeWindow("group:"+g.name, g.cfg)
+	d, err = l.incrementWithResult(ctx, l.keyForGroup(g.name), n, windowCapacity(cfg), cfg.Window)
+	return d, true, err
+}
+
+// checkGroup is the read-only counterpart of allowGroup.
+func (l *Limiter) checkGroup(ctx context.Context, client string, now time.Time) (res Result, ok bool, err error) {
+	l.configMu.RLock()
+	g, ok := l.groups[client]
+	l.configMu.RUnlock()
+	if !ok {
+		return Result{}, false, nil
+	}
+
+	count, expiry, err := l.store.Get(storeContext(ctx), l.keyForGroup(g.name))
+	if err != nil {
+		return Result{}, true, err
+	}
+	return peekResult(g.cfg, count, expiry, now), true, nil
+}
+
+// clampToGroup limits a peeked result to its group pool's state gres: a full
+// pool denies the client, otherwise the smaller remaining count wins.
+func clampToGroup(res, gres Result) Result {
+	if res.Allowed && !gres.Allowed {
+		res.Allowed, res.Remaining, res.ResetAt = false, 0, gres.ResetAt
+		res.Reason = ReasonGroupLimit
+	} else if gres.Remaining < res.Remaining {
+		res.Remaining = gres.Remaining
+	}
+	return res
+}
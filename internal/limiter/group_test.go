@@ -0,0 +1,59 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestWithGroup(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{
+		"c1": {Limit: 5, Window: time.Minute},
+		"c2": {Limit: 5, Window: time.Minute},
+		"c3": {Limit: 5, Window: time.Minute},
+	}
+	l := New(memory.NewMemoryStore(),
+		WithConfigs(cfgs),
+		WithGroup("team-a", config.ClientConfig{Limit: 6, Window: time.Minute}, "c1", "c2"),
+	)
+
+	for i := 1; i <= 3; i++ {
+		for _, client := range []string{"c1", "c2"} {
+			res, err := l.AllowResult(client)
+			if err != nil || !res.Allowed {
+				t.Fatalf("%s request %d: expected allowed, got %+v err=%v", client, i, res, err)
+			}
+		}
+	}
+
+	res, _ := l.AllowResult("c1")
+	if res.Allowed || res.Reason != ReasonGroupLimit || res.Remaining != 0 {
+		t.Fatalf("expected group pool exhaustion, got %+v", res)
+	}
+	if res.Count > int64(cfgs["c1"].Limit) {
+		t.Fatalf("expected c1 within its own limit, got count %d", res.Count)
+	}
+	if res, _ := l.Peek("c2"); res.Allowed || res.Reason != ReasonGroupLimit {
+		t.Fatalf("expected peek to report exhausted group, got %+v", res)
+	}
+
+	if res, _ := l.AllowResult("c3"); !res.Allowed || res.Remaining != 4 {
+		t.Fatalf("expected client outside the group unaffected, got %+v", res)
+	}
+}
+
+func TestWithGroupRemaining(t *testing.T) {
+	l := New(memory.NewMemoryStore(),
+		WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 10, Window: time.Minute}}),
+		WithGroup("team-a", config.ClientConfig{Limit: 3, Window: time.Minute}, "c1"),
+	)
+
+	if res, _ := l.AllowResult("c1"); res.Remaining != 2 {
+		t.Fatalf("expected remaining capped by the group pool, got %+v", res)
+	}
+	if res, _ := l.Peek("c1"); res.Remaining != 2 {
+		t.Fatalf("expected peek remaining capped by the group pool, got %+v", res)
+	}
+}
@@ -0,0 +1,73 @@
+package limiter
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Headers renders the decision as the standard rate limit headers, for HTTP
+// responses and gRPC metadata alike. Unlimited results produce none; denied
+// results add X-RateLimit-Reason and, when the window reset is known,
+// Retry-After in whole seconds.
+func (r Result) Headers() map[string]string {
+	return r.headersAt(time.Now())
+}
+
+func (r Result) headersAt(now time.Time) map[string]string {
+	h := map[string]string{}
+	if r.Limit < 0 {
+		return h
+	}
+
+	h["X-RateLimit-Limit"] = strconv.Itoa(r.Limit)
+	h["X-RateLimit-Remaining"] = strconv.Itoa(r.headerRemaining())
+	if !r.ResetAt.IsZero() {
+		h["X-RateLimit-Reset"] = strconv.FormatInt(r.ResetAt.Unix(), 10)
+	}
+
+	if r.Allowed {
+		return h
+	}
+	if r.Reason != "" {
+		h["X-RateLimit-Reason"] = string(r.Reason)
+	}
+	if secs := r.retryAfterAt(now); secs > 0 {
+		h["Retry-After"] = strconv.FormatInt(secs, 10)
+	}
+	return h
+}
+
+// RetryAfterSeconds is the Retry-After value for the result: the whole seconds
+// until the reset, at least 1, or 0 when the reset is unknown.
+func (r Result) RetryAfterSeconds() int64 {
+	return r.retryAfterAt(time.Now())
+}
+
+func (r Result) retryAfterAt(now time.Time) int64 {
+	if r.ResetAt.IsZero() {
+		return 0
+	}
+	secs := int64(math.Ceil(r.ResetAt.Sub(now).Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
+
+// headerRemaining is the whole number of units left, clamped to [0, Limit]
+// and flooring fractional budgets so clients are never promised a request
+// they cannot make.
+func (r Result) headerRemaining() int {
+	remaining := r.Remaining
+	if r.RemainingFloat != 0 {
+		remaining = int(math.Floor(r.RemainingFloat))
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	if remaining > r.Limit {
+		remaining = r.Limit
+	}
+	return remaining
+}
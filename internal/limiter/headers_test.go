@@ -0,0 +1,99 @@
+package limiter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestResultHeaders(t *testing.T) {
+	now := time.Date(2025, 10, 23, 10, 30, 0, 0, time.UTC)
+	reset := now.Add(29*time.Second + 300*time.Millisecond)
+
+	tests := []struct {
+		name string
+		res  Result
+		want map[string]string
+	}{
+		{
+			name: "allowed",
+			res:  Result{Allowed: true, Limit: 10, Remaining: 7, ResetAt: reset},
+			want: map[string]string{
+				"X-RateLimit-Limit":     "10",
+				"X-RateLimit-Remaining": "7",
+				"X-RateLimit-Reset":     "1761215429",
+			},
+		},
+		{
+			name: "denied",
+			res:  Result{Limit: 10, Remaining: 0, ResetAt: reset, Reason: ReasonRateLimit},
+			want: map[string]string{
+				"X-RateLimit-Limit":     "10",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "1761215429",
+				"X-RateLimit-Reason":    "rate_limit",
+				"Retry-After":           "30",
+			},
+		},
+		{
+			name: "denied without reset",
+			res:  Result{Limit: 2, Reason: ReasonConcurrency},
+			want: map[string]string{
+				"X-RateLimit-Limit":     "2",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reason":    "concurrency",
+			},
+		},
+		{
+			name: "denied past reset",
+			res:  Result{Limit: 1, ResetAt: now.Add(-time.Second)},
+			want: map[string]string{
+				"X-RateLimit-Limit":     "1",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "1761215399",
+				"Retry-After":           "1",
+			},
+		},
+		{
+			name: "unlimited",
+			res:  Result{Allowed: true, Limit: config.Unlimited, Remaining: config.Unlimited},
+			want: map[string]string{},
+		},
+		{
+			name: "negative remaining",
+			res:  Result{Allowed: true, Limit: 5, Remaining: -2},
+			want: map[string]string{"X-RateLimit-Limit": "5", "X-RateLimit-Remaining": "0"},
+		},
+		{
+			name: "remaining above limit",
+			res:  Result{Allowed: true, Limit: 5, Remaining: 1 << 30},
+			want: map[string]string{"X-RateLimit-Limit": "5", "X-RateLimit-Remaining": "5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.res.headersAt(now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHeaderRemaining(t *testing.T) {
+	tests := []struct {
+		res  Result
+		want int
+	}{
+		{Result{Limit: 5, Remaining: 4}, 4},
+		{Result{Limit: 5, Remaining: 2, RemainingFloat: 2.99}, 2},
+		{Result{Limit: 5, Remaining: 0, RemainingFloat: 0.4}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.res.headerRemaining(); got != tt.want {
+			t.Errorf("%+v: expected %d, got %d", tt.res, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+type Decision struct {
+	Timestamp time.Time `json:"timestamp"`
+	Allowed   bool      `json:"allowed"`
+	Count     int64     `json:"count"`
+	Remaining int       `json:"remaining"`
+}
+
+// maxHistoryClients bounds how many clients History tracks. Past it, a new
+// client replaces the one with the oldest last decision among a few picked at
+// random.
+const maxHistoryClients = 10000
+
+// historySample is how many clients are compared to pick one to forget.
+const historySample = 5
+
+// History keeps the last size decisions per client in fixed-size ring buffers.
+type History struct {
+	mu      sync.Mutex
+	size    int
+	clients map[string]*decisionRing
+}
+
+type decisionRing struct {
+	entries []Decision
+	next    int
+	full    bool
+}
+
+func NewHistory(size int) *History {
+	if size < 1 {
+		size = 1
+	}
+	return &History{size: size, clients: map[string]*decisionRing{}}
+}
+
+func (h *History) Record(client string, d Decision) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.clients[client]
+	if !ok {
+		if len(h.clients) >= maxHistoryClients {
+			h.forgetLocked()
+		}
+		r = &decisionRing{entries: make([]Decision, h.size)}
+		h.clients[client] = r
+	}
+
+	r.entries[r.next] = d
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// forgetLocked drops the client with the oldest last decision among
+// historySample clients; map iteration order makes them a random pick.
+func (h *History) forgetLocked() {
+	var oldest string
+	var oldestAt time.Time
+	n := 0
+	for client, r := range h.clients {
+		if at := r.last(); n == 0 || at.Before(oldestAt) {
+			oldest, oldestAt = client, at
+		}
+		if n++; n == historySample {
+			break
+		}
+	}
+	delete(h.clients, oldest)
+}
+
+func (r *decisionRing) last() time.Time {
+	return r.entries[(r.next+len(r.entries)-1)%len(r.entries)].Timestamp
+}
+
+// Decisions returns the recorded decisions for client, oldest first.
+func (h *History) Decisions(client string) []Decision {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.clients[client]
+	if !ok {
+		return []Decision{}
+	}
+
+	if !r.full {
+		return append([]Decision{}, r.entries[:r.next]...)
+	}
+
+	out := make([]Decision, 0, len(r.entries))
+	out = append(out, r.entries[r.next:]...)
+	out = append(out, r.entries[:r.next]...)
+	return out
+}
@@ -0,0 +1,100 @@
+package limiter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestHistoryRetainsMostRecent(t *testing.T) {
+	h := NewHistory(3)
+	for i := 1; i <= 5; i++ {
+		h.Record("c1", Decision{Count: int64(i)})
+	}
+
+	got := h.Decisions("c1")
+	if len(got) != 3 {
+		t.Fatalf("expected 3 decisions got %d", len(got))
+	}
+	for i, d := range got {
+		if d.Count != int64(i+3) {
+			t.Fatalf("position %d: expected count %d got %d", i, i+3, d.Count)
+		}
+	}
+}
+
+func TestHistoryPartiallyFilled(t *testing.T) {
+	h := NewHistory(5)
+	h.Record("c1", Decision{Count: 1})
+	h.Record("c1", Decision{Count: 2})
+
+	got := h.Decisions("c1")
+	if len(got) != 2 || got[0].Count != 1 || got[1].Count != 2 {
+		t.Fatalf("unexpected decisions: %+v", got)
+	}
+	if len(h.Decisions("unknown")) != 0 {
+		t.Fatal("expected no decisions for unknown client")
+	}
+}
+
+func TestHistoryBoundsClients(t *testing.T) {
+	h := NewHistory(2)
+	start := time.Now()
+	for i := 0; i < maxHistoryClients+100; i++ {
+		h.Record(fmt.Sprintf("c%d", i), Decision{Timestamp: start.Add(time.Duration(i) * time.Millisecond)})
+	}
+	if len(h.clients) != maxHistoryClients {
+		t.Fatalf("expected at most %d clients, got %d", maxHistoryClients, len(h.clients))
+	}
+	if got := h.Decisions(fmt.Sprintf("c%d", maxHistoryClients+99)); len(got) != 1 {
+		t.Fatalf("expected the newest client tracked, got %v", got)
+	}
+}
+
+func TestLimiterHistory(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+	if NewLimiter(memory.NewMemoryStore(), cfgs).History("c1") != nil {
+		t.Fatal("expected nil history when disabled")
+	}
+
+	l := New(memory.NewMemoryStore(), WithConfigs(cfgs), WithHistory(2))
+	for i := 0; i < 5; i++ {
+		l.Allow("c1")
+	}
+
+	got := l.History("c1")
+	if len(got) != 2 {
+		t.Fatalf("expected 2 decisions got %d", len(got))
+	}
+	if got[0].Count != 4 || got[1].Count != 5 {
+		t.Fatalf("expected counts 4,5 got %d,%d", got[0].Count, got[1].Count)
+	}
+	if got[1].Allowed || got[1].Remaining != 0 {
+		t.Fatalf("expected last decision denied with 0 remaining: %+v", got[1])
+	}
+	if got[0].Timestamp.After(got[1].Timestamp) {
+		t.Fatal("expected decisions in chronological order")
+	}
+}
+
+func TestHistoryConcurrency(t *testing.T) {
+	h := NewHistory(10)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			h.Record("c1", Decision{Count: int64(i)})
+			h.Decisions("c1")
+		}(i)
+	}
+	wg.Wait()
+
+	if len(h.Decisions("c1")) != 10 {
+		t.Fatalf("expected 10 decisions got %d", len(h.Decisions("c1")))
+	}
+}
@@ -0,0 +1,181 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// keyRecordingStore records the keys the limiter asks for.
+type keyRecordingStore struct {
+	store *memory.MemoryStore
+	keys  []string
+}
+
+func (s *keyRecordingStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	s.keys = append(s.keys, key)
+	return s.store.Increment(ctx, key, ttl)
+}
+
+func (s *keyRecordingStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	s.keys = append(s.keys, key)
+	return s.store.Get(ctx, key)
+}
+
+func (s *keyRecordingStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return s.store.SetIfAbsent(ctx, key, count, ttl)
+}
+
+func TestWithKeyBuilder(t *testing.T) {
+	now := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+	store := &keyRecordingStore{store: memory.NewMemoryStore()}
+	byDate := func(client string, cfg config.ClientConfig, now time.Time) string {
+		return fmt.Sprintf("rate:%s:%s", now.Format("2006-01-02"), client)
+	}
+	l := New(store,
+		WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Hour}}),
+		WithClock(func() time.Time { return now }),
+		WithKeyBuilder(byDate),
+	)
+
+	l.AllowResult("c1")
+	if res, _ := l.AllowResult("c1"); res.Allowed {
+		t.Fatalf("expected budget exhausted within the day, got %+v", res)
+	}
+	l.AllowScoped("c1", "reports")
+	l.Peek("c1")
+
+	now = now.Add(2 * time.Minute)
+	if res, _ := l.AllowResult("c1"); !res.Allowed {
+		t.Fatalf("expected a fresh partition on the next day, got %+v", res)
+	}
+
+	want := []string{
+		"rate:2024-05-01:c1",
+		"rate:2024-05-01:c1",
+		"rate:2024-05-01:c1:reports",
+		"rate:2024-05-01:c1",
+		"rate:2024-05-02:c1",
+	}
+	if fmt.Sprint(store.keys) != fmt.Sprint(want) {
+		t.Fatalf("expected keys %v, got %v", want, store.keys)
+	}
+}
+
+func TestDefaultKeys(t *testing.T) {
+	l := New(memory.NewMemoryStore())
+	tests := []struct {
+		req  Request
+		want string
+	}{
+		{Request{Client: "c1"}, "rate:c1"},
+		{Request{Client: "c1", Scope: "reports"}, "rate:c1:reports"},
+		{Request{Client: "c1", Class: "bot"}, "rate:c1:class=bot"},
+		{Request{Client: "c1", Scope: "reports", Class: "bot"}, "rate:c1:reports:class=bot"},
+	}
+	for _, tt := range tests {
+		if got := l.keyForRequest(tt.req, config.DefaultConfig, time.Now()); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestKeyForClient(t *testing.T) {
+	for _, client := range []string{"client-1", "", "ümlaut"} {
+		if got, want := keyForClient(client), fmt.Sprintf("rate:%s", client); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+	for client, want := range map[string]string{"a:b": "rate:a%3Ab", "100%": "rate:100%25", "a%3Ab": "rate:a%253Ab"} {
+		if got := keyForClient(client); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestClientKeysDoNotCollideWithScopes(t *testing.T) {
+	l := New(memory.NewMemoryStore())
+	scoped := l.keyForRequest(Request{Client: "a", Scope: "reports"}, config.DefaultConfig, time.Now())
+	colon := l.keyForRequest(Request{Client: "a:reports"}, config.DefaultConfig, time.Now())
+	if scoped == colon {
+		t.Fatalf("expected distinct keys, both got %q", scoped)
+	}
+
+	d := New(memory.NewMemoryStore(), WithKeyDimensions(DimensionClient, DimensionIP))
+	withIP := d.keyForRequest(Request{Client: "a", IP: "10.0.0.1"}, config.DefaultConfig, time.Now())
+	spoofed := d.keyForRequest(Request{Client: "a:ip=10.0.0.1"}, config.DefaultConfig, time.Now())
+	if withIP == spoofed {
+		t.Fatalf("expected distinct dimension keys, both got %q", withIP)
+	}
+}
+
+// TestAllowAllocs guards the hot path: the only allocation left per request
+// on the memory store is the key string itself.
+func TestAllowAllocs(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"client-1": {Limit: 1 << 30, Window: time.Minute}}
+	l := New(memory.NewMemoryStore(), WithConfigs(cfgs))
+
+	if allocs := testing.AllocsPerRun(1000, func() { l.AllowResult("client-1") }); allocs > 1 {
+		t.Fatalf("expected at most 1 alloc per Allow, got %v", allocs)
+	}
+}
+
+func BenchmarkAllow(b *testing.B) {
+	cfgs := map[string]config.ClientConfig{"client-1": {Limit: 1 << 30, Window: time.Minute}}
+	l := New(memory.NewMemoryStore(), WithConfigs(cfgs))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.AllowResult("client-1")
+	}
+}
+
+func TestWithNamespace(t *testing.T) {
+	store := &keyRecordingStore{store: memory.NewMemoryStore()}
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 5, Window: time.Minute}}
+	l := New(store, WithConfigs(cfgs), WithNamespace("prod"),
+		WithGroup("team", config.ClientConfig{Limit: 10, Window: time.Minute}, "c1"))
+
+	l.Allow("c1")
+	l.AllowScoped("c1", "reports")
+	l.Peek("c1")
+	l.PeekMany([]string{"c1"})
+
+	want := []string{"prod:rate:c1", "prod:ratepool:team", "prod:rate:c1:reports", "prod:ratepool:team", "prod:rate:c1", "prod:ratepool:team", "prod:rate:c1", "prod:ratepool:team"}
+	if fmt.Sprint(store.keys) != fmt.Sprint(want) {
+		t.Fatalf("expected keys %v, got %v", want, store.keys)
+	}
+}
+
+func TestNamespacesDoNotInterfere(t *testing.T) {
+	store := memory.NewMemoryStore()
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+	dev := New(store, WithConfigs(cfgs), WithNamespace("dev"))
+	prod := New(store, WithConfigs(cfgs), WithNamespace("prod"))
+	plain := New(store, WithConfigs(cfgs))
+
+	for i := 0; i < 3; i++ {
+		dev.Allow("c1")
+	}
+	if ok, _, _, _ := dev.Allow("c1"); ok {
+		t.Fatal("expected dev client exhausted")
+	}
+
+	for _, l := range []*Limiter{prod, plain} {
+		if res, _ := l.AllowResult("c1"); !res.Allowed || res.Count != 1 {
+			t.Fatalf("expected an untouched counter in another namespace, got %+v", res)
+		}
+	}
+
+	if err := prod.Reset("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := dev.Peek("c1"); res.Remaining != 0 {
+		t.Fatalf("expected reset in prod to leave dev alone, got %+v", res)
+	}
+}
@@ -0,0 +1,19 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// leaky reports whether cfg is queued in a leaky bucket.
+func (l *Limiter) leaky(cfg config.ClientConfig) bool {
+	return l.algorithmFor(cfg) == AlgorithmLeakyBucket && cfg.Window > 0
+}
+
+// queueDelay is how long n units just admitted to a leaky bucket, leaving
+// tokens of free room, wait for the units queued before them to drain.
+func queueDelay(cfg config.ClientConfig, tokens float64, n int64) time.Duration {
+	b := newTokenBucket(cfg)
+	return b.after(float64(b.capacity)-tokens-float64(n), cfg.Window)
+}
@@ -0,0 +1,60 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestLeakyBucketQueue(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmLeakyBucket))
+	// Drains one unit per second and queues up to five.
+	l.SetLimit("c1", config.ClientConfig{Limit: 3, Window: 3 * time.Second, Burst: 2})
+	start := clock.now
+
+	for i := 0; i < 5; i++ {
+		res, err := l.AllowResult("c1")
+		if err != nil || !res.Allowed {
+			t.Fatalf("request %d: expected queued, got %+v, %v", i, res, err)
+		}
+		if want := time.Duration(i) * time.Second; res.Delay != want || res.Count != int64(i+1) {
+			t.Fatalf("request %d: expected depth %d waiting %v, got %+v", i, i+1, want, res)
+		}
+	}
+	res, err := l.AllowResult("c1")
+	if err != nil || res.Allowed || res.Delay != 0 || res.Count != 6 {
+		t.Fatalf("expected a full queue to deny, got %+v, %v", res, err)
+	}
+	if want := start.Add(time.Second); !res.ResetAt.Equal(want) {
+		t.Fatalf("expected reset when the next unit drains at %v, got %v", want, res.ResetAt)
+	}
+
+	// AllowN queues all n units or none, and waits behind the whole queue.
+	clock.now = start.Add(2500 * time.Millisecond)
+	if res, _ := l.AllowN("c1", "", 3); res.Allowed {
+		t.Fatalf("expected 3 units not to fit in 2.5 free slots, got %+v", res)
+	}
+	if res, _ := l.AllowN("c1", "", 2); !res.Allowed || res.Delay != 2500*time.Millisecond {
+		t.Fatalf("expected 2 units queued behind 2.5, got %+v", res)
+	}
+
+	clock.now = start.Add(time.Minute)
+	if res, err := l.Peek("c1"); err != nil || res.Count != 0 || res.Remaining != 5 {
+		t.Fatalf("expected a drained queue, got %+v, %v", res, err)
+	}
+}
+
+func TestLeakyBucketDepth(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmLeakyBucket))
+	l.SetLimit("c1", config.ClientConfig{Limit: 10, Window: 10 * time.Second})
+
+	l.AllowN("c1", "", 4)
+	clock.now = clock.now.Add(1500 * time.Millisecond)
+	res, err := l.Peek("c1")
+	if err != nil || res.Count != 3 || res.Remaining != 7 {
+		t.Fatalf("expected a depth of 2.5 rounded up, got %+v, %v", res, err)
+	}
+}
@@ -1,54 +1,678 @@
 package limiter
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/Dzaakk/rate-limiter/config"
 )
 
+// Store counts requests per key in windows of ttl. A zero expiry from Increment
+// or Get means the store cannot tell when the window resets, e.g. for a key
+// without a TTL; the limiter then reports an unknown (zero) reset time. ctx is
+// the request's context, or context.Background when there is none; stores
+// with network calls abandon them once it is done.
 type Store interface {
-	Increment(key string, ttl time.Duration) (int64, time.Time, error)
-	Get(key string) (int64, time.Time, error)
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error)
+	Get(ctx context.Context, key string) (int64, time.Time, error)
+	// SetIfAbsent creates key with count in a new window of ttl unless a live
+	// window already exists, reporting whether it created one. Exactly one of
+	// several racing callers starts the window.
+	SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error)
+}
+
+// CostStore is implemented by stores that can add more than one unit per call.
+type CostStore interface {
+	IncrementBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, error)
+}
+
+// StoreDecision is a decision computed by the store itself. WindowStart is
+// set by stores that record when the window began; the limiter then derives
+// the reset time from it instead of from Expiry.
+type StoreDecision struct {
+	Allowed     bool
+	Count       int64
+	Remaining   int64
+	Expiry      time.Time
+	WindowStart time.Time
+	// RemainingFloat is the fractional remaining budget, see
+	// Result.RemainingFloat.
+	RemainingFloat float64
+	// Delay is the queueing delay, see Result.Delay.
+	Delay time.Duration
+	// Created is set when the key did not exist before this increment, as
+	// opposed to counting in or restarting a window it already held.
+	Created bool
+}
+
+// StoreEntry is a counter and its expiry as read from a store.
+type StoreEntry struct {
+	Count  int64
+	Expiry time.Time
+}
+
+// BatchStore is implemented by stores that can read many keys in one round
+// trip. Missing keys yield a zero StoreEntry.
+type BatchStore interface {
+	GetMany(ctx context.Context, keys []string) ([]StoreEntry, error)
+}
+
+// WindowStore is implemented by stores that record when each window started.
+// Reset times derived from the window start stay constant within a window,
+// unlike ones derived from a remaining TTL. created reports that key did not
+// exist before the call.
+type WindowStore interface {
+	IncrementWindow(ctx context.Context, key string, n int64, ttl time.Duration) (count int64, windowStart time.Time, created bool, err error)
+}
+
+// DecisionStore is implemented by stores that can increment and evaluate the
+// limit in a single round trip. The limiter prefers it over Increment.
+type DecisionStore interface {
+	IncrementWithResult(ctx context.Context, key string, n int64, limit int, ttl time.Duration) (StoreDecision, error)
 }
 
 type Limiter struct {
-	store   Store
-	configs map[string]config.ClientConfig
+	store         Store
+	configMu      sync.RWMutex
+	configs       map[string]config.ClientConfig
+	static        map[string]config.ClientConfig
+	external      map[string]config.ClientConfig
+	overrides     map[string]config.ClientConfig
+	defaultConfig config.ClientConfig
+	classDefaults map[string]config.ClientConfig
+	resolver      LimitResolver
+	failurePolicy FailurePolicy
+	logger        *slog.Logger
+	now           func() time.Time
+	history       *History
+	shedding      bool
+	shedThreshold float64
+	shedRandom    func() float64
+	rand          Rand
+	grace         *graceCache
+	groups        map[string]group
+	keyBuilder    KeyBuilder
+	namespace     string
+	unlimitedAt   int
+	metrics       Metrics
+	degraded      degradedLog
+	windowWarn    windowWarning
+	keyGrowth     *keyGrowth
+	standing      *goodStanding
+	sink          EventSink
+	dimensions    []KeyDimension
+	algorithm     Algorithm
+
+	inFlightMu sync.Mutex
+	inFlight   map[string]int
+}
+
+func New(s Store, opts ...Option) *Limiter {
+	l := &Limiter{
+		store:         s,
+		configs:       map[string]config.ClientConfig{},
+		external:      map[string]config.ClientConfig{},
+		overrides:     map[string]config.ClientConfig{},
+		defaultConfig: config.DefaultConfig,
+		failurePolicy: FailError,
+		logger:        slog.Default(),
+		now:           nowUTC,
+		keyBuilder:    defaultKeyBuilder,
+		rand:          globalRand{},
+		inFlight:      map[string]int{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.sanitizeConfigs()
+	if l.standing != nil {
+		l.standing.since = l.now()
+	}
+	return l
 }
 
+// sanitizeConfigs copies the configured clients and normalizes every negative
+// limit to config.Unlimited.
+func (l *Limiter) sanitizeConfigs() {
+	l.static = l.sanitizeClientConfigs(l.configs)
+	l.mergeConfigsLocked()
+
+	classDefaults := make(map[string]config.ClientConfig, len(l.classDefaults))
+	for class, cfg := range l.classDefaults {
+		classDefaults[class] = l.checkAlgorithm("class "+class, normalizeLimit(cfg))
+	}
+	l.classDefaults = classDefaults
+
+	l.defaultConfig = l.checkAlgorithm("default", normalizeLimit(l.defaultConfig))
+}
+
+func (l *Limiter) sanitizeClientConfigs(in map[string]config.ClientConfig) map[string]config.ClientConfig {
+	cfgs := make(map[string]config.ClientConfig, len(in))
+	for client, cfg := range in {
+		cfgs[client] = l.checkAlgorithm(client, normalizeLimit(cfg))
+	}
+	return cfgs
+}
+
+func normalizeLimit(cfg config.ClientConfig) config.ClientConfig {
+	if cfg.Limit < 0 {
+		cfg.Limit = config.Unlimited
+	}
+	return cfg
+}
+
+// nowUTC is the default clock. All window math and reported reset times are in
+// UTC so that instances in different time zones agree on window boundaries.
+func nowUTC() time.Time {
+	return time.Now().UTC()
+}
+
+// NewLimiter is kept for existing callers; prefer New with options.
 func NewLimiter(s Store, cfgs map[string]config.ClientConfig) *Limiter {
-	return &Limiter{store: s, configs: cfgs}
+	return New(s, WithConfigs(cfgs))
 }
 
-func keyForClient(client string) string {
-	return fmt.Sprintf("rate:%s", client)
+// ConfigFor returns the effective config for client.
+func (l *Limiter) ConfigFor(client string) config.ClientConfig {
+	if cfg, ok := l.resolve(client); ok {
+		return cfg
+	}
+	l.configMu.RLock()
+	defer l.configMu.RUnlock()
+	if cfg, ok := l.configs[client]; ok {
+		return cfg
+	}
+	return l.defaultConfig
 }
 
-func (l *Limiter) Allow(client string) (bool, int, time.Time, error) {
-	cfg, ok := l.configs[client]
+// ExportConfig returns a copy of the per-client configs, including runtime
+// changes, and the default config used for everyone else.
+func (l *Limiter) ExportConfig() (map[string]config.ClientConfig, config.ClientConfig) {
+	l.configMu.RLock()
+	defer l.configMu.RUnlock()
+	clients := make(map[string]config.ClientConfig, len(l.configs))
+	for client, cfg := range l.configs {
+		clients[client] = cfg
+	}
+	return clients, l.defaultConfig
+}
+
+// SetLimit adds or replaces the config for a single client at runtime. It
+// takes precedence over both the static and the external configs.
+func (l *Limiter) SetLimit(client string, cfg config.ClientConfig) {
+	cfg = l.checkAlgorithm(client, normalizeLimit(cfg))
+
+	l.configMu.Lock()
+	defer l.configMu.Unlock()
+	l.overrides[client] = cfg
+	l.configs[client] = cfg
+}
+
+// SetConfigs replaces the external per-client configs at once, e.g. after a
+// reload from a KV store. They are layered over the configs passed to New and
+// under any SetLimit overrides, so clients missing from cfgs fall back to
+// those rather than to the defaults.
+func (l *Limiter) SetConfigs(cfgs map[string]config.ClientConfig) {
+	sanitized := l.sanitizeClientConfigs(cfgs)
+
+	l.configMu.Lock()
+	defer l.configMu.Unlock()
+	l.external = sanitized
+	l.mergeConfigsLocked()
+}
+
+// RemoveLimit drops a client's config from every layer so it falls back to the
+// defaults.
+func (l *Limiter) RemoveLimit(client string) {
+	l.configMu.Lock()
+	defer l.configMu.Unlock()
+	delete(l.static, client)
+	delete(l.external, client)
+	delete(l.overrides, client)
+	delete(l.configs, client)
+}
+
+// mergeConfigsLocked rebuilds configs from the static, external and override
+// layers, later layers winning.
+func (l *Limiter) mergeConfigsLocked() {
+	cfgs := make(map[string]config.ClientConfig, len(l.static)+len(l.external)+len(l.overrides))
+	for _, layer := range []map[string]config.ClientConfig{l.static, l.external, l.overrides} {
+		for client, cfg := range layer {
+			cfgs[client] = cfg
+		}
+	}
+	l.configs = cfgs
+}
+
+// configForRequest prefers the resolver's config, then the client's own
+// config, then the default for the request's class, then the global default.
+func (l *Limiter) configForRequest(req Request) config.ClientConfig {
+	cfg := l.baseConfig(req)
+	if req.Limit > 0 {
+		cfg.Limit = req.Limit
+	}
+	return l.effectiveConfig(cfg)
+}
+
+func (l *Limiter) baseConfig(req Request) config.ClientConfig {
+	if cfg, ok := l.resolve(req.Client); ok {
+		return cfg
+	}
+	l.configMu.RLock()
+	defer l.configMu.RUnlock()
+	if cfg, ok := l.configs[req.Client]; ok {
+		return cfg
+	}
+	if cfg, ok := l.classDefaults[req.Class]; ok && req.Class != "" {
+		return cfg
+	}
+	return l.defaultConfig
+}
+
+// resolve asks the LimitResolver, if any, for client's config. It runs outside
+// configMu so a slow resolver does not block config updates.
+func (l *Limiter) resolve(client string) (config.ClientConfig, bool) {
+	if l.resolver == nil {
+		return config.ClientConfig{}, false
+	}
+	cfg, ok := l.resolver(client)
 	if !ok {
-		cfg = config.DefaultConfig
+		return config.ClientConfig{}, false
 	}
+	return normalizeLimit(cfg), true
+}
 
-	now := time.Now()
-	key := keyForClient(client)
+// History returns the recorded decisions for client, or nil when history is disabled.
+func (l *Limiter) History(client string) []Decision {
+	if l.history == nil {
+		return nil
+	}
+	return l.history.Decisions(client)
+}
+
+type Reason string
+
+const (
+	ReasonRateLimit   Reason = "rate_limit"
+	ReasonConcurrency Reason = "concurrency"
+	// ReasonBurstExhausted is reported instead of ReasonRateLimit for clients
+	// that have a burst allowance and used all of it.
+	ReasonBurstExhausted Reason = "burst_exhausted"
+)
+
+// Request describes a single request to be counted by the limiter.
+type Request struct {
+	Client string
+	// Scope selects a separate budget for the client; empty is the main budget.
+	Scope string
+	// Class is a caller-assigned category (e.g. a user agent class). It gets
+	// its own budget and selects per-class defaults for unconfigured clients.
+	Class string
+	// IP and Method identify the request's origin and HTTP method. They only
+	// affect the key when selected by WithKeyDimensions.
+	IP     string
+	Method string
+	// Cost is the number of units consumed; values below 1 count as 1.
+	Cost int64
+	// Limit, when positive, replaces the client's configured limit for this
+	// decision only, e.g. for a pre-authorized bulk operation. It counts
+	// against the same window. Callers must only set it for trusted requests.
+	Limit int
+	// Context, when set, is passed to the store calls made for the request.
+	Context context.Context
+}
+
+// Result describes a single rate limit decision. Reason is set when the
+// request is denied. Throttled is set for allowed requests past the client's
+// soft limit, UsedBurst for those admitted only thanks to the burst allowance.
+// Limit includes the burst allowance.
+type Result struct {
+	Allowed   bool
+	Throttled bool
+	UsedBurst bool
+	Limit     int
+	Remaining int
+	// RemainingFloat is the exact remaining budget from algorithms that track
+	// fractional units, such as token buckets, with Remaining as its floor.
+	// Integral limiters leave it zero.
+	RemainingFloat float64
+	ResetAt        time.Time
+	Count          int64
+	Reason         Reason
+	// Delay is how long an admitted request waits in a leaky bucket's queue
+	// before it should be served. Other algorithms leave it zero.
+	Delay time.Duration
+	// Uncounted is set on denials by algorithms that do not count denied
+	// requests, such as the sliding log. Count then stays at what the request
+	// would have made however often the client retries.
+	Uncounted bool
+}
+
+// AllowReason counts a request for client and reports why it was denied; the
+// reason is empty when the request is allowed.
+func (l *Limiter) AllowReason(client string) (bool, Reason, error) {
+	res, err := l.AllowResult(client)
+	return res.Allowed, res.Reason, err
+}
+
+func (l *Limiter) Allow(client string) (bool, int, time.Time, error) {
+	res, err := l.AllowResult(client)
+	return res.Allowed, res.Remaining, res.ResetAt, err
+}
+
+// AllowResult counts a request for client and returns the full decision,
+// including the raw counter value from the store.
+func (l *Limiter) AllowResult(client string) (Result, error) {
+	return l.AllowScoped(client, "")
+}
+
+// AllowScoped is like AllowResult but counts against a separate budget for
+// scope, using the client's config. An empty scope is the client's main budget.
+func (l *Limiter) AllowScoped(client, scope string) (Result, error) {
+	return l.AllowN(client, scope, 1)
+}
+
+// AllowN counts a request costing n units against the client's scoped budget.
+func (l *Limiter) AllowN(client, scope string, n int64) (Result, error) {
+	return l.AllowRequest(Request{Client: client, Scope: scope, Cost: n})
+}
+
+// AllowRequest counts req against its budget and returns the decision.
+func (l *Limiter) AllowRequest(req Request) (Result, error) {
+	res, err := l.allowConfigured(req, l.configForRequest(req))
+	if err == nil {
+		l.emit(req, res)
+	}
+	return res, err
+}
+
+// AllowWithConfig counts a request for client under cfg instead of looking up
+// the client's config, for callers that resolved it once and decide many
+// messages with it, e.g. per WebSocket connection. The same key is used as
+// by AllowResult, so both draw on one budget.
+func (l *Limiter) AllowWithConfig(client string, cfg config.ClientConfig) (Result, error) {
+	req := Request{Client: client}
+	res, err := l.allowConfigured(req, l.effectiveConfig(normalizeLimit(cfg)))
+	if err == nil {
+		l.emit(req, res)
+	}
+	return res, err
+}
+
+func (l *Limiter) allowConfigured(req Request, cfg config.ClientConfig) (Result, error) {
+	client := req.Client
+	n := req.Cost
+	if n < 1 {
+		n = 1
+	}
+	if res, ok := presetResult(cfg); ok {
+		return res, nil
+	}
+	cfg = l.withSafeWindow(client, cfg)
+
+	now := l.now()
+	key := l.keyForRequest(req, cfg, now)
 	ttl := cfg.Window
+	capacity := windowCapacity(cfg)
+
+	d, err := l.countKey(req.Context, key, n, cfg, now)
+	if err != nil {
+		if canceled(req.Context) {
+			return Result{}, err
+		}
+		count, expiry, ok := l.graceIncrement(key, n, now)
+		if !ok {
+			return l.onStoreError(client, cfg, err)
+		}
+		l.logger.Warn("rate limiter store error, serving from local cache", "error", err, "client", client)
+		allowed, remaining := fixedWindowDecision(capacity, count)
+		d = StoreDecision{Allowed: allowed, Count: count, Remaining: int64(remaining), Expiry: expiry}
+	} else {
+		if d.Created {
+			l.keyCreated(now)
+		}
+		if l.grace != nil {
+			l.grace.remember(key, d.Count, d.Expiry, ttl, now)
+		}
+	}
+	counter, expiry := d.Count, d.Expiry
+
+	reason := limitReason(cfg)
+	if d.Allowed {
+		gd, ok, err := l.allowGroup(req.Context, client, n)
+		if err != nil {
+			if canceled(req.Context) {
+				return Result{}, err
+			}
+			return l.onStoreError(client, cfg, err)
+		}
+		if ok && !gd.Allowed {
+			d.Allowed, d.Remaining, d.RemainingFloat = false, 0, 0
+			expiry = gd.Expiry
+			reason = ReasonGroupLimit
+		} else if ok && gd.Remaining < d.Remaining {
+			d.Remaining, d.RemainingFloat = gd.Remaining, 0
+		}
+	}
+
+	res := Result{
+		Allowed:        d.Allowed,
+		Limit:          capacity,
+		Remaining:      narrowRemaining(d.Remaining, capacity),
+		Count:          counter,
+		RemainingFloat: d.RemainingFloat,
+	}
+	if !res.Allowed {
+		res.Reason = reason
+		if reason != ReasonGroupLimit && l.standing != nil && l.standing.grant(client, now) {
+			res.Allowed, res.Reason, res.UsedBurst = true, "", true
+		}
+	} else if l.shouldShed(counter-n, capacity) {
+		res.Allowed = false
+		res.Reason = ReasonLoadShed
+	} else {
+		res.Throttled = cfg.SoftLimit > 0 && counter > int64(cfg.SoftLimit)
+		res.UsedBurst = counter > int64(cfg.Limit)
+	}
+	res.Uncounted = !res.Allowed && !l.countsDenials(cfg)
+
+	if l.history != nil {
+		l.history.Record(client, Decision{
+			Timestamp: now,
+			Allowed:   res.Allowed,
+			Count:     res.Count,
+			Remaining: res.Remaining,
+		})
+	}
+
+	if res.Allowed {
+		res.Delay = d.Delay
+	}
+	res.ResetAt = resetAt(expiry, now)
+
+	return res, nil
+}
+
+// Check reports whether client would be allowed without consuming any quota.
+func (l *Limiter) Check(client string) (bool, int, time.Time, error) {
+	res, err := l.CheckScoped(client, "")
+	return res.Allowed, res.Remaining, res.ResetAt, err
+}
+
+// CheckScoped is the read-only counterpart of AllowScoped.
+func (l *Limiter) CheckScoped(client, scope string) (Result, error) {
+	return l.CheckRequest(Request{Client: client, Scope: scope})
+}
 
-	counter, expiry, err := l.store.Increment(key, ttl)
+// CheckRequest is the read-only counterpart of AllowRequest.
+func (l *Limiter) CheckRequest(req Request) (Result, error) {
+	client := req.Client
+	cfg := l.configForRequest(req)
+	if res, ok := presetResult(cfg); ok {
+		return res, nil
+	}
+	cfg = l.withSafeWindow(client, cfg)
+	now := l.now()
+
+	key := l.keyForRequest(req, cfg, now)
+	counter, expiry, err := l.getKey(req.Context, key, cfg, now)
 	if err != nil {
-		return true, cfg.Limit, time.Time{}, err
+		if canceled(req.Context) {
+			return Result{}, err
+		}
+		var ok bool
+		if l.grace != nil {
+			counter, expiry, ok = l.grace.get(key, now)
+		}
+		if !ok {
+			return l.onStoreError(client, cfg, err)
+		}
+	} else if l.grace != nil {
+		l.grace.remember(key, counter, expiry, cfg.Window, now)
+	}
+
+	res := peekResult(cfg, counter, expiry, now)
+
+	gres, ok, err := l.checkGroup(req.Context, client, now)
+	if err != nil {
+		if canceled(req.Context) {
+			return Result{}, err
+		}
+		return l.onStoreError(client, cfg, err)
+	}
+	if ok {
+		res = clampToGroup(res, gres)
+	}
+	res.Uncounted = !res.Allowed && !l.countsDenials(cfg)
+	return res, nil
+}
+
+func peekResult(cfg config.ClientConfig, counter int64, expiry, now time.Time) Result {
+	if res, ok := presetResult(cfg); ok {
+		return res
+	}
+	capacity := windowCapacity(cfg)
+	_, remaining := fixedWindowDecision(capacity, counter)
+	res := Result{
+		Allowed:   remaining > 0,
+		Limit:     capacity,
+		Remaining: remaining,
+		Count:     counter,
+	}
+	if !res.Allowed {
+		res.Reason = limitReason(cfg)
 	}
+	res.ResetAt = resetAt(expiry, now)
+	return res
+}
 
-	allowed := counter <= int64(cfg.Limit)
-	remaining := cfg.Limit - int(counter)
-	if remaining < 0 {
-		remaining = 0
+// resetAt is the reset time to report for a window expiring at expiry: zero
+// when the store did not know it or the window is already over, so headers
+// never carry a reset in the past.
+func resetAt(expiry, now time.Time) time.Time {
+	if expiry.IsZero() || expiry.Before(now) {
+		return time.Time{}
 	}
+	return expiry.UTC()
+}
 
-	if expiry.Before(now) {
-		return allowed, remaining, time.Time{}, nil
+func (l *Limiter) graceIncrement(key string, n int64, now time.Time) (int64, time.Time, bool) {
+	if l.grace == nil {
+		return 0, time.Time{}, false
+	}
+	return l.grace.increment(key, n, now)
+}
+
+func (l *Limiter) incrementWithResult(ctx context.Context, key string, n int64, limit int, ttl time.Duration) (StoreDecision, error) {
+	if ds, ok := l.store.(DecisionStore); ok {
+		d, err := ds.IncrementWithResult(storeContext(ctx), key, n, limit, ttl)
+		if err == nil && !d.WindowStart.IsZero() {
+			d.Expiry = d.WindowStart.Add(ttl)
+		}
+		return d, err
 	}
 
-	return allowed, remaining, expiry, nil
+	var (
+		counter     int64
+		expiry      time.Time
+		windowStart time.Time
+		created     bool
+		err         error
+	)
+	if ws, ok := l.store.(WindowStore); ok {
+		counter, windowStart, created, err = ws.IncrementWindow(storeContext(ctx), key, n, ttl)
+		if !windowStart.IsZero() {
+			expiry = windowStart.Add(ttl)
+		}
+	} else {
+		counter, expiry, err = l.increment(ctx, key, n, ttl)
+	}
+	if err != nil {
+		return StoreDecision{}, err
+	}
+
+	allowed, remaining := fixedWindowDecision(limit, counter)
+	return StoreDecision{
+		Allowed:     allowed,
+		Count:       counter,
+		Remaining:   int64(remaining),
+		Expiry:      expiry,
+		WindowStart: windowStart,
+		Created:     created,
+	}, nil
+}
+
+func (l *Limiter) increment(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, error) {
+	if n == 1 {
+		return l.store.Increment(storeContext(ctx), key, ttl)
+	}
+	if cs, ok := l.store.(CostStore); ok {
+		return cs.IncrementBy(storeContext(ctx), key, n, ttl)
+	}
+
+	var (
+		counter int64
+		expiry  time.Time
+		err     error
+	)
+	for i := int64(0); i < n; i++ {
+		counter, expiry, err = l.store.Increment(storeContext(ctx), key, ttl)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+	return counter, expiry, nil
+}
+
+// storeContext is the context passed to Store methods: ctx, or Background
+// for calls made without a request context.
+func storeContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// canceled reports whether a store error may be down to ctx ending. Such
+// errors are returned as is: nobody is waiting for the decision, so neither
+// the local cache nor the failure policy should act on them.
+func canceled(ctx context.Context) bool {
+	return ctx != nil && ctx.Err() != nil
+}
+
+func (l *Limiter) onStoreError(client string, cfg config.ClientConfig, err error) (Result, error) {
+	l.degraded.lastError.Store(l.now().UnixNano())
+	capacity := windowCapacity(cfg)
+	switch l.failurePolicy {
+	case FailOpen:
+		l.failedOpen(client, err)
+		return Result{Allowed: true, Limit: capacity, Remaining: capacity}, nil
+	case FailClosed:
+		l.logger.Warn("rate limiter store error, failing closed", "error", err, "client", client)
+		return Result{Allowed: false, Limit: capacity, Reason: ReasonRateLimit}, nil
+	default:
+		return Result{Allowed: true, Limit: capacity, Remaining: capacity}, err
+	}
 }
@@ -1,6 +1,7 @@
 package limiter
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -11,24 +12,128 @@ import (
 
 type mockStoreError struct{}
 
-func (m *mockStoreError) Increment(key string, ttl time.Duration) (int64, time.Time, error) {
+func (m *mockStoreError) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
 	return 0, time.Time{}, errors.New("mock increment error")
 }
-func (m *mockStoreError) Get(key string) (int64, time.Time, error) {
+func (m *mockStoreError) Get(ctx context.Context, key string) (int64, time.Time, error) {
 	return 0, time.Time{}, errors.New("mock get error")
 }
 
+func (m *mockStoreError) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return false, errors.New("mock set error")
+}
+
 type mockStorePastExpiry struct {
 	count int64
 }
 
-func (m *mockStorePastExpiry) Increment(key string, ttl time.Duration) (int64, time.Time, error) {
+func (m *mockStorePastExpiry) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
 	return m.count + 1, time.Now().Add(-1 * time.Second), nil
 }
-func (m *mockStorePastExpiry) Get(key string) (int64, time.Time, error) {
+func (m *mockStorePastExpiry) Get(ctx context.Context, key string) (int64, time.Time, error) {
 	return m.count, time.Now().Add(-1 * time.Second), nil
 }
 
+func (m *mockStorePastExpiry) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return false, nil
+}
+
+// mockStoreZeroExpiry counts like a store whose keys carry no TTL, reporting a
+// zero expiry, and fails every call while down is set.
+type mockStoreZeroExpiry struct {
+	count int64
+	down  bool
+}
+
+func (m *mockStoreZeroExpiry) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	if m.down {
+		return 0, time.Time{}, errors.New("store unavailable")
+	}
+	m.count++
+	return m.count, time.Time{}, nil
+}
+func (m *mockStoreZeroExpiry) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	return m.count, time.Time{}, nil
+}
+
+func (m *mockStoreZeroExpiry) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return false, nil
+}
+
+// mockWindowStoreZeroStart is a WindowStore that cannot report window starts.
+type mockWindowStoreZeroStart struct {
+	mockStoreZeroExpiry
+}
+
+func (m *mockWindowStoreZeroStart) IncrementWindow(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, bool, error) {
+	m.count += n
+	return m.count, time.Time{}, m.count == n, nil
+}
+
+func TestZeroExpiryIsUnknownReset(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+
+	for _, tc := range []struct {
+		name  string
+		store Store
+	}{
+		{"increment", &mockStoreZeroExpiry{}},
+		{"window store", &mockWindowStoreZeroStart{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLimiter(tc.store, cfgs)
+
+			res, err := l.AllowResult("c1")
+			if err != nil || !res.Allowed || res.Remaining != 1 || !res.ResetAt.IsZero() {
+				t.Fatalf("expected an allowed result with an unknown reset, got %+v err=%v", res, err)
+			}
+			l.AllowResult("c1")
+			res, _ = l.AllowResult("c1")
+			if res.Allowed || !res.ResetAt.IsZero() {
+				t.Fatalf("expected a denial with an unknown reset, got %+v", res)
+			}
+
+			h := res.Headers()
+			if h["X-RateLimit-Limit"] != "2" || h["X-RateLimit-Remaining"] != "0" {
+				t.Fatalf("unexpected headers %v", h)
+			}
+			if v, ok := h["X-RateLimit-Reset"]; ok {
+				t.Errorf("expected no reset header, got %q", v)
+			}
+			if v, ok := h["Retry-After"]; ok {
+				t.Errorf("expected no Retry-After, got %q", v)
+			}
+
+			if peek, err := l.Peek("c1"); err != nil || peek.Count != 3 || !peek.ResetAt.IsZero() {
+				t.Fatalf("expected peek with an unknown reset, got %+v err=%v", peek, err)
+			}
+		})
+	}
+}
+
+func TestZeroExpiryGraceKeepsCounting(t *testing.T) {
+	now := time.Now()
+	store := &mockStoreZeroExpiry{}
+	l := New(store,
+		WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}),
+		WithClock(func() time.Time { return now }),
+		WithStaleGrace(time.Minute),
+		WithFailurePolicy(FailClosed),
+	)
+
+	l.AllowResult("c1")
+	store.down = true
+	now = now.Add(time.Second)
+
+	res, err := l.AllowResult("c1")
+	if err != nil || !res.Allowed || res.Count != 2 || !res.ResetAt.IsZero() {
+		t.Fatalf("expected the cached count to carry on, got %+v err=%v", res, err)
+	}
+	if res, _ := l.AllowResult("c1"); res.Allowed {
+		t.Fatalf("expected the cached count to enforce the limit, got %+v", res)
+	}
+}
+
 func TestAllow(t *testing.T) {
 	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Second}}
 
@@ -109,3 +214,631 @@ func TestLimiterConcurrency(t *testing.T) {
 		t.Fatalf("expected %d allowed got %d", N, allowedCount)
 	}
 }
+
+func TestAllowResult(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Minute}}
+
+	t.Run("count matches number of calls", func(t *testing.T) {
+		l := NewLimiter(memory.NewMemoryStore(), cfgs)
+		for i := 1; i <= 5; i++ {
+			res, err := l.AllowResult("c1")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Count != int64(i) {
+				t.Fatalf("call %d: expected count %d got %d", i, i, res.Count)
+			}
+			if res.Limit != 3 {
+				t.Fatalf("expected limit 3 got %d", res.Limit)
+			}
+			if res.Allowed != (i <= 3) {
+				t.Fatalf("call %d: unexpected allowed %v", i, res.Allowed)
+			}
+		}
+	})
+	t.Run("matches Allow", func(t *testing.T) {
+		a := NewLimiter(memory.NewMemoryStore(), cfgs)
+		b := NewLimiter(memory.NewMemoryStore(), cfgs)
+		for i := 0; i < 5; i++ {
+			ok, remaining, _, _ := a.Allow("c1")
+			res, _ := b.AllowResult("c1")
+			if ok != res.Allowed || remaining != res.Remaining {
+				t.Fatalf("call %d: Allow=(%v,%d) AllowResult=(%v,%d)", i, ok, remaining, res.Allowed, res.Remaining)
+			}
+		}
+	})
+	t.Run("store error", func(t *testing.T) {
+		l := NewLimiter(&mockStoreError{}, cfgs)
+		res, err := l.AllowResult("c1")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if res.Count != 0 || !res.Allowed {
+			t.Fatalf("unexpected result on error: %+v", res)
+		}
+	})
+}
+
+func TestAllowScoped(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	l := NewLimiter(memory.NewMemoryStore(), cfgs)
+
+	if res, _ := l.AllowScoped("c1", "reports"); !res.Allowed {
+		t.Fatal("expected first reports request allowed")
+	}
+	if res, _ := l.AllowScoped("c1", "reports"); res.Allowed {
+		t.Fatal("expected second reports request denied")
+	}
+	if res, _ := l.AllowScoped("c1", "search"); !res.Allowed {
+		t.Fatal("expected separate scope to have its own budget")
+	}
+	if res, _ := l.AllowScoped("c1", ""); !res.Allowed {
+		t.Fatal("expected empty scope to use the main budget")
+	}
+	if ok, _, _, _ := l.Allow("c1"); ok {
+		t.Fatal("expected Allow to share the empty-scope budget")
+	}
+}
+
+type mockStoreIncrementOnly struct {
+	calls int
+	count int64
+}
+
+func (m *mockStoreIncrementOnly) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	m.calls++
+	m.count++
+	return m.count, time.Now().Add(ttl), nil
+}
+func (m *mockStoreIncrementOnly) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	return m.count, time.Now().Add(time.Minute), nil
+}
+
+func (m *mockStoreIncrementOnly) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return false, nil
+}
+
+func TestAllowN(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 5, Window: time.Minute}}
+
+	t.Run("cost store", func(t *testing.T) {
+		l := NewLimiter(memory.NewMemoryStore(), cfgs)
+		res, _ := l.AllowN("c1", "", 3)
+		if !res.Allowed || res.Count != 3 || res.Remaining != 2 {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+		res, _ = l.AllowN("c1", "", 3)
+		if res.Allowed || res.Count != 6 {
+			t.Fatalf("expected denial at count 6: %+v", res)
+		}
+	})
+	t.Run("falls back to repeated Increment", func(t *testing.T) {
+		s := &mockStoreIncrementOnly{}
+		l := NewLimiter(s, cfgs)
+		res, _ := l.AllowN("c1", "", 4)
+		if s.calls != 4 || res.Count != 4 {
+			t.Fatalf("expected 4 increments, got calls=%d count=%d", s.calls, res.Count)
+		}
+	})
+	t.Run("non-positive cost counts as one", func(t *testing.T) {
+		l := NewLimiter(memory.NewMemoryStore(), cfgs)
+		res, _ := l.AllowN("c1", "", 0)
+		if res.Count != 1 {
+			t.Fatalf("expected count 1, got %d", res.Count)
+		}
+	})
+}
+
+func TestRequestLimitOverride(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute, Burst: 1}}
+	l := NewLimiter(memory.NewMemoryStore(), cfgs)
+
+	for i := 0; i < 3; i++ {
+		l.AcquireRequest(Request{Client: "c1"})
+	}
+	if res, _, _ := l.AcquireRequest(Request{Client: "c1"}); res.Allowed {
+		t.Fatalf("expected the configured limit to deny, got %+v", res)
+	}
+
+	res, _, _ := l.AcquireRequest(Request{Client: "c1", Limit: 10})
+	if !res.Allowed || res.Limit != 11 || res.Count != 5 {
+		t.Fatalf("expected the override to apply with the burst on the same window, got %+v", res)
+	}
+	if got := l.ConfigFor("c1").Limit; got != 2 {
+		t.Fatalf("expected the stored config untouched, got limit %d", got)
+	}
+}
+
+func TestAllowWithConfig(t *testing.T) {
+	cfg := config.ClientConfig{Limit: 3, Window: time.Minute, Burst: 1}
+	cfgs := map[string]config.ClientConfig{"c1": cfg}
+
+	t.Run("matches AllowResult with the client's config", func(t *testing.T) {
+		now := time.Now().UTC()
+		clock := func() time.Time { return now }
+		a := New(memory.NewMemoryStore(memory.WithClock(clock)), WithConfigs(cfgs), WithClock(clock))
+		b := New(memory.NewMemoryStore(memory.WithClock(clock)), WithConfigs(cfgs), WithClock(clock))
+		for i := 0; i < 6; i++ {
+			want, _ := a.AllowResult("c1")
+			got, err := b.AllowWithConfig("c1", cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Allowed != want.Allowed || got.Remaining != want.Remaining || got.Count != want.Count ||
+				got.Reason != want.Reason || !got.ResetAt.Equal(want.ResetAt) {
+				t.Fatalf("call %d: AllowWithConfig %+v, AllowResult %+v", i+1, got, want)
+			}
+		}
+	})
+	t.Run("uses the supplied config", func(t *testing.T) {
+		l := NewLimiter(memory.NewMemoryStore(), cfgs)
+		override := config.ClientConfig{Limit: 1, Window: time.Minute}
+		if res, _ := l.AllowWithConfig("c1", override); !res.Allowed || res.Limit != 1 {
+			t.Fatalf("expected the supplied limit of 1, got %+v", res)
+		}
+		if res, _ := l.AllowWithConfig("c1", override); res.Allowed {
+			t.Fatalf("expected the supplied limit to deny, got %+v", res)
+		}
+		if res, _ := l.AllowResult("c1"); !res.Allowed || res.Count != 3 {
+			t.Fatalf("expected the configured limit on the same budget, got %+v", res)
+		}
+
+		if res, _ := l.AllowWithConfig("c1", config.ClientConfig{Limit: -5}); !res.Allowed || res.Limit != config.Unlimited {
+			t.Fatalf("expected a negative limit to mean unlimited, got %+v", res)
+		}
+	})
+}
+
+func TestNilConfigs(t *testing.T) {
+	l := NewLimiter(memory.NewMemoryStore(), nil)
+
+	res, err := l.AllowResult("c1")
+	if err != nil || !res.Allowed || res.Limit != windowCapacity(config.DefaultConfig) {
+		t.Fatalf("expected the default config, got %+v err=%v", res, err)
+	}
+
+	l.SetLimit("c1", config.ClientConfig{Limit: 1, Window: time.Minute})
+	if got := l.ConfigFor("c1").Limit; got != 1 {
+		t.Fatalf("expected SetLimit to apply, got limit %d", got)
+	}
+	if res, _ := l.AllowResult("c1"); res.Allowed {
+		t.Fatalf("expected the new limit to deny, got %+v", res)
+	}
+
+	l.SetConfigs(nil)
+	l.SetLimit("c2", config.ClientConfig{Limit: 5, Window: time.Minute})
+	if clients, _ := l.ExportConfig(); len(clients) != 2 || clients["c2"].Limit != 5 {
+		t.Fatalf("expected SetLimit to work after SetConfigs(nil), got %v", clients)
+	}
+}
+
+func TestSetConfigsLayers(t *testing.T) {
+	l := NewLimiter(memory.NewMemoryStore(), map[string]config.ClientConfig{
+		"static":   {Limit: 1, Window: time.Minute},
+		"shadowed": {Limit: 2, Window: time.Minute},
+	})
+	l.SetLimit("admin", config.ClientConfig{Limit: 3, Window: time.Minute})
+
+	l.SetConfigs(map[string]config.ClientConfig{
+		"shadowed": {Limit: 20, Window: time.Minute},
+		"admin":    {Limit: 30, Window: time.Minute},
+		"kv":       {Limit: 40, Window: time.Minute},
+	})
+	for client, want := range map[string]int{"static": 1, "shadowed": 20, "admin": 3, "kv": 40} {
+		if got := l.ConfigFor(client).Limit; got != want {
+			t.Fatalf("expected %s limit %d, got %d", client, want, got)
+		}
+	}
+
+	l.SetConfigs(nil)
+	for client, want := range map[string]int{"static": 1, "shadowed": 2, "admin": 3} {
+		if got := l.ConfigFor(client).Limit; got != want {
+			t.Fatalf("expected %s limit %d after clearing external configs, got %d", client, want, got)
+		}
+	}
+	if got := l.ConfigFor("kv"); got != config.DefaultConfig {
+		t.Fatalf("expected kv client to fall back to default, got %+v", got)
+	}
+
+	l.RemoveLimit("static")
+	l.SetConfigs(nil)
+	if got := l.ConfigFor("static"); got != config.DefaultConfig {
+		t.Fatalf("expected removed client to stay removed, got %+v", got)
+	}
+}
+
+func TestLimitResolver(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	plan := map[string]int{"c1": 2}
+	l := New(memory.NewMemoryStore(),
+		WithConfigs(cfgs),
+		WithLimitResolver(func(client string) (config.ClientConfig, bool) {
+			limit, ok := plan[client]
+			return config.ClientConfig{Limit: limit, Window: time.Minute}, ok
+		}),
+	)
+
+	for i := 0; i < 2; i++ {
+		if res, _ := l.AllowResult("c1"); !res.Allowed || res.Limit != 2 {
+			t.Fatalf("request %d: expected the resolved limit of 2 to allow, got %+v", i+1, res)
+		}
+	}
+	if res, _ := l.AllowResult("c1"); res.Allowed {
+		t.Fatalf("expected the resolved limit to deny, got %+v", res)
+	}
+
+	// An upgrade takes effect on the next call, over the same window.
+	plan["c1"] = 5
+	if res, _ := l.AllowResult("c1"); !res.Allowed || res.Limit != 5 || res.Count != 4 {
+		t.Fatalf("expected the upgraded limit to allow, got %+v", res)
+	}
+	if got := l.ConfigFor("c1").Limit; got != 5 {
+		t.Fatalf("expected ConfigFor to report the resolved limit, got %d", got)
+	}
+
+	// Unresolved clients fall back to the static config.
+	delete(plan, "c1")
+	if res, _ := l.AllowResult("c1"); res.Allowed || res.Limit != 1 {
+		t.Fatalf("expected the static limit of 1 to deny, got %+v", res)
+	}
+	if res, _ := l.AllowResult("other"); !res.Allowed || res.Limit != config.DefaultConfig.Limit {
+		t.Fatalf("expected the default config for unknown clients, got %+v", res)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+	s := memory.NewMemoryStore()
+	l := NewLimiter(s, cfgs)
+
+	t.Run("never changes the counter", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			ok, remaining, _, err := l.Check("c1")
+			if err != nil || !ok || remaining != 2 {
+				t.Fatalf("unexpected check result: ok=%v remaining=%d err=%v", ok, remaining, err)
+			}
+		}
+		if count, _, _ := s.Get(context.Background(), keyForClient("c1")); count != 0 {
+			t.Fatalf("expected counter untouched, got %d", count)
+		}
+	})
+	t.Run("reflects consumed quota", func(t *testing.T) {
+		l.Allow("c1")
+		ok, remaining, resetAt, _ := l.Check("c1")
+		if !ok || remaining != 1 || resetAt.IsZero() {
+			t.Fatalf("unexpected check after one request: ok=%v remaining=%d", ok, remaining)
+		}
+
+		l.Allow("c1")
+		ok, remaining, _, _ = l.Check("c1")
+		if ok || remaining != 0 {
+			t.Fatalf("expected check to deny once exhausted: ok=%v remaining=%d", ok, remaining)
+		}
+		if count, _, _ := s.Get(context.Background(), keyForClient("c1")); count != 2 {
+			t.Fatalf("expected counter 2, got %d", count)
+		}
+	})
+	t.Run("store error", func(t *testing.T) {
+		_, _, _, err := NewLimiter(&mockStoreError{}, cfgs).Check("c1")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestLimitSentinels(t *testing.T) {
+	store := &countingStore{MemoryStore: memory.NewMemoryStore()}
+	cfgs := map[string]config.ClientConfig{
+		"blocked":   {Limit: 0, Window: time.Minute},
+		"unlimited": {Limit: config.Unlimited, Window: time.Minute},
+		"negative":  {Limit: -5, Window: time.Minute},
+	}
+	l := New(store, WithConfigs(cfgs))
+
+	if cfgs["negative"].Limit != -5 {
+		t.Fatal("expected caller's config map to be left untouched")
+	}
+	if got := l.ConfigFor("negative").Limit; got != config.Unlimited {
+		t.Fatalf("expected negative limit normalized to Unlimited, got %d", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		if res, _ := l.AllowResult("blocked"); res.Allowed || res.Remaining != 0 || res.Reason != ReasonRateLimit {
+			t.Fatalf("expected block-all, got %+v", res)
+		}
+		for _, client := range []string{"unlimited", "negative"} {
+			res, err := l.AllowResult(client)
+			if err != nil || !res.Allowed || res.Limit != config.Unlimited || res.Remaining != config.Unlimited {
+				t.Fatalf("%s: expected allow-all, got %+v err=%v", client, res, err)
+			}
+		}
+	}
+	if res, _ := l.Peek("unlimited"); !res.Allowed || res.Limit != config.Unlimited {
+		t.Fatalf("expected unlimited peek, got %+v", res)
+	}
+	if store.calls != 0 {
+		t.Fatalf("expected sentinel limits to skip the store, got %d calls", store.calls)
+	}
+
+	l.SetLimit("c3", config.ClientConfig{Limit: -7, Window: time.Minute})
+	if got := l.ConfigFor("c3").Limit; got != config.Unlimited {
+		t.Fatalf("expected negative override normalized to Unlimited, got %d", got)
+	}
+}
+
+func TestUnlimitedAboveSkipsStore(t *testing.T) {
+	store := &countingStore{MemoryStore: memory.NewMemoryStore()}
+	cfgs := map[string]config.ClientConfig{
+		"internal": {Limit: 1_000_000, Window: time.Minute},
+		"bursty":   {Limit: 500_000, Burst: 500_000, Window: time.Minute},
+		"normal":   {Limit: 5, Window: time.Minute},
+	}
+	l := New(store, WithConfigs(cfgs), WithUnlimitedAbove(1_000_000))
+
+	for _, client := range []string{"internal", "bursty"} {
+		for i := 0; i < 3; i++ {
+			res, err := l.AllowResult(client)
+			if err != nil || !res.Allowed || res.Limit != config.Unlimited || res.Remaining != config.Unlimited {
+				t.Fatalf("%s: expected allow-all, got %+v err=%v", client, res, err)
+			}
+		}
+		if res, _ := l.Peek(client); !res.Allowed || res.Remaining != config.Unlimited {
+			t.Fatalf("%s: expected unlimited peek, got %+v", client, res)
+		}
+	}
+	if store.calls != 0 {
+		t.Fatalf("expected very high limits to skip the store, got %d calls", store.calls)
+	}
+
+	for i := 0; i < 3; i++ {
+		if res, _ := l.AllowResult("normal"); !res.Allowed || res.Remaining != 4-i {
+			t.Fatalf("expected normal client counted, got %+v", res)
+		}
+	}
+	if store.calls != 3 {
+		t.Fatalf("expected one store call per normal request, got %d", store.calls)
+	}
+
+	if got := l.ConfigFor("internal").Limit; got != 1_000_000 {
+		t.Fatalf("expected configured limit kept, got %d", got)
+	}
+}
+
+func TestUnlimitedAboveDisabled(t *testing.T) {
+	store := &countingStore{MemoryStore: memory.NewMemoryStore()}
+	cfgs := map[string]config.ClientConfig{"internal": {Limit: 1_000_000, Window: time.Minute}}
+	l := New(store, WithConfigs(cfgs))
+
+	if res, _ := l.AllowResult("internal"); res.Limit != 1_000_000 || store.calls != 1 {
+		t.Fatalf("expected high limit counted without the option, got %+v after %d calls", res, store.calls)
+	}
+}
+
+// countingStore counts every store call.
+type countingStore struct {
+	*memory.MemoryStore
+	calls int
+}
+
+func (c *countingStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	c.calls++
+	return c.MemoryStore.Increment(ctx, key, ttl)
+}
+
+func (c *countingStore) IncrementWindow(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, bool, error) {
+	c.calls++
+	return c.MemoryStore.IncrementWindow(ctx, key, n, ttl)
+}
+
+func (c *countingStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	c.calls++
+	return c.MemoryStore.Get(ctx, key)
+}
+
+// decisionMemoryStore computes decisions in the store, mirroring the Redis script.
+type decisionMemoryStore struct {
+	*memory.MemoryStore
+	calls int
+}
+
+func (d *decisionMemoryStore) IncrementWithResult(ctx context.Context, key string, n int64, limit int, ttl time.Duration) (StoreDecision, error) {
+	d.calls++
+	count, expiry, err := d.IncrementBy(ctx, key, n, ttl)
+	if err != nil {
+		return StoreDecision{}, err
+	}
+	remaining := int64(limit) - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return StoreDecision{Allowed: count <= int64(limit), Count: count, Remaining: remaining, Expiry: expiry}, nil
+}
+
+func TestDecisionStoreMatchesGenericPath(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Minute}}
+	ds := &decisionMemoryStore{MemoryStore: memory.NewMemoryStore()}
+	withDecision := NewLimiter(ds, cfgs)
+	generic := NewLimiter(memory.NewMemoryStore(), cfgs)
+
+	for i := 0; i < 5; i++ {
+		a, _ := withDecision.AllowN("c1", "", 1)
+		b, _ := generic.AllowN("c1", "", 1)
+		if a.Allowed != b.Allowed || a.Remaining != b.Remaining || a.Count != b.Count || a.Reason != b.Reason {
+			t.Fatalf("request %d: decision store %+v differs from generic %+v", i, a, b)
+		}
+	}
+	if ds.calls != 5 {
+		t.Fatalf("expected IncrementWithResult to be used, got %d calls", ds.calls)
+	}
+}
+
+func TestSetLimit(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Minute}}
+	l := NewLimiter(memory.NewMemoryStore(), cfgs)
+
+	l.SetLimit("c2", config.ClientConfig{Limit: 7, Window: time.Minute})
+	if got := l.ConfigFor("c2").Limit; got != 7 {
+		t.Fatalf("expected added override 7, got %d", got)
+	}
+	if res, _ := l.AllowResult("c2"); res.Limit != 7 || res.Remaining != 6 {
+		t.Fatalf("expected Allow to use override, got %+v", res)
+	}
+
+	l.SetLimit("c1", config.ClientConfig{Limit: 10, Window: time.Minute})
+	if got := l.ConfigFor("c1").Limit; got != 10 {
+		t.Fatalf("expected changed limit 10, got %d", got)
+	}
+	if cfgs["c1"].Limit != 3 {
+		t.Fatal("expected caller's config map to be left untouched")
+	}
+
+	l.RemoveLimit("c1")
+	if got := l.ConfigFor("c1"); got != config.DefaultConfig {
+		t.Fatalf("expected default after removal, got %+v", got)
+	}
+
+}
+
+func TestSetLimitConcurrent(t *testing.T) {
+	l := NewLimiter(memory.NewMemoryStore(), map[string]config.ClientConfig{})
+	done := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		go func(i int) {
+			defer func() { done <- struct{}{} }()
+			if i%2 == 0 {
+				l.SetLimit("c1", config.ClientConfig{Limit: i + 1, Window: time.Minute})
+			} else {
+				l.RemoveLimit("c1")
+			}
+			l.Allow("c1")
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		<-done
+	}
+}
+
+func TestSoftLimitThrottles(t *testing.T) {
+	l := NewLimiter(memory.NewMemoryStore(), map[string]config.ClientConfig{
+		"c1": {Limit: 4, SoftLimit: 2, Window: time.Minute},
+	})
+
+	for i := 1; i <= 4; i++ {
+		res, err := l.AllowResult("c1")
+		if err != nil || !res.Allowed {
+			t.Fatalf("request %d: expected allowed, got %+v err=%v", i, res, err)
+		}
+		if want := i > 2; res.Throttled != want {
+			t.Fatalf("request %d: expected throttled=%v, got %v", i, want, res.Throttled)
+		}
+	}
+
+	res, _ := l.AllowResult("c1")
+	if res.Allowed || res.Throttled {
+		t.Fatalf("expected denial at hard limit, got %+v", res)
+	}
+}
+
+func TestExportConfig(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 5, Window: time.Minute}}
+	l := New(memory.NewMemoryStore(), WithConfigs(cfgs))
+	l.SetLimit("c2", config.ClientConfig{Limit: 9, Window: time.Second})
+	l.SetLimit("c1", config.ClientConfig{Limit: 6, Window: time.Minute})
+
+	clients, def := l.ExportConfig()
+	if def != config.DefaultConfig {
+		t.Fatalf("expected default config, got %+v", def)
+	}
+	if len(clients) != 2 || clients["c1"].Limit != 6 || clients["c2"].Limit != 9 {
+		t.Fatalf("expected runtime overrides exported, got %+v", clients)
+	}
+
+	clients["c3"] = config.ClientConfig{Limit: 1}
+	if l.ConfigFor("c3") != config.DefaultConfig {
+		t.Fatal("expected export to be a copy")
+	}
+}
+
+// ctxStore is a memory store failing every call whose context is done.
+type ctxStore struct {
+	store *memory.MemoryStore
+}
+
+func (c *ctxStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+	return c.store.Increment(ctx, key, ttl)
+}
+
+func (c *ctxStore) IncrementBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+	return c.store.IncrementBy(ctx, key, n, ttl)
+}
+
+func (c *ctxStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+	return c.store.Get(ctx, key)
+}
+
+func (c *ctxStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return c.store.SetIfAbsent(ctx, key, count, ttl)
+}
+
+func (c *ctxStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.store.Delete(ctx, key)
+}
+
+func (c *ctxStore) ResetKey(ctx context.Context, key string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.store.ResetKey(ctx, key, ttl)
+}
+
+func TestRequestContext(t *testing.T) {
+	l := New(&ctxStore{store: memory.NewMemoryStore()}, WithFailurePolicy(FailOpen))
+
+	if _, err := l.AllowRequest(Request{Client: "c1", Context: context.Background()}); err != nil {
+		t.Fatalf("expected a live request to pass, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := l.AllowRequest(Request{Client: "c1", Context: ctx}); err != nil {
+		t.Fatalf("expected a cancelable request to pass, got %v", err)
+	}
+
+	cancel()
+	res, err := l.AllowRequest(Request{Client: "c1", Context: ctx})
+	if !errors.Is(err, context.Canceled) || res.Allowed {
+		t.Fatalf("expected a canceled request to skip the failure policy, got %+v, %v", res, err)
+	}
+	if _, err := l.AllowRequest(Request{Client: "c1", Cost: 2, Context: ctx}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled weighted request to fail, got %v", err)
+	}
+	if _, err := l.CheckRequest(Request{Client: "c1", Context: ctx}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled check to fail, got %v", err)
+	}
+	if res, err := l.Peek("c1"); err != nil || res.Count != 2 {
+		t.Fatalf("expected requests without a context to run unbound, got %+v, %v", res, err)
+	}
+	if err := l.ResetRequest(Request{Client: "c1", Context: ctx}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled reset to fail, got %v", err)
+	}
+	if err := l.ResetRequest(Request{Client: "c1"}); err != nil {
+		t.Fatalf("expected a reset without a context to run, got %v", err)
+	}
+	if res, err := l.Peek("c1"); err != nil || res.Count != 0 {
+		t.Fatalf("expected the reset to clear the budget, got %+v, %v", res, err)
+	}
+}
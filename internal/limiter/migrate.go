@@ -0,0 +1,72 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrListUnsupported is returned by MigrateAll when the source store cannot
+// list its keys.
+var ErrListUnsupported = errors.New("limiter: store does not support listing keys")
+
+// KeyLister is implemented by stores that can enumerate their live keys.
+type KeyLister interface {
+	// Keys returns the keys starting with prefix that hold a live window.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+}
+
+// RestoreStore is implemented by stores that can recreate a window ending at a
+// known time, as Migrate needs.
+type RestoreStore interface {
+	// Restore creates key with count in a window ending at expiry unless a
+	// live window already exists or expiry has passed, reporting whether it
+	// created one. Unlike
+	// SetIfAbsent, the expiry is kept as is even where the store aligns or
+	// staggers the windows it starts itself.
+	Restore(ctx context.Context, key string, count int64, expiry time.Time) (bool, error)
+}
+
+// Migrate copies the live counters for keys from src to dst, each keeping the
+// reset time it had in src, e.g. while cutting over from the memory store to
+// Redis. Keys that are absent or expired in src, whose reset src does not
+// know, or that dst already holds are skipped, so counters written by
+// instances already on dst are never clobbered. It returns how many keys were
+// copied. Increments landing on src during the copy are not carried over, so
+// run it once traffic has moved to dst.
+func Migrate(ctx context.Context, src Store, dst RestoreStore, keys []string) (int, error) {
+	copied := 0
+	for _, key := range keys {
+		count, expiry, err := src.Get(ctx, key)
+		if err != nil {
+			return copied, fmt.Errorf("migrate %s: %w", key, err)
+		}
+		// dst checks the expiry against its own clock.
+		if count <= 0 || expiry.IsZero() {
+			continue
+		}
+		created, err := dst.Restore(ctx, key, count, expiry)
+		if err != nil {
+			return copied, fmt.Errorf("migrate %s: %w", key, err)
+		}
+		if created {
+			copied++
+		}
+	}
+	return copied, nil
+}
+
+// MigrateAll migrates every live key in src starting with prefix, e.g. the
+// limiter's namespace. src must implement KeyLister.
+func MigrateAll(ctx context.Context, src Store, dst RestoreStore, prefix string) (int, error) {
+	kl, ok := src.(KeyLister)
+	if !ok {
+		return 0, ErrListUnsupported
+	}
+	keys, err := kl.Keys(ctx, prefix)
+	if err != nil {
+		return 0, fmt.Errorf("migrate: list keys: %w", err)
+	}
+	return Migrate(ctx, src, dst, keys)
+}
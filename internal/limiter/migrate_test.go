@@ -0,0 +1,109 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+type restoredEntry struct {
+	count int64
+	ttl   time.Duration
+}
+
+// recordingRestoreStore records what Restore writes, with the expiry as the
+// time left, holding existing keys.
+type recordingRestoreStore struct {
+	entries map[string]restoredEntry
+}
+
+func (s *recordingRestoreStore) Restore(ctx context.Context, key string, count int64, expiry time.Time) (bool, error) {
+	if _, ok := s.entries[key]; ok || !expiry.After(time.Now()) {
+		return false, nil
+	}
+	s.entries[key] = restoredEntry{count, time.Until(expiry)}
+	return true, nil
+}
+
+func TestMigrate(t *testing.T) {
+	src := memory.NewMemoryStore()
+	defer src.Close()
+	for i := 0; i < 3; i++ {
+		src.Increment(context.Background(), "rate:a", time.Minute)
+	}
+	src.Increment(context.Background(), "rate:b", 10*time.Second)
+	src.Increment(context.Background(), "rate:held", time.Minute)
+	src.Increment(context.Background(), "other:c", time.Minute)
+
+	dst := &recordingRestoreStore{entries: map[string]restoredEntry{"rate:held": {count: 7, ttl: time.Minute}}}
+	copied, err := MigrateAll(context.Background(), src, dst, "rate:")
+	if err != nil || copied != 2 {
+		t.Fatalf("expected 2 keys copied, got %d, %v", copied, err)
+	}
+
+	for key, want := range map[string]restoredEntry{
+		"rate:a":    {3, time.Minute},
+		"rate:b":    {1, 10 * time.Second},
+		"rate:held": {7, time.Minute},
+	} {
+		got, ok := dst.entries[key]
+		if !ok || got.count != want.count {
+			t.Fatalf("%s: expected count %d, got %+v", key, want.count, got)
+		}
+		if got.ttl > want.ttl || got.ttl < want.ttl-time.Second {
+			t.Fatalf("%s: expected the remaining ttl of about %v, got %v", key, want.ttl, got.ttl)
+		}
+	}
+	if _, ok := dst.entries["other:c"]; ok {
+		t.Fatal("expected keys outside the prefix to stay behind")
+	}
+}
+
+func TestMigrateKeepsExpiry(t *testing.T) {
+	now := time.Date(2025, 10, 23, 10, 20, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	src := memory.NewMemoryStore(memory.WithClock(clock))
+	defer src.Close()
+	src.IncrementBy(context.Background(), "rate:c1", 4, time.Hour)
+	_, want, _ := src.Get(context.Background(), "rate:c1")
+
+	for name, dst := range map[string]*memory.MemoryStore{
+		"aligned":   memory.NewMemoryStore(memory.WithClock(clock), memory.WithAlignedWindows()),
+		"staggered": memory.NewMemoryStore(memory.WithClock(clock), memory.WithStaggeredWindows()),
+	} {
+		if copied, err := Migrate(context.Background(), src, dst, []string{"rate:c1"}); err != nil || copied != 1 {
+			t.Fatalf("%s: expected the key copied, got %d, %v", name, copied, err)
+		}
+		if count, expiry, _ := dst.Get(context.Background(), "rate:c1"); count != 4 || !expiry.Equal(want) {
+			t.Errorf("%s: expected count 4 resetting at %v, got %d at %v", name, want, count, expiry)
+		}
+		dst.Close()
+	}
+}
+
+func TestMigrateSkipsDeadKeys(t *testing.T) {
+	dst := &recordingRestoreStore{entries: map[string]restoredEntry{}}
+	keys := []string{"rate:c1"}
+
+	for _, src := range []Store{&mockStorePastExpiry{count: 2}, &mockStoreZeroExpiry{count: 2}, memory.NewMemoryStore()} {
+		if copied, err := Migrate(context.Background(), src, dst, keys); err != nil || copied != 0 {
+			t.Fatalf("%T: expected nothing copied, got %d, %v", src, copied, err)
+		}
+	}
+	if len(dst.entries) != 0 {
+		t.Fatalf("expected no writes, got %v", dst.entries)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	dst := &recordingRestoreStore{entries: map[string]restoredEntry{}}
+	if _, err := MigrateAll(context.Background(), &mockStoreError{}, dst, ""); !errors.Is(err, ErrListUnsupported) {
+		t.Fatalf("expected ErrListUnsupported, got %v", err)
+	}
+	if _, err := Migrate(context.Background(), &mockStoreError{}, dst, []string{"rate:c1"}); err == nil {
+		t.Fatal("expected the source error to be returned")
+	}
+}
@@ -0,0 +1,90 @@
+package limiter
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+type FailurePolicy int
+
+const (
+	// FailError returns storage errors to the caller, which decides how to respond.
+	FailError FailurePolicy = iota
+	// FailOpen admits the request when the store is unavailable.
+	FailOpen
+	// FailClosed denies the request when the store is unavailable.
+	FailClosed
+)
+
+type Option func(*Limiter)
+
+// WithConfigs sets the per-client configs. The map is copied; nil means every
+// client uses the default config.
+func WithConfigs(cfgs map[string]config.ClientConfig) Option {
+	return func(l *Limiter) {
+		l.configs = cfgs
+	}
+}
+
+func WithDefault(cfg config.ClientConfig) Option {
+	return func(l *Limiter) {
+		l.defaultConfig = cfg
+	}
+}
+
+// WithClassDefaults sets default configs per request class, used for clients
+// without an explicit config.
+func WithClassDefaults(defaults map[string]config.ClientConfig) Option {
+	return func(l *Limiter) {
+		l.classDefaults = defaults
+	}
+}
+
+// LimitResolver computes a client's config at request time, e.g. from the
+// customer's current plan. It reports false to fall back to the static
+// configs. It runs on every decision, so it should cache internally.
+type LimitResolver func(client string) (config.ClientConfig, bool)
+
+// WithLimitResolver consults r before the static per-client configs.
+func WithLimitResolver(r LimitResolver) Option {
+	return func(l *Limiter) {
+		l.resolver = r
+	}
+}
+
+func WithFailurePolicy(p FailurePolicy) Option {
+	return func(l *Limiter) {
+		l.failurePolicy = p
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(l *Limiter) {
+		l.logger = logger
+	}
+}
+
+// WithClock replaces the limiter's clock. Its times are converted to UTC.
+func WithClock(now func() time.Time) Option {
+	return func(l *Limiter) {
+		l.now = func() time.Time { return now().UTC() }
+	}
+}
+
+// WithUnlimitedAbove treats clients whose capacity (Limit plus Burst) is at
+// least n as unlimited: they are allowed without a store round trip and report
+// config.Unlimited as limit and remaining. Use it for internal clients whose
+// very high limits only exist to never bind. n <= 0 disables it.
+func WithUnlimitedAbove(n int) Option {
+	return func(l *Limiter) {
+		l.unlimitedAt = n
+	}
+}
+
+func WithHistory(size int) Option {
+	return func(l *Limiter) {
+		l.history = NewHistory(size)
+	}
+}
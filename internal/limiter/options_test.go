@@ -0,0 +1,173 @@
+package limiter
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestNewDefaults(t *testing.T) {
+	l := New(memory.NewMemoryStore())
+
+	if l.ConfigFor("anyone") != config.DefaultConfig {
+		t.Fatal("expected package default config")
+	}
+	if l.failurePolicy != FailError {
+		t.Fatal("expected FailError policy by default")
+	}
+	if l.logger == nil || l.now == nil {
+		t.Fatal("expected logger and clock to be set")
+	}
+	if l.history != nil {
+		t.Fatal("expected history disabled by default")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	t.Run("WithConfigs", func(t *testing.T) {
+		cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+		l := New(memory.NewMemoryStore(), WithConfigs(cfgs))
+
+		if l.ConfigFor("c1") != cfgs["c1"] {
+			t.Fatalf("expected c1 config, got %+v", l.ConfigFor("c1"))
+		}
+		l.Allow("c1")
+		if ok, _, _, _ := l.Allow("c1"); ok {
+			t.Fatal("expected second request denied under c1 config")
+		}
+	})
+	t.Run("WithDefault", func(t *testing.T) {
+		def := config.ClientConfig{Limit: 1, Window: time.Minute}
+		l := New(memory.NewMemoryStore(), WithDefault(def))
+
+		if l.ConfigFor("unknown") != def {
+			t.Fatalf("expected custom default, got %+v", l.ConfigFor("unknown"))
+		}
+		l.Allow("unknown")
+		if ok, _, _, _ := l.Allow("unknown"); ok {
+			t.Fatal("expected second request denied under custom default")
+		}
+	})
+	t.Run("WithFailurePolicy open", func(t *testing.T) {
+		l := New(&mockStoreError{}, WithFailurePolicy(FailOpen))
+		ok, remaining, _, err := l.Allow("c1")
+		if err != nil || !ok || remaining != config.DefaultConfig.Limit {
+			t.Fatalf("expected fail open, got ok=%v remaining=%d err=%v", ok, remaining, err)
+		}
+	})
+	t.Run("WithFailurePolicy closed", func(t *testing.T) {
+		l := New(&mockStoreError{}, WithFailurePolicy(FailClosed))
+		ok, remaining, _, err := l.Allow("c1")
+		if err != nil || ok || remaining != 0 {
+			t.Fatalf("expected fail closed, got ok=%v remaining=%d err=%v", ok, remaining, err)
+		}
+	})
+	t.Run("WithLogger", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+		l := New(&mockStoreError{}, WithLogger(logger), WithFailurePolicy(FailOpen))
+
+		l.Allow("c1")
+		if !strings.Contains(buf.String(), "failing open") {
+			t.Fatalf("expected log through custom logger, got %q", buf.String())
+		}
+	})
+	t.Run("WithClock", func(t *testing.T) {
+		future := time.Now().Add(time.Hour)
+		l := New(memory.NewMemoryStore(), WithClock(func() time.Time { return future }))
+
+		_, _, resetAt, _ := l.Allow("c1")
+		if !resetAt.IsZero() {
+			t.Fatal("expected store expiry before the injected clock to yield zero resetAt")
+		}
+	})
+	t.Run("WithHistory", func(t *testing.T) {
+		l := New(memory.NewMemoryStore(), WithHistory(2))
+		l.Allow("c1")
+		if len(l.History("c1")) != 1 {
+			t.Fatal("expected history to be recorded")
+		}
+	})
+}
+
+func TestWithClassDefaults(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"vip": {Limit: 10, Window: time.Minute}}
+	l := New(memory.NewMemoryStore(), WithConfigs(cfgs), WithClassDefaults(map[string]config.ClientConfig{
+		"bot": {Limit: 1, Window: time.Minute},
+	}))
+
+	res, _ := l.AllowRequest(Request{Client: "anon", Class: "bot"})
+	if res.Limit != 1 {
+		t.Fatalf("expected class default limit 1, got %d", res.Limit)
+	}
+	res, _ = l.AllowRequest(Request{Client: "anon", Class: "browser"})
+	if res.Limit != config.DefaultConfig.Limit || res.Count != 1 {
+		t.Fatalf("expected global default and a separate counter per class, got %+v", res)
+	}
+	res, _ = l.AllowRequest(Request{Client: "vip", Class: "bot"})
+	if res.Limit != 10 {
+		t.Fatalf("expected explicit client config to win over class default, got %d", res.Limit)
+	}
+}
+
+// zoneStore computes expiries from a clock that reports local (non-UTC) times.
+type zoneStore struct {
+	now    func() time.Time
+	counts map[string]int64
+	expiry map[string]time.Time
+}
+
+func (s *zoneStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	if _, ok := s.expiry[key]; !ok {
+		s.expiry[key] = s.now().Add(ttl)
+	}
+	s.counts[key]++
+	return s.counts[key], s.expiry[key], nil
+}
+
+func (s *zoneStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	return s.counts[key], s.expiry[key], nil
+}
+
+func (s *zoneStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	if _, ok := s.expiry[key]; ok {
+		return false, nil
+	}
+	s.counts[key], s.expiry[key] = count, s.now().Add(ttl)
+	return true, nil
+}
+
+func TestWindowsUnaffectedByTimeZone(t *testing.T) {
+	instant := time.Date(2024, 3, 31, 23, 59, 30, 0, time.UTC)
+	zones := []*time.Location{
+		time.UTC,
+		time.FixedZone("IST", 5*3600+1800),
+		time.FixedZone("PST", -8*3600),
+	}
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+
+	for _, loc := range zones {
+		clock := func() time.Time { return instant.In(loc) }
+		store := &zoneStore{now: clock, counts: map[string]int64{}, expiry: map[string]time.Time{}}
+		l := New(store, WithConfigs(cfgs), WithClock(clock))
+
+		res, _ := l.AllowResult("c1")
+		if want := instant.Add(time.Minute); res.ResetAt != want {
+			t.Errorf("%s: expected reset at %v, got %v", loc, want, res.ResetAt)
+		}
+		if got := l.now(); got != instant {
+			t.Errorf("%s: expected clock normalized to UTC, got %v", loc, got)
+		}
+
+		peek, _ := l.Peek("c1")
+		if peek.ResetAt.Location() != time.UTC || peek.Remaining != 1 {
+			t.Errorf("%s: unexpected peek %+v", loc, peek)
+		}
+	}
+}
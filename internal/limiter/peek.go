@@ -0,0 +1,113 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// Peek returns the client's current quota without consuming any.
+func (l *Limiter) Peek(client string) (Result, error) {
+	if l.splitBudget() {
+		return Result{}, ErrSplitBudget
+	}
+	return l.CheckRequest(Request{Client: client})
+}
+
+// PeekMany returns the current quota for each client, in input order, using a
+// single batched read when the store supports it. Clients without a counter
+// report their full quota. Like Peek, grouped clients are clamped to their
+// group's pool.
+func (l *Limiter) PeekMany(clients []string) ([]Result, error) {
+	if l.splitBudget() {
+		return nil, ErrSplitBudget
+	}
+	now := l.now()
+	cfgs := make([]config.ClientConfig, len(clients))
+	keys := make([]string, len(clients))
+	for i, client := range clients {
+		cfgs[i] = l.withSafeWindow(client, l.effectiveConfig(l.ConfigFor(client)))
+		keys[i] = l.keyForRequest(Request{Client: client}, cfgs[i], now)
+	}
+
+	for _, cfg := range cfgs {
+		if l.algorithmFor(cfg) != AlgorithmFixedWindow {
+			return l.peekEach(context.Background(), clients, cfgs, keys, now)
+		}
+	}
+
+	// Group pools are read in the same batch, after the client keys. Clients
+	// with a preset result skip their group, as in CheckRequest.
+	l.configMu.RLock()
+	groups := make([]group, len(clients))
+	grouped := make([]bool, len(clients))
+	for i, client := range clients {
+		if _, preset := presetResult(cfgs[i]); preset {
+			continue
+		}
+		if g, ok := l.groups[client]; ok {
+			groups[i], grouped[i] = g, true
+			keys = append(keys, l.keyForGroup(g.name))
+		}
+	}
+	l.configMu.RUnlock()
+
+	entries, err := l.getMany(context.Background(), keys)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(clients))
+	next := len(clients)
+	for i := range clients {
+		results[i] = peekResult(cfgs[i], entries[i].Count, entries[i].Expiry, now)
+		if grouped[i] {
+			gres := peekResult(groups[i].cfg, entries[next].Count, entries[next].Expiry, now)
+			results[i] = clampToGroup(results[i], gres)
+			next++
+		}
+	}
+	return results, nil
+}
+
+// peekEach reads each client's state separately, for algorithms keeping more
+// than one value per client.
+func (l *Limiter) peekEach(ctx context.Context, clients []string, cfgs []config.ClientConfig, keys []string, now time.Time) ([]Result, error) {
+	results := make([]Result, len(clients))
+	for i := range clients {
+		if res, ok := presetResult(cfgs[i]); ok {
+			results[i] = res
+			continue
+		}
+		count, expiry, err := l.getKey(ctx, keys[i], cfgs[i], now)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = peekResult(cfgs[i], count, expiry, now)
+		gres, ok, err := l.checkGroup(ctx, clients[i], now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			results[i] = clampToGroup(results[i], gres)
+		}
+	}
+	return results, nil
+}
+
+func (l *Limiter) getMany(ctx context.Context, keys []string) ([]StoreEntry, error) {
+	if bs, ok := l.store.(BatchStore); ok {
+		return bs.GetMany(storeContext(ctx), keys)
+	}
+
+	entries := make([]StoreEntry, len(keys))
+	for i, key := range keys {
+		count, expiry, err := l.store.Get(storeContext(ctx), key)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = StoreEntry{Count: count, Expiry: expiry}
+	}
+	return entries, nil
+}
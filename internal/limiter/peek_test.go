@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+type batchMemoryStore struct {
+	*memory.MemoryStore
+	batches int
+}
+
+func (b *batchMemoryStore) GetMany(ctx context.Context, keys []string) ([]StoreEntry, error) {
+	b.batches++
+	entries := make([]StoreEntry, len(keys))
+	for i, key := range keys {
+		count, expiry, _ := b.Get(context.Background(), key)
+		entries[i] = StoreEntry{Count: count, Expiry: expiry}
+	}
+	return entries, nil
+}
+
+func TestPeekMany(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{
+		"c1": {Limit: 5, Window: time.Minute},
+		"c2": {Limit: 2, Window: time.Minute},
+		"g1": {Limit: 5, Window: time.Minute},
+		"g2": {Limit: 5, Window: time.Minute},
+	}
+	clients := []string{"c2", "missing", "c1", "g1", "g2"}
+
+	stores := map[string]Store{
+		"loop":  memory.NewMemoryStore(),
+		"batch": &batchMemoryStore{MemoryStore: memory.NewMemoryStore()},
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			l := New(store, WithConfigs(cfgs),
+				WithGroup("team", config.ClientConfig{Limit: 3, Window: time.Minute}, "g1", "g2"))
+			l.Allow("c1")
+			l.Allow("c2")
+			l.Allow("c2")
+			l.Allow("c2")
+			l.Allow("g1")
+			l.Allow("g1")
+
+			got, err := l.PeekMany(clients)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(clients) {
+				t.Fatalf("expected %d results, got %d", len(clients), len(got))
+			}
+
+			for i, client := range clients {
+				want, _ := l.Peek(client)
+				if got[i] != want {
+					t.Errorf("%s: PeekMany %+v differs from Peek %+v", client, got[i], want)
+				}
+			}
+
+			if got[1].Remaining != config.DefaultConfig.Limit || !got[1].Allowed {
+				t.Errorf("expected missing client at full quota, got %+v", got[1])
+			}
+			if got[0].Allowed || got[0].Remaining != 0 {
+				t.Errorf("expected exhausted c2, got %+v", got[0])
+			}
+			if got[2].Remaining != 4 {
+				t.Errorf("expected c1 remaining 4, got %d", got[2].Remaining)
+			}
+			if got[3].Remaining != 1 || got[4].Remaining != 1 {
+				t.Errorf("expected g1 and g2 clamped to the pool's 1 remaining, got %+v, %+v", got[3], got[4])
+			}
+
+			l.Allow("g2")
+			got, err = l.PeekMany(clients)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, client := range clients {
+				want, _ := l.Peek(client)
+				if got[i] != want {
+					t.Errorf("%s: PeekMany %+v differs from Peek %+v", client, got[i], want)
+				}
+			}
+			if got[4].Allowed || got[4].Reason != ReasonGroupLimit {
+				t.Errorf("expected g2 denied by its exhausted pool, got %+v", got[4])
+			}
+		})
+	}
+
+	bs := stores["batch"].(*batchMemoryStore)
+	if bs.batches != 2 {
+		t.Errorf("expected a single batched read per PeekMany, got %d", bs.batches)
+	}
+}
@@ -0,0 +1,37 @@
+package limiter
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Rand is the source for the limiter's randomized decisions. *rand.Rand
+// implements it.
+type Rand interface {
+	Float64() float64
+}
+
+// WithRand routes every randomized decision, such as load shedding, through
+// r so tests can use a seeded source. Calls to r are serialized, so a
+// *rand.Rand needs no locking of its own. The default is math/rand's global
+// source.
+func WithRand(r Rand) Option {
+	return func(l *Limiter) {
+		l.rand = &lockedRand{r: r}
+	}
+}
+
+type globalRand struct{}
+
+func (globalRand) Float64() float64 { return rand.Float64() }
+
+type lockedRand struct {
+	mu sync.Mutex
+	r  Rand
+}
+
+func (lr *lockedRand) Float64() float64 {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	return lr.r.Float64()
+}
@@ -0,0 +1,81 @@
+package limiter
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestWithRandReproducibleShedding(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 100, Window: time.Minute}}
+
+	run := func(seed int64) []bool {
+		l := New(&mockStoreFixedCount{count: 80}, WithConfigs(cfgs),
+			WithLoadShedding(0.5, nil), WithRand(rand.New(rand.NewSource(seed))))
+		out := make([]bool, 100)
+		for i := range out {
+			res, _ := l.AllowResult("c1")
+			out[i] = res.Allowed
+		}
+		return out
+	}
+
+	a, b := run(1), run(1)
+	shed := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("decision %d differs between identically seeded runs", i)
+		}
+		if !a[i] {
+			shed++
+		}
+	}
+	if shed == 0 || shed == len(a) {
+		t.Fatalf("expected a mix of shed and admitted requests, got %d shed", shed)
+	}
+
+	c := run(2)
+	same := true
+	for i := range a {
+		same = same && a[i] == c[i]
+	}
+	if same {
+		t.Fatal("expected a different seed to change the outcome")
+	}
+}
+
+type fixedRand float64
+
+func (r fixedRand) Float64() float64 { return float64(r) }
+
+func TestWithRandOverriddenBySheddingFunc(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 100, Window: time.Minute}}
+	always := func() float64 { return 0 }
+	l := New(&mockStoreFixedCount{count: 80}, WithConfigs(cfgs),
+		WithLoadShedding(0.5, always), WithRand(fixedRand(0.99)))
+
+	if res, _ := l.AllowResult("c1"); res.Allowed {
+		t.Fatal("expected the shedding func to take precedence over WithRand")
+	}
+}
+
+func TestWithRandConcurrentUse(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 100, Window: time.Minute}}
+	l := New(&mockStoreFixedCount{count: 80}, WithConfigs(cfgs),
+		WithLoadShedding(0.5, nil), WithRand(rand.New(rand.NewSource(1))))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.AllowResult("c1")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,55 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestDenialReasons(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{
+		"client": {Limit: 1, Window: time.Minute},
+		"burst":  {Limit: 1, Burst: 1, Window: time.Minute},
+		"member": {Limit: 5, Window: time.Minute},
+		"conc":   {Limit: 5, MaxConcurrent: 1, Window: time.Minute},
+	}
+	l := New(memory.NewMemoryStore(),
+		WithConfigs(cfgs),
+		WithGroup("team", config.ClientConfig{Limit: 1, Window: time.Minute}, "member"),
+	)
+
+	exhaust := func(client string, n int) Reason {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if ok, reason, err := l.AllowReason(client); !ok || reason != "" || err != nil {
+				t.Fatalf("%s request %d: expected allowed without reason, got %v %q %v", client, i+1, ok, reason, err)
+			}
+		}
+		ok, reason, _ := l.AllowReason(client)
+		if ok {
+			t.Fatalf("%s: expected denial", client)
+		}
+		return reason
+	}
+
+	if got := exhaust("client", 1); got != ReasonRateLimit {
+		t.Errorf("per-client: expected %q, got %q", ReasonRateLimit, got)
+	}
+	if got := exhaust("burst", 2); got != ReasonBurstExhausted {
+		t.Errorf("burst: expected %q, got %q", ReasonBurstExhausted, got)
+	}
+	if res, _ := l.Peek("burst"); res.Reason != ReasonBurstExhausted {
+		t.Errorf("burst peek: expected %q, got %q", ReasonBurstExhausted, res.Reason)
+	}
+	if got := exhaust("member", 1); got != ReasonGroupLimit {
+		t.Errorf("group: expected %q, got %q", ReasonGroupLimit, got)
+	}
+
+	_, release, _ := l.Acquire("conc", "", 1)
+	defer release()
+	if res, _, _ := l.Acquire("conc", "", 1); res.Reason != ReasonConcurrency {
+		t.Errorf("concurrency: expected %q, got %q", ReasonConcurrency, res.Reason)
+	}
+}
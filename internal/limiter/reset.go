@@ -0,0 +1,80 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrResetUnsupported is returned by Reset and ResetWindow when the store
+// cannot clear keys.
+var ErrResetUnsupported = errors.New("limiter: store does not support reset")
+
+// ResetStore is implemented by stores that can clear a counter. Both methods
+// must be atomic and idempotent so concurrent resets from several replicas
+// agree on the outcome.
+type ResetStore interface {
+	// Delete removes key; the next increment starts a new window.
+	Delete(ctx context.Context, key string) error
+	// ResetKey sets key's count to 0 in a fresh window of ttl starting now.
+	ResetKey(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// Reset clears the client's current window by deleting its counter. It is
+// safe to retry. An increment racing with it is either erased by the delete
+// or starts a new window right after it; it is never partly applied.
+func (l *Limiter) Reset(client string) error {
+	rs, ok := l.store.(ResetStore)
+	if !ok {
+		return ErrResetUnsupported
+	}
+	if l.splitBudget() {
+		return ErrSplitBudget
+	}
+	now := l.now()
+	cfg := l.withSafeWindow(client, l.ConfigFor(client))
+	return l.deleteKey(context.Background(), rs, l.keyForRequest(Request{Client: client}, cfg, now), cfg, now)
+}
+
+// ResetRequest is like Reset but clears the budget req counts against,
+// including its scope and class. The store calls run under req.Context like
+// decisions do.
+func (l *Limiter) ResetRequest(req Request) error {
+	rs, ok := l.store.(ResetStore)
+	if !ok {
+		return ErrResetUnsupported
+	}
+	now := l.now()
+	cfg := l.withSafeWindow(req.Client, l.configForRequest(req))
+	return l.deleteKey(storeContext(req.Context), rs, l.keyForRequest(req, cfg, now), cfg, now)
+}
+
+// ResetWindow is like Reset but sets the counter to 0 in a fresh window
+// starting now instead of deleting it, so the key and its expiry survive the
+// reset. Increments racing with it count in the new window or are erased.
+func (l *Limiter) ResetWindow(client string) error {
+	rs, ok := l.store.(ResetStore)
+	if !ok {
+		return ErrResetUnsupported
+	}
+	if l.splitBudget() {
+		return ErrSplitBudget
+	}
+	now := l.now()
+	cfg := l.withSafeWindow(client, l.ConfigFor(client))
+	key := l.keyForRequest(Request{Client: client}, cfg, now)
+	ctx := context.Background()
+	if l.logConfigured(cfg) || l.bucketConfigured(cfg) || l.gcraConfigured(cfg) {
+		// A log, bucket or TAT has no window to restart; deleting it frees
+		// every slot.
+		return rs.Delete(ctx, key)
+	}
+	if !l.sliding(cfg) || cfg.Window <= 0 {
+		return rs.ResetKey(ctx, key, cfg.Window)
+	}
+	sw := newSlidingWindow(key, cfg.Window, now)
+	if err := rs.ResetKey(ctx, sw.cur, 2*cfg.Window); err != nil {
+		return err
+	}
+	return rs.Delete(ctx, sw.prev)
+}
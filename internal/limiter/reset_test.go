@@ -0,0 +1,140 @@
+package limiter
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestReset(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+	l := New(memory.NewMemoryStore(), WithConfigs(cfgs))
+
+	for _, reset := range []func(string) error{l.Reset, l.ResetWindow} {
+		l.Allow("c1")
+		l.Allow("c1")
+		if ok, _, _, _ := l.Allow("c1"); ok {
+			t.Fatal("expected client exhausted before reset")
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := reset("c1"); err != nil {
+				t.Fatalf("reset %d: %v", i+1, err)
+			}
+		}
+		if res, _ := l.Peek("c1"); res.Remaining != 2 {
+			t.Fatalf("expected full quota after repeated reset, got %+v", res)
+		}
+		if res, _ := l.AllowResult("c1"); !res.Allowed || res.Count != 1 {
+			t.Fatalf("expected first request of a new window, got %+v", res)
+		}
+		l.Reset("c1")
+	}
+}
+
+func TestResetRequest(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	l := New(memory.NewMemoryStore(), WithConfigs(cfgs))
+	login := Request{Client: "c1", Scope: "login"}
+
+	l.AllowRequest(login)
+	l.AllowResult("c1")
+	if err := l.ResetRequest(login); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := l.AllowRequest(login); !res.Allowed || res.Count != 1 {
+		t.Fatalf("expected the scoped budget cleared, got %+v", res)
+	}
+	if res, _ := l.AllowResult("c1"); res.Allowed {
+		t.Fatalf("expected the main budget untouched, got %+v", res)
+	}
+}
+
+func TestResetWindowStartsFreshWindow(t *testing.T) {
+	window := time.Minute
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 5, Window: window}}
+	l := New(memory.NewMemoryStore(), WithConfigs(cfgs))
+	l.Allow("c1")
+
+	before := time.Now()
+	if err := l.ResetWindow("c1"); err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now()
+
+	res, _ := l.Peek("c1")
+	if res.Remaining != 5 {
+		t.Fatalf("expected zeroed counter, got %+v", res)
+	}
+	if res.ResetAt.Before(before.Add(window)) || res.ResetAt.After(after.Add(window)) {
+		t.Fatalf("expected window to restart at reset, got reset at %v", res.ResetAt)
+	}
+}
+
+func TestResetUnsupported(t *testing.T) {
+	l := New(&mockStoreError{})
+	if err := l.Reset("c1"); !errors.Is(err, ErrResetUnsupported) {
+		t.Fatalf("expected ErrResetUnsupported, got %v", err)
+	}
+	if err := l.ResetWindow("c1"); !errors.Is(err, ErrResetUnsupported) {
+		t.Fatalf("expected ErrResetUnsupported, got %v", err)
+	}
+}
+
+func TestResetConcurrentWithAllow(t *testing.T) {
+	const limit = 50
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: limit, Window: time.Minute}}
+
+	for name, reset := range map[string]func(*Limiter, string) error{
+		"delete": (*Limiter).Reset,
+		"zero":   (*Limiter).ResetWindow,
+	} {
+		t.Run(name, func(t *testing.T) {
+			l := New(memory.NewMemoryStore(), WithConfigs(cfgs))
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 200; j++ {
+						res, err := l.AllowResult("c1")
+						if err != nil || res.Count < 1 {
+							t.Errorf("unexpected result during resets: %+v err=%v", res, err)
+							return
+						}
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 50; j++ {
+						if err := reset(l, "c1"); err != nil {
+							t.Errorf("reset: %v", err)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			if err := reset(l, "c1"); err != nil {
+				t.Fatal(err)
+			}
+			if res, _ := l.Peek("c1"); !res.Allowed || res.Remaining != limit {
+				t.Fatalf("expected full quota after final reset, got %+v", res)
+			}
+			for i := 1; i <= limit; i++ {
+				if res, _ := l.AllowResult("c1"); !res.Allowed || res.Count != int64(i) {
+					t.Fatalf("request %d: expected consistent counting after reset, got %+v", i, res)
+				}
+			}
+			if ok, _, _, _ := l.Allow("c1"); ok {
+				t.Fatal("expected limit enforced after reset")
+			}
+		})
+	}
+}
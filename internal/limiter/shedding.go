@@ -0,0 +1,45 @@
+package limiter
+
+const ReasonLoadShed Reason = "load_shed"
+
+// WithLoadShedding rejects a growing fraction of requests once a client has
+// used more than softThreshold (0..1) of its limit. The reject probability
+// ramps linearly from 0 at the threshold to 1 at the hard limit. random
+// returns values in [0, 1); nil uses the limiter's Rand (see WithRand).
+func WithLoadShedding(softThreshold float64, random func() float64) Option {
+	return func(l *Limiter) {
+		l.shedding = true
+		l.shedThreshold = softThreshold
+		l.shedRandom = random
+	}
+}
+
+// shedProbability returns the chance of rejecting a request that finds used
+// units already counted against limit.
+func (l *Limiter) shedProbability(used int64, limit int) float64 {
+	if !l.shedding || limit <= 0 {
+		return 0
+	}
+
+	soft := l.shedThreshold * float64(limit)
+	if float64(used) <= soft {
+		return 0
+	}
+
+	p := (float64(used) - soft) / (float64(limit) - soft)
+	if p > 1 {
+		return 1
+	}
+	return p
+}
+
+func (l *Limiter) shouldShed(used int64, limit int) bool {
+	p := l.shedProbability(used, limit)
+	if p <= 0 {
+		return false
+	}
+	if l.shedRandom != nil {
+		return l.shedRandom() < p
+	}
+	return l.rand.Float64() < p
+}
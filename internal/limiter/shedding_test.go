@@ -0,0 +1,97 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+type mockStoreFixedCount struct {
+	count int64
+}
+
+func (m *mockStoreFixedCount) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	return m.count, time.Now().Add(ttl), nil
+}
+func (m *mockStoreFixedCount) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	return m.count, time.Now().Add(time.Minute), nil
+}
+
+func (m *mockStoreFixedCount) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return false, nil
+}
+
+func TestLoadSheddingCurve(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 100, Window: time.Minute}}
+
+	tests := []struct {
+		used     int64
+		wantRate float64
+	}{
+		{used: 10, wantRate: 0},
+		{used: 50, wantRate: 0},
+		{used: 75, wantRate: 0.5},
+		{used: 90, wantRate: 0.8},
+		{used: 99, wantRate: 0.98},
+	}
+
+	for _, tt := range tests {
+		store := &mockStoreFixedCount{count: tt.used + 1}
+		rng := rand.New(rand.NewSource(42))
+		l := New(store, WithConfigs(cfgs), WithLoadShedding(0.5, rng.Float64))
+
+		const trials = 20000
+		shed := 0
+		for i := 0; i < trials; i++ {
+			res, _ := l.AllowResult("c1")
+			if !res.Allowed {
+				if res.Reason != ReasonLoadShed {
+					t.Fatalf("expected load shed reason, got %q", res.Reason)
+				}
+				shed++
+			}
+		}
+
+		got := float64(shed) / trials
+		if math.Abs(got-tt.wantRate) > 0.02 {
+			t.Errorf("used %d: expected shed rate %.2f, got %.3f", tt.used, tt.wantRate, got)
+		}
+	}
+}
+
+func TestLoadSheddingDeterministic(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 100, Window: time.Minute}}
+
+	run := func() []bool {
+		rng := rand.New(rand.NewSource(7))
+		l := New(&mockStoreFixedCount{count: 80}, WithConfigs(cfgs), WithLoadShedding(0.5, rng.Float64))
+		out := make([]bool, 50)
+		for i := range out {
+			res, _ := l.AllowResult("c1")
+			out[i] = res.Allowed
+		}
+		return out
+	}
+
+	a, b := run(), run()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("decision %d differs between identically seeded runs", i)
+		}
+	}
+}
+
+func TestLoadSheddingDisabled(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 100, Window: time.Minute}}
+	l := New(&mockStoreFixedCount{count: 99}, WithConfigs(cfgs))
+
+	for i := 0; i < 100; i++ {
+		if res, _ := l.AllowResult("c1"); !res.Allowed {
+			t.Fatal("expected no shedding without the option")
+		}
+	}
+}
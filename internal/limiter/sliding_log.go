@@ -0,0 +1,72 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// ErrLogUnsupported is returned for AlgorithmSlidingLog decisions when the
+// store does not implement LogStore.
+var ErrLogUnsupported = errors.New("limiter: store does not support the sliding log")
+
+// LogStore is implemented by stores that can keep a log of request times per
+// key for AlgorithmSlidingLog. A window covers the times after now-window.
+type LogStore interface {
+	// AppendLog drops times that left the window and, if at most limit-n
+	// remain, records n requests at now. It returns the count in the window
+	// after any append, the oldest time in it (zero when empty) and whether
+	// the requests were recorded. Both steps must be atomic.
+	AppendLog(ctx context.Context, key string, n, limit int64, window time.Duration) (count int64, oldest time.Time, appended bool, err error)
+	// CountLog returns the count in the window and its oldest time.
+	CountLog(ctx context.Context, key string, window time.Duration) (count int64, oldest time.Time, err error)
+}
+
+// logIncrement decides a request from key's request log. Only admitted
+// requests are logged, so a client retrying while denied does not push its
+// own recovery back. Denials report the count the request would have made.
+// The expiry is when the oldest logged request leaves the window and frees a
+// slot.
+func (l *Limiter) logIncrement(ctx context.Context, key string, n int64, limit int, window time.Duration) (StoreDecision, error) {
+	ls, ok := l.store.(LogStore)
+	if !ok {
+		return StoreDecision{}, ErrLogUnsupported
+	}
+	count, oldest, appended, err := ls.AppendLog(storeContext(ctx), key, n, int64(limit), window)
+	if err != nil {
+		return StoreDecision{}, err
+	}
+	if !appended {
+		count += n
+	}
+	_, remaining := fixedWindowDecision(limit, count)
+	return StoreDecision{Allowed: appended, Count: count, Remaining: int64(remaining), Expiry: logExpiry(oldest, window)}, nil
+}
+
+// logGet is the read-only counterpart of logIncrement.
+func (l *Limiter) logGet(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	ls, ok := l.store.(LogStore)
+	if !ok {
+		return 0, time.Time{}, ErrLogUnsupported
+	}
+	count, oldest, err := ls.CountLog(storeContext(ctx), key, window)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return count, logExpiry(oldest, window), nil
+}
+
+func logExpiry(oldest time.Time, window time.Duration) time.Time {
+	if oldest.IsZero() {
+		return time.Time{}
+	}
+	return oldest.Add(window)
+}
+
+// logConfigured reports whether cfg is counted with the sliding log; configs
+// without a positive window never reach the log.
+func (l *Limiter) logConfigured(cfg config.ClientConfig) bool {
+	return l.algorithmFor(cfg) == AlgorithmSlidingLog && cfg.Window > 0
+}
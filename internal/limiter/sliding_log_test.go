@@ -0,0 +1,78 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestSlidingLogBoundaryBurst(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmSlidingLog))
+	start := clock.now
+
+	allowedCount(t, l, 1)
+	clock.now = start.Add(59 * time.Second)
+	if got := allowedCount(t, l, 49); got != 49 {
+		t.Fatalf("expected the first 50 allowed, got %d", got+1)
+	}
+
+	// The first request has left the window, the other 49 have not.
+	clock.now = start.Add(61 * time.Second)
+	if got := allowedCount(t, l, 50); got != 1 {
+		t.Fatalf("expected exactly one slot free across the boundary, got %d", got)
+	}
+	res, err := l.AllowResult("c1")
+	if err != nil || res.Allowed || res.Count != 51 || res.Remaining != 0 {
+		t.Fatalf("unexpected denial %+v, %v", res, err)
+	}
+	if want := start.Add(59*time.Second + time.Minute); !res.ResetAt.Equal(want) {
+		t.Fatalf("expected reset when the oldest request leaves the window at %v, got %v", want, res.ResetAt)
+	}
+
+	// Denied requests were not logged, so the 49 free up on schedule.
+	clock.now = start.Add(59*time.Second + time.Minute + time.Millisecond)
+	if got := allowedCount(t, l, 50); got != 49 {
+		t.Fatalf("expected 49 slots freed, got %d", got)
+	}
+}
+
+func TestSlidingLogPeekAndReset(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmSlidingLog))
+	l.SetLimit("c1", config.ClientConfig{Limit: 3, Window: time.Minute})
+
+	allowedCount(t, l, 2)
+	clock.now = clock.now.Add(30 * time.Second)
+	res, err := l.Peek("c1")
+	if err != nil || res.Count != 2 || res.Remaining != 1 || !res.Allowed {
+		t.Fatalf("unexpected peek %+v, %v", res, err)
+	}
+	results, err := l.PeekMany([]string{"c1", "other"})
+	if err != nil || results[0].Remaining != 1 || results[1].Remaining != results[1].Limit {
+		t.Fatalf("unexpected peeks %+v, %v", results, err)
+	}
+
+	allowedCount(t, l, 1)
+	if err := l.ResetWindow("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := allowedCount(t, l, 4); got != 3 {
+		t.Fatalf("expected a full budget after ResetWindow, got %d", got)
+	}
+	if err := l.Reset("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := l.Peek("c1"); res.Count != 0 {
+		t.Fatalf("expected Reset to empty the log, got %+v", res)
+	}
+}
+
+func TestSlidingLogUnsupportedStore(t *testing.T) {
+	l := New(&ttlStore{}, WithAlgorithm(AlgorithmSlidingLog))
+	if _, err := l.AllowResult("c1"); !errors.Is(err, ErrLogUnsupported) {
+		t.Fatalf("expected ErrLogUnsupported, got %v", err)
+	}
+}
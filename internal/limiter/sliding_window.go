@@ -0,0 +1,112 @@
+package limiter
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// slidingWindow locates now within the epoch-aligned windows of key: the
+// current and previous counter keys, when the current window ends, and the
+// weight of the previous window's count.
+type slidingWindow struct {
+	cur, prev string
+	end       time.Time
+	weight    float64
+}
+
+func newSlidingWindow(key string, window time.Duration, now time.Time) slidingWindow {
+	idx := now.UnixNano() / int64(window)
+	start := time.Unix(0, idx*int64(window)).UTC()
+	return slidingWindow{
+		cur:    key + ":sw" + strconv.FormatInt(idx, 10),
+		prev:   key + ":sw" + strconv.FormatInt(idx-1, 10),
+		end:    start.Add(window),
+		weight: 1 - float64(now.Sub(start))/float64(window),
+	}
+}
+
+// estimate is the request count over the last window, rounding the previous
+// window's share down.
+func (sw slidingWindow) estimate(prev, cur int64) int64 {
+	return cur + int64(float64(prev)*sw.weight)
+}
+
+// slidingIncrement counts n units in key's current window and decides on the
+// weighted count. The reported expiry is the end of the current window, when
+// the previous window stops counting.
+func (l *Limiter) slidingIncrement(ctx context.Context, key string, n int64, limit int, window time.Duration, now time.Time) (StoreDecision, error) {
+	sw := newSlidingWindow(key, window, now)
+	prev, _, err := l.store.Get(storeContext(ctx), sw.prev)
+	if err != nil {
+		return StoreDecision{}, err
+	}
+	// The counter must outlive its own window to serve as the previous one.
+	cur, _, err := l.increment(ctx, sw.cur, n, 2*window)
+	if err != nil {
+		return StoreDecision{}, err
+	}
+
+	count := sw.estimate(prev, cur)
+	allowed, remaining := fixedWindowDecision(limit, count)
+	return StoreDecision{Allowed: allowed, Count: count, Remaining: int64(remaining), Expiry: sw.end}, nil
+}
+
+// slidingGet is the read-only counterpart of slidingIncrement.
+func (l *Limiter) slidingGet(ctx context.Context, key string, window time.Duration, now time.Time) (int64, time.Time, error) {
+	sw := newSlidingWindow(key, window, now)
+	entries, err := l.getMany(ctx, []string{sw.prev, sw.cur})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return sw.estimate(entries[0].Count, entries[1].Count), sw.end, nil
+}
+
+// countKey counts n units against key with the configured algorithm.
+func (l *Limiter) countKey(ctx context.Context, key string, n int64, cfg config.ClientConfig, now time.Time) (StoreDecision, error) {
+	if l.gcraConfigured(cfg) {
+		return l.gcraArrive(ctx, key, n, cfg, now)
+	}
+	if l.bucketConfigured(cfg) {
+		return l.bucketTake(ctx, key, n, cfg, now)
+	}
+	if l.logConfigured(cfg) {
+		return l.logIncrement(ctx, key, n, windowCapacity(cfg), cfg.Window)
+	}
+	if l.sliding(cfg) {
+		return l.slidingIncrement(ctx, key, n, windowCapacity(cfg), cfg.Window, now)
+	}
+	return l.incrementWithResult(ctx, key, n, windowCapacity(cfg), cfg.Window)
+}
+
+// getKey reads key's count with the configured algorithm.
+func (l *Limiter) getKey(ctx context.Context, key string, cfg config.ClientConfig, now time.Time) (int64, time.Time, error) {
+	if l.gcraConfigured(cfg) {
+		return l.gcraGet(ctx, key, cfg, now)
+	}
+	if l.bucketConfigured(cfg) {
+		return l.bucketGet(ctx, key, cfg, now)
+	}
+	if l.logConfigured(cfg) {
+		return l.logGet(ctx, key, cfg.Window)
+	}
+	if l.sliding(cfg) {
+		return l.slidingGet(ctx, key, cfg.Window, now)
+	}
+	return l.store.Get(storeContext(ctx), key)
+}
+
+// deleteKey removes every counter backing key; deleting key also clears its
+// request log, token bucket and GCRA timestamp. Configs without a positive window never had sliding counters.
+func (l *Limiter) deleteKey(ctx context.Context, rs ResetStore, key string, cfg config.ClientConfig, now time.Time) error {
+	if !l.sliding(cfg) || cfg.Window <= 0 {
+		return rs.Delete(ctx, key)
+	}
+	sw := newSlidingWindow(key, cfg.Window, now)
+	if err := rs.Delete(ctx, sw.cur); err != nil {
+		return err
+	}
+	return rs.Delete(ctx, sw.prev)
+}
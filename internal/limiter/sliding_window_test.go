@@ -0,0 +1,120 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// slidingTestClock starts at the beginning of an epoch-aligned minute.
+type slidingTestClock struct {
+	now time.Time
+}
+
+func newSlidingTestClock() *slidingTestClock {
+	return &slidingTestClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *slidingTestClock) Now() time.Time { return c.now }
+
+func newClockedLimiter(clock *slidingTestClock, opts ...Option) *Limiter {
+	store := memory.NewMemoryStore(memory.WithClock(clock.Now))
+	opts = append([]Option{
+		WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 50, Window: time.Minute}}),
+		WithClock(clock.Now),
+	}, opts...)
+	return New(store, opts...)
+}
+
+func allowedCount(t *testing.T, l *Limiter, n int) int {
+	t.Helper()
+	allowed := 0
+	for i := 0; i < n; i++ {
+		res, err := l.AllowResult("c1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestSlidingWindowBoundaryBurst(t *testing.T) {
+	for _, tt := range []struct {
+		algorithm Algorithm
+		want      int
+	}{
+		// The fixed window opened at the first request and resets a minute
+		// later, so the second burst lands in a fresh window.
+		{AlgorithmFixedWindow, 50},
+		// One second into the next window, 59/60 of the previous 50 still
+		// count: floor(49.17) + 1 = 50 admits one request.
+		{AlgorithmSlidingWindow, 1},
+	} {
+		clock := newSlidingTestClock()
+		l := newClockedLimiter(clock, WithAlgorithm(tt.algorithm))
+
+		allowedCount(t, l, 1)
+		clock.now = clock.now.Add(59 * time.Second)
+		if got := allowedCount(t, l, 49); got != 49 {
+			t.Fatalf("algorithm %d: expected the first 50 allowed, got %d", tt.algorithm, got+1)
+		}
+
+		clock.now = clock.now.Add(2 * time.Second)
+		if got := allowedCount(t, l, 50); got != tt.want {
+			t.Fatalf("algorithm %d: expected %d of the straddling burst allowed, got %d", tt.algorithm, tt.want, got)
+		}
+	}
+}
+
+func TestSlidingWindowWeighting(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmSlidingWindow))
+
+	allowedCount(t, l, 50)
+
+	// Halfway through the next window the previous 50 count as 25.
+	clock.now = clock.now.Add(90 * time.Second)
+	res, err := l.Peek("c1")
+	if err != nil || res.Count != 25 || res.Remaining != 25 {
+		t.Fatalf("expected 25 counted halfway through, got %+v, %v", res, err)
+	}
+	if !res.ResetAt.Equal(time.Date(2024, 1, 1, 12, 2, 0, 0, time.UTC)) {
+		t.Fatalf("expected reset at the end of the current window, got %v", res.ResetAt)
+	}
+	if got := allowedCount(t, l, 30); got != 25 {
+		t.Fatalf("expected 25 more allowed, got %d", got)
+	}
+
+	// Two windows later nothing from the first burst counts.
+	clock.now = clock.now.Add(2 * time.Minute)
+	if got := allowedCount(t, l, 50); got != 50 {
+		t.Fatalf("expected a full budget once both windows passed, got %d", got)
+	}
+}
+
+func TestSlidingWindowSetLimitAndReset(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmSlidingWindow))
+	l.SetLimit("c1", config.ClientConfig{Limit: 2, Window: time.Minute})
+
+	if got := allowedCount(t, l, 3); got != 2 {
+		t.Fatalf("expected SetLimit to apply, got %d allowed", got)
+	}
+	results, err := l.PeekMany([]string{"c1", "other"})
+	if err != nil || results[0].Allowed || results[0].Remaining != 0 || results[1].Remaining != results[1].Limit {
+		t.Fatalf("unexpected peek %+v, %v", results, err)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	if err := l.Reset("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := allowedCount(t, l, 3); got != 2 {
+		t.Fatalf("expected the previous window cleared by Reset, got %d allowed", got)
+	}
+}
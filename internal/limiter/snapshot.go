@@ -0,0 +1,68 @@
+package limiter
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Snapshot is a point-in-time view of the limiter's state for debugging.
+type Snapshot struct {
+	Taken time.Time `json:"taken"`
+	// ActiveKeys counts the keys holding a live window under the limiter's
+	// namespace, or is -1 when the store cannot list its keys.
+	ActiveKeys int  `json:"active_keys"`
+	Degraded   bool `json:"degraded"`
+	// Top lists the busiest keys, highest count first. Only counters have a
+	// count, so keys held by logs, buckets or TATs are left out.
+	Top []KeyUsage `json:"top"`
+}
+
+// KeyUsage is one key's count in its current window.
+type KeyUsage struct {
+	Key    string    `json:"key"`
+	Count  int64     `json:"count"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// Snapshot reports the degraded state and, if the store implements KeyLister,
+// the number of live keys and the topN busiest ones. Client keys carry the
+// client ID, as in "rate:<client>". Without a namespace every key in the
+// store is listed, so it can be slow on a large shared Redis.
+func (l *Limiter) Snapshot(topN int) (Snapshot, error) {
+	snap := Snapshot{Taken: l.now(), ActiveKeys: -1, Degraded: l.Degraded()}
+
+	kl, ok := l.store.(KeyLister)
+	if !ok {
+		return snap, nil
+	}
+	ctx := context.Background()
+	keys, err := kl.Keys(ctx, l.namespaced(""))
+	if err != nil {
+		return snap, err
+	}
+	entries, err := l.getMany(ctx, keys)
+	if err != nil {
+		return snap, err
+	}
+
+	usage := make([]KeyUsage, 0, len(keys))
+	for i, key := range keys {
+		if entries[i].Count > 0 {
+			usage = append(usage, KeyUsage{Key: key, Count: entries[i].Count, Expiry: entries[i].Expiry})
+		}
+	}
+	snap.ActiveKeys = len(keys)
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Count != usage[j].Count {
+			return usage[i].Count > usage[j].Count
+		}
+		return usage[i].Key < usage[j].Key
+	})
+	if len(usage) > topN {
+		usage = usage[:max(topN, 0)]
+	}
+	snap.Top = usage
+	return snap, nil
+}
@@ -0,0 +1,65 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestSnapshot(t *testing.T) {
+	store := memory.NewMemoryStore()
+	defer store.Close()
+	cfgs := map[string]config.ClientConfig{"a": {Limit: 10, Window: time.Minute}}
+	l := New(store, WithConfigs(cfgs), WithNamespace("prod"))
+	other := New(store, WithNamespace("staging"))
+
+	for client, n := range map[string]int{"a": 5, "b": 2, "c": 2, "d": 1} {
+		for i := 0; i < n; i++ {
+			l.AllowResult(client)
+		}
+	}
+	other.AllowResult("a")
+
+	snap, err := l.Snapshot(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snap.ActiveKeys != 4 || snap.Degraded {
+		t.Fatalf("expected 4 active keys in the namespace, got %+v", snap)
+	}
+	want := []KeyUsage{{Key: "prod:rate:a", Count: 5}, {Key: "prod:rate:b", Count: 2}, {Key: "prod:rate:c", Count: 2}}
+	if len(snap.Top) != len(want) {
+		t.Fatalf("expected the top 3 keys, got %+v", snap.Top)
+	}
+	for i, w := range want {
+		if got := snap.Top[i]; got.Key != w.Key || got.Count != w.Count || got.Expiry.IsZero() {
+			t.Fatalf("top %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+}
+
+func TestSnapshotWithoutKeyLister(t *testing.T) {
+	l := New(&mockStoreError{}, WithFailurePolicy(FailClosed))
+	l.AllowResult("c1")
+
+	snap, err := l.Snapshot(10)
+	if err != nil || snap.ActiveKeys != -1 || snap.Top != nil || !snap.Degraded {
+		t.Fatalf("expected only the degraded state, got %+v err=%v", snap, err)
+	}
+}
+
+func TestSnapshotCountsAlgorithmState(t *testing.T) {
+	store := memory.NewMemoryStore()
+	defer store.Close()
+	l := New(store, WithAlgorithm(AlgorithmSlidingLog))
+	l.SetLimit("b", config.ClientConfig{Limit: 10, Window: time.Minute, Algorithm: "token_bucket"})
+	l.AllowResult("a")
+	l.AllowResult("b")
+
+	snap, err := l.Snapshot(10)
+	if err != nil || snap.ActiveKeys != 2 || len(snap.Top) != 0 {
+		t.Fatalf("expected the log and bucket counted as active without a count, got %+v, %v", snap, err)
+	}
+}
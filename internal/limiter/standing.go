@@ -0,0 +1,72 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// WithGoodStandingBonus rewards clients that have not been denied for after
+// with bonus extra requests past their limit, spent on demand. Spending the
+// whole bonus, or a denial once it is gone or not yet earned, restarts the
+// period, so the bonus is re-earned only by another after of compliance.
+// Clients never denied count as compliant since the limiter was created.
+// Bonus requests are allowed with UsedBurst set.
+func WithGoodStandingBonus(bonus int, after time.Duration) Option {
+	return func(l *Limiter) {
+		l.standing = &goodStanding{bonus: bonus, after: after, clients: map[string]*standing{}}
+	}
+}
+
+// goodStanding keeps state only for clients denied within the last after or
+// holding a partly spent bonus.
+type goodStanding struct {
+	bonus int
+	after time.Duration
+	since time.Time
+
+	mu      sync.Mutex
+	clients map[string]*standing
+	swept   time.Time
+}
+
+type standing struct {
+	// violatedAt starts the client's current compliance period.
+	violatedAt time.Time
+	used       int
+}
+
+// grant spends one bonus request for a client denied at now, reporting false
+// and restarting its compliance period when none is available.
+func (g *goodStanding) grant(client string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if now.Sub(g.swept) >= g.after {
+		g.sweepLocked(now)
+	}
+	s, ok := g.clients[client]
+	if !ok {
+		s = &standing{violatedAt: g.since}
+		g.clients[client] = s
+	}
+	if now.Sub(s.violatedAt) < g.after || s.used >= g.bonus {
+		*s = standing{violatedAt: now}
+		return false
+	}
+	s.used++
+	if s.used == g.bonus {
+		*s = standing{violatedAt: now}
+	}
+	return true
+}
+
+// sweepLocked drops clients that have earned their whole bonus back; they
+// fare exactly like clients never denied. It runs at most once per after.
+func (g *goodStanding) sweepLocked(now time.Time) {
+	g.swept = now
+	for client, s := range g.clients {
+		if s.used == 0 && now.Sub(s.violatedAt) >= g.after {
+			delete(g.clients, client)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestGoodStandingBonus(t *testing.T) {
+	now := time.Date(2025, 10, 23, 10, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	l := New(memory.NewMemoryStore(memory.WithClock(clock)),
+		WithConfigs(map[string]config.ClientConfig{"good": {Limit: 2, Window: time.Minute}, "bad": {Limit: 2, Window: time.Minute}}),
+		WithClock(clock),
+		WithGoodStandingBonus(2, time.Hour),
+	)
+	// fill uses up the client's window and reports whether each of extra more
+	// requests was allowed.
+	fill := func(client string, extra int) []bool {
+		for i := 0; i < 2; i++ {
+			if res, _ := l.AllowResult(client); !res.Allowed {
+				t.Fatalf("%s: expected request %d within the limit, got %+v", client, i+1, res)
+			}
+		}
+		var allowed []bool
+		for i := 0; i < extra; i++ {
+			res, _ := l.AllowResult(client)
+			allowed = append(allowed, res.Allowed)
+		}
+		return allowed
+	}
+
+	// No bonus until the client has been compliant for the whole period.
+	if got := fill("bad", 1); got[0] {
+		t.Fatal("expected no bonus before earning it")
+	}
+
+	now = now.Add(30 * time.Minute)
+	if got := fill("bad", 1); got[0] {
+		t.Fatal("expected no bonus half way through the period")
+	}
+
+	now = now.Add(30 * time.Minute)
+	got := fill("good", 3)
+	if !got[0] || !got[1] || got[2] {
+		t.Fatalf("expected a compliant client to spend exactly 2 bonus requests, got %v", got)
+	}
+	if got := fill("bad", 1); got[0] {
+		t.Fatal("expected the repeated violation to have restarted the period")
+	}
+
+	// Spending the bonus restarts the period; it is re-earned after another hour.
+	now = now.Add(30 * time.Minute)
+	if got := fill("good", 1); got[0] {
+		t.Fatal("expected the bonus not yet re-earned")
+	}
+	now = now.Add(time.Hour)
+	if got := fill("good", 1); !got[0] {
+		t.Fatal("expected the bonus re-earned by continued compliance")
+	}
+}
+
+func TestGoodStandingBonusResult(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	l := New(memory.NewMemoryStore(memory.WithClock(clock)),
+		WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}),
+		WithClock(clock),
+		WithGoodStandingBonus(1, time.Minute),
+	)
+	now = now.Add(time.Minute)
+
+	l.AllowResult("c1")
+	res, _ := l.AllowResult("c1")
+	if !res.Allowed || !res.UsedBurst || res.Reason != "" || res.Remaining != 0 {
+		t.Fatalf("expected a bonus request reported as burst with nothing remaining, got %+v", res)
+	}
+}
+
+func TestGoodStandingForgetsCompliantClients(t *testing.T) {
+	now := time.Date(2025, 10, 23, 10, 0, 0, 0, time.UTC)
+	g := &goodStanding{bonus: 2, after: time.Hour, since: now, clients: map[string]*standing{}}
+
+	for i := 0; i < 100; i++ {
+		g.grant(fmt.Sprintf("spoofed-%d", i), now)
+	}
+	if len(g.clients) != 100 {
+		t.Fatalf("expected every denied client tracked, got %d", len(g.clients))
+	}
+
+	now = now.Add(time.Hour)
+	if !g.grant("spoofed-0", now) {
+		t.Fatal("expected a compliant client to earn its bonus")
+	}
+	if len(g.clients) != 1 {
+		t.Fatalf("expected only the client with a partly spent bonus kept, got %d", len(g.clients))
+	}
+}
@@ -0,0 +1,118 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// ErrBucketUnsupported is returned for AlgorithmTokenBucket decisions when the
+// store does not implement BucketStore.
+var ErrBucketUnsupported = errors.New("limiter: store does not support the token bucket")
+
+// BucketStore is implemented by stores that can keep a token bucket per key
+// for AlgorithmTokenBucket. A new bucket starts full.
+type BucketStore interface {
+	// TakeTokens refills key's bucket at rate tokens per second up to
+	// capacity and then takes n tokens if that many are available. It returns
+	// the tokens left and whether n were taken. Both steps must be atomic. A
+	// bucket left alone for ttl is full again and may be dropped.
+	TakeTokens(ctx context.Context, key string, n, capacity int64, rate float64, ttl time.Duration) (tokens float64, taken bool, err error)
+	// PeekTokens returns the tokens key's bucket holds after refilling.
+	PeekTokens(ctx context.Context, key string, capacity int64, rate float64) (float64, error)
+}
+
+// tokenBucket is a client's bucket: it holds capacity tokens, the limit plus
+// its burst, and refills at limit tokens per window.
+type tokenBucket struct {
+	capacity int64
+	rate     float64
+}
+
+func newTokenBucket(cfg config.ClientConfig) tokenBucket {
+	return tokenBucket{
+		capacity: int64(windowCapacity(cfg)),
+		rate:     float64(cfg.Limit) / cfg.Window.Seconds(),
+	}
+}
+
+// after is how long the bucket takes to gain need tokens. A bucket that never
+// refills is reported as gaining them a window later.
+func (b tokenBucket) after(need float64, window time.Duration) time.Duration {
+	if need <= 0 {
+		return 0
+	}
+	if b.rate <= 0 {
+		return window
+	}
+	return time.Duration(math.Ceil(need / b.rate * float64(time.Second)))
+}
+
+// at is when the bucket will have gained need tokens, zero if it already has
+// them.
+func (b tokenBucket) at(now time.Time, need float64, window time.Duration) time.Time {
+	if need <= 0 {
+		return time.Time{}
+	}
+	return now.Add(b.after(need, window))
+}
+
+// used is the whole number of tokens missing from a bucket holding tokens.
+func (b tokenBucket) used(tokens float64) int64 {
+	return int64(math.Ceil(float64(b.capacity) - tokens))
+}
+
+// bucketTake decides a request from key's token bucket. Like the sliding log
+// it only charges admitted requests; denials report the count the request
+// would have made and expire when n tokens are back, so Retry-After is exact.
+// Admitted requests expire when the bucket is full again.
+func (l *Limiter) bucketTake(ctx context.Context, key string, n int64, cfg config.ClientConfig, now time.Time) (StoreDecision, error) {
+	bs, ok := l.store.(BucketStore)
+	if !ok {
+		return StoreDecision{}, ErrBucketUnsupported
+	}
+	b := newTokenBucket(cfg)
+	ttl := b.after(float64(b.capacity), cfg.Window)
+	tokens, taken, err := bs.TakeTokens(storeContext(ctx), key, n, b.capacity, b.rate, ttl)
+	if err != nil {
+		return StoreDecision{}, err
+	}
+
+	d := StoreDecision{
+		Allowed:        taken,
+		Count:          b.used(tokens),
+		Remaining:      int64(math.Floor(tokens)),
+		RemainingFloat: tokens,
+		Expiry:         b.at(now, float64(b.capacity)-tokens, cfg.Window),
+	}
+	if !taken {
+		d.Count += n
+		d.Expiry = b.at(now, float64(n)-tokens, cfg.Window)
+	} else if l.leaky(cfg) {
+		d.Delay = queueDelay(cfg, tokens, n)
+	}
+	return d, nil
+}
+
+// bucketGet is the read-only counterpart of bucketTake.
+func (l *Limiter) bucketGet(ctx context.Context, key string, cfg config.ClientConfig, now time.Time) (int64, time.Time, error) {
+	bs, ok := l.store.(BucketStore)
+	if !ok {
+		return 0, time.Time{}, ErrBucketUnsupported
+	}
+	b := newTokenBucket(cfg)
+	tokens, err := bs.PeekTokens(storeContext(ctx), key, b.capacity, b.rate)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return b.used(tokens), b.at(now, float64(b.capacity)-tokens, cfg.Window), nil
+}
+
+// bucketConfigured reports whether cfg is counted with a token or leaky
+// bucket; configs without a positive window never reach the bucket.
+func (l *Limiter) bucketConfigured(cfg config.ClientConfig) bool {
+	return (l.algorithmFor(cfg) == AlgorithmTokenBucket || l.leaky(cfg)) && cfg.Window > 0
+}
@@ -0,0 +1,111 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func newBucketLimiter(clock *slidingTestClock) *Limiter {
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmTokenBucket))
+	// One token per second, with room for five more than the steady rate.
+	l.SetLimit("c1", config.ClientConfig{Limit: 10, Window: 10 * time.Second, Burst: 5})
+	return l
+}
+
+func TestTokenBucketBurstAndRefill(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newBucketLimiter(clock)
+	start := clock.now
+
+	if got := allowedCount(t, l, 20); got != 15 {
+		t.Fatalf("expected the full bucket of limit+burst allowed, got %d", got)
+	}
+	res, err := l.AllowResult("c1")
+	if err != nil || res.Allowed || res.Remaining != 0 || res.Count != 16 || res.Limit != 15 {
+		t.Fatalf("unexpected denial %+v, %v", res, err)
+	}
+	if want := start.Add(time.Second); !res.ResetAt.Equal(want) {
+		t.Fatalf("expected reset when the next token arrives at %v, got %v", want, res.ResetAt)
+	}
+
+	clock.now = start.Add(1500 * time.Millisecond)
+	res, err = l.AllowResult("c1")
+	if err != nil || !res.Allowed || res.RemainingFloat != 0.5 || res.Remaining != 0 {
+		t.Fatalf("expected one refilled token taken with half left, got %+v, %v", res, err)
+	}
+	if !res.UsedBurst {
+		t.Fatal("expected a request beyond the steady limit to report UsedBurst")
+	}
+	if got := allowedCount(t, l, 1); got != 0 {
+		t.Fatalf("expected half a token to deny, got %d allowed", got)
+	}
+
+	// Denials took nothing, so the bucket refills on schedule.
+	clock.now = start.Add(time.Minute)
+	if got := allowedCount(t, l, 20); got != 15 {
+		t.Fatalf("expected the bucket full again, got %d", got)
+	}
+}
+
+func TestTokenBucketSteadyRate(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newClockedLimiter(clock, WithAlgorithm(AlgorithmTokenBucket))
+	l.SetLimit("c1", config.ClientConfig{Limit: 10, Window: 10 * time.Second})
+
+	allowedCount(t, l, 10)
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		clock.now = clock.now.Add(100 * time.Millisecond)
+		allowed += allowedCount(t, l, 1)
+	}
+	if allowed != 10 {
+		t.Fatalf("expected one request per second over 10s, got %d", allowed)
+	}
+}
+
+func TestTokenBucketPeekAndReset(t *testing.T) {
+	clock := newSlidingTestClock()
+	l := newBucketLimiter(clock)
+	start := clock.now
+
+	if res, err := l.Peek("c1"); err != nil || res.Remaining != 15 || !res.ResetAt.IsZero() {
+		t.Fatalf("expected a full bucket with no reset, got %+v, %v", res, err)
+	}
+
+	allowedCount(t, l, 12)
+	clock.now = start.Add(2 * time.Second)
+	res, err := l.Peek("c1")
+	if err != nil || res.Count != 10 || res.Remaining != 5 || !res.Allowed {
+		t.Fatalf("unexpected peek %+v, %v", res, err)
+	}
+	if want := start.Add(12 * time.Second); !res.ResetAt.Equal(want) {
+		t.Fatalf("expected reset when the bucket is full at %v, got %v", want, res.ResetAt)
+	}
+	results, err := l.PeekMany([]string{"c1", "other"})
+	if err != nil || results[0].Remaining != 5 || results[1].Remaining != results[1].Limit {
+		t.Fatalf("unexpected peeks %+v, %v", results, err)
+	}
+
+	if err := l.ResetWindow("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := allowedCount(t, l, 20); got != 15 {
+		t.Fatalf("expected a full bucket after ResetWindow, got %d", got)
+	}
+	if err := l.Reset("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := l.Peek("c1"); res.Count != 0 {
+		t.Fatalf("expected Reset to refill the bucket, got %+v", res)
+	}
+}
+
+func TestTokenBucketUnsupportedStore(t *testing.T) {
+	l := New(&ttlStore{}, WithAlgorithm(AlgorithmTokenBucket))
+	if _, err := l.AllowResult("c1"); !errors.Is(err, ErrBucketUnsupported) {
+		t.Fatalf("expected ErrBucketUnsupported, got %v", err)
+	}
+}
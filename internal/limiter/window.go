@@ -0,0 +1,43 @@
+package limiter
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+// DefaultWindow replaces a zero or negative window in a limited config, which
+// would otherwise reach the store as a TTL of 0: Redis rejects it and the
+// memory store expires the key at once, so the client would never be limited.
+const DefaultWindow = time.Minute
+
+// windowWarning rate-limits the bad window warning like the fail-open one,
+// since a misconfigured default would otherwise log on every request.
+type windowWarning struct {
+	lastNanos  atomic.Int64
+	suppressed atomic.Int64
+}
+
+// withSafeWindow returns cfg with a positive window. Configs that never reach
+// the store (unlimited or zero capacity) are returned unchanged.
+func (l *Limiter) withSafeWindow(client string, cfg config.ClientConfig) config.ClientConfig {
+	if cfg.Window > 0 || windowCapacity(cfg) <= 0 {
+		return cfg
+	}
+
+	now := l.now().UnixNano()
+	last := l.windowWarn.lastNanos.Load()
+	if (last != 0 && now-last < int64(degradedLogInterval)) || !l.windowWarn.lastNanos.CompareAndSwap(last, now) {
+		l.windowWarn.suppressed.Add(1)
+	} else {
+		l.logger.Warn("rate limit window is not positive, using default",
+			"client", client,
+			"window", cfg.Window,
+			"default", DefaultWindow,
+			"suppressed", l.windowWarn.suppressed.Swap(0),
+		)
+	}
+	cfg.Window = DefaultWindow
+	return cfg
+}
@@ -0,0 +1,87 @@
+package limiter
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// ttlStore records the TTLs the limiter asks for. It only implements Store,
+// so every increment goes through Increment.
+type ttlStore struct {
+	mem  *memory.MemoryStore
+	ttls []time.Duration
+}
+
+func (s *ttlStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	s.ttls = append(s.ttls, ttl)
+	return s.mem.Increment(ctx, key, ttl)
+}
+
+func (s *ttlStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	return s.mem.Get(ctx, key)
+}
+
+func (s *ttlStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return s.mem.SetIfAbsent(ctx, key, count, ttl)
+}
+
+func TestZeroWindowUsesDefault(t *testing.T) {
+	for _, window := range []time.Duration{0, -time.Second} {
+		var buf bytes.Buffer
+		store := &ttlStore{mem: memory.NewMemoryStore()}
+		l := New(store,
+			WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 1, Window: window}}),
+			WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+		)
+
+		start := time.Now()
+		res, err := l.AllowResult("c1")
+		if err != nil || !res.Allowed {
+			t.Fatalf("window %v: expected first request allowed, got %+v, %v", window, res, err)
+		}
+		if len(store.ttls) != 1 || store.ttls[0] != DefaultWindow {
+			t.Fatalf("window %v: expected TTL %v, got %v", window, DefaultWindow, store.ttls)
+		}
+		if res.ResetAt.Before(start.Add(DefaultWindow-time.Second)) || res.ResetAt.After(start.Add(DefaultWindow+time.Second)) {
+			t.Fatalf("window %v: expected reset about %v out, got %v", window, DefaultWindow, res.ResetAt.Sub(start))
+		}
+		if res, _ := l.AllowResult("c1"); res.Allowed {
+			t.Fatalf("window %v: expected the key to persist and deny the second request", window)
+		}
+		if res, _ := l.CheckScoped("c1", ""); res.Allowed || res.Remaining != 0 {
+			t.Fatalf("window %v: expected check to see the same window, got %+v", window, res)
+		}
+
+		if n := strings.Count(buf.String(), "rate limit window is not positive"); n != 1 {
+			t.Fatalf("window %v: expected one rate-limited warning, got %d: %s", window, n, buf.String())
+		}
+		if !strings.Contains(buf.String(), "client=c1") || !strings.Contains(buf.String(), "default=1m0s") {
+			t.Fatalf("window %v: unexpected warning %s", window, buf.String())
+		}
+	}
+}
+
+func TestZeroWindowAllowWithConfig(t *testing.T) {
+	var buf bytes.Buffer
+	store := &ttlStore{mem: memory.NewMemoryStore()}
+	l := New(store, WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	l.AllowWithConfig("c1", config.ClientConfig{Limit: 5})
+	if len(store.ttls) != 1 || store.ttls[0] != DefaultWindow {
+		t.Fatalf("expected TTL %v, got %v", DefaultWindow, store.ttls)
+	}
+
+	// Unlimited configs never reach the store, so their window is irrelevant.
+	buf.Reset()
+	l.AllowWithConfig("c2", config.ClientConfig{Limit: config.Unlimited})
+	if len(store.ttls) != 1 || buf.Len() != 0 {
+		t.Fatalf("expected unlimited config left alone, got TTLs %v, log %s", store.ttls, buf.String())
+	}
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"expvar"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// PublishExpvar publishes the collector's decision totals as the expvar map
+// name, which the expvar handler serves at /debug/vars. When given, l adds its
+// degraded state and store its active key count, if the store can count keys.
+// Values are computed on every read. Like expvar.Publish, it panics if name is
+// already published.
+func (c *Collector) PublishExpvar(name string, l *limiter.Limiter, store limiter.Store) *expvar.Map {
+	vars := new(expvar.Map).Init()
+	vars.Set("allowed", expvar.Func(func() any { return c.total("rate_limiter_requests_total", "result", "allowed") }))
+	vars.Set("denied", expvar.Func(func() any { return c.total("rate_limiter_requests_total", "result", "denied") }))
+	vars.Set("failed_open", expvar.Func(func() any { return c.total("rate_limiter_failed_open_total", "", "") }))
+	if l != nil {
+		vars.Set("degraded", expvar.Func(func() any { return l.Degraded() }))
+	}
+	if counter, ok := store.(interface{ Len() int }); ok {
+		vars.Set("active_keys", expvar.Func(func() any { return counter.Len() }))
+	}
+	expvar.Publish(name, vars)
+	return vars
+}
+
+// total sums the samples of the counter family name whose label has value,
+// or all of them when label is empty.
+func (c *Collector) total(name, label, value string) int64 {
+	families, err := c.registry.Gather()
+	if err != nil {
+		return 0
+	}
+	var sum float64
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range mf.GetMetric() {
+			if label != "" {
+				for _, lp := range m.GetLabel() {
+					if lp.GetName() == label && lp.GetValue() != value {
+						continue metrics
+					}
+				}
+			}
+			sum += m.GetCounter().GetValue()
+		}
+	}
+	return int64(sum)
+}
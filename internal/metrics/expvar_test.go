@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/middleware"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// failingStore fails every call, as an unreachable store would.
+type failingStore struct{}
+
+func (failingStore) Increment(context.Context, string, time.Duration) (int64, time.Time, error) {
+	return 0, time.Time{}, errors.New("store down")
+}
+
+func (failingStore) Get(context.Context, string) (int64, time.Time, error) {
+	return 0, time.Time{}, errors.New("store down")
+}
+
+func (failingStore) SetIfAbsent(context.Context, string, int64, time.Duration) (bool, error) {
+	return false, errors.New("store down")
+}
+
+func doRequest(mw *middleware.RateLimitMiddleware, client string) {
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-Client-ID", client)
+	mw.Handler(func(w http.ResponseWriter, r *http.Request) {})(httptest.NewRecorder(), req)
+}
+
+func TestPublishExpvar(t *testing.T) {
+	c := NewCollector()
+	store := memory.NewMemoryStore()
+	defer store.Close()
+	l := limiter.New(store,
+		limiter.WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}),
+		limiter.WithMetrics(c),
+	)
+	mw := middleware.NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(io.Discard, nil)), middleware.WithMetrics(c))
+	c.PublishExpvar("ratelimiter_test", l, store)
+
+	for _, client := range []string{"c1", "c1", "c1", "c2"} {
+		doRequest(mw, client)
+	}
+
+	rec := httptest.NewRecorder()
+	expvar.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/vars", nil))
+	var vars struct {
+		RateLimiter struct {
+			Allowed    int64 `json:"allowed"`
+			Denied     int64 `json:"denied"`
+			FailedOpen int64 `json:"failed_open"`
+			Degraded   bool  `json:"degraded"`
+			ActiveKeys int   `json:"active_keys"`
+		} `json:"ratelimiter_test"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("decoding /debug/vars: %v", err)
+	}
+	got := vars.RateLimiter
+	if got.Allowed != 3 || got.Denied != 1 || got.FailedOpen != 0 || got.Degraded || got.ActiveKeys != 2 {
+		t.Fatalf("unexpected vars %+v", got)
+	}
+}
+
+func TestPublishExpvarDegraded(t *testing.T) {
+	c := NewCollector()
+	l := limiter.New(failingStore{}, limiter.WithFailurePolicy(limiter.FailOpen), limiter.WithMetrics(c),
+		limiter.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	vars := c.PublishExpvar("ratelimiter_test_degraded", l, failingStore{})
+
+	if got := vars.Get("degraded").String(); got != "false" {
+		t.Fatalf("expected not degraded before any error, got %s", got)
+	}
+	l.Allow("c1")
+	if got := vars.Get("degraded").String(); got != "true" {
+		t.Fatalf("expected degraded after a store error, got %s", got)
+	}
+	if got := vars.Get("failed_open").String(); got != "1" {
+		t.Fatalf("expected 1 fail-open request, got %s", got)
+	}
+	if vars.Get("active_keys") != nil {
+		t.Fatal("expected no active_keys for a store that cannot count keys")
+	}
+}
@@ -0,0 +1,106 @@
+// Package metrics collects rate limiter events in a Prometheus registry and
+// serves them with promhttp.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// Collector counts limiter, store and middleware events. It implements
+// limiter.Metrics, limiter.CardinalityMetrics, memory.Metrics, middleware.Metrics and
+// middleware.ShadowMetrics, and serves its registry as an http.Handler.
+// Counters are not labelled by client to keep cardinality bounded.
+type Collector struct {
+	registry *prometheus.Registry
+	handler  http.Handler
+
+	decisions     *prometheus.CounterVec
+	shadow        *prometheus.CounterVec
+	failedOpen    prometheus.Counter
+	keysEvicted   prometheus.Counter
+	keysReclaimed prometheus.Counter
+	growthAlerts  prometheus.Counter
+}
+
+// NewCollector returns a collector with its counters registered on a fresh
+// registry.
+func NewCollector() *Collector {
+	c := &Collector{
+		registry: prometheus.NewRegistry(),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rate_limiter_requests_total",
+			Help: "Rate limit decisions by result and denial reason.",
+		}, []string{"result", "reason"}),
+		shadow: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rate_limiter_shadow_decisions_total",
+			Help: "Shadow limiter decisions next to the primary's.",
+		}, []string{"allowed", "shadow_allowed"}),
+		failedOpen: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rate_limiter_failed_open_total",
+			Help: "Requests admitted unenforced because the store failed.",
+		}),
+		keysEvicted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rate_limiter_keys_evicted_total",
+			Help: "Keys evicted early to stay under the store's key cap.",
+		}),
+		keysReclaimed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rate_limiter_keys_reclaimed_total",
+			Help: "Expired keys removed by the store's sweep.",
+		}),
+		growthAlerts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rate_limiter_key_growth_alerts_total",
+			Help: "Intervals in which new keys exceeded the growth threshold.",
+		}),
+	}
+	c.registry.MustRegister(c.decisions, c.shadow, c.failedOpen, c.keysEvicted, c.keysReclaimed, c.growthAlerts)
+	c.handler = promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+	return c
+}
+
+// Registry returns the registry the counters live in, e.g. to add the Go
+// runtime collectors next to them.
+func (c *Collector) Registry() *prometheus.Registry {
+	return c.registry
+}
+
+func (c *Collector) RequestDecided(res limiter.Result) {
+	c.decisions.WithLabelValues(result(res.Allowed), string(res.Reason)).Inc()
+}
+
+func (c *Collector) ShadowDecision(client string, primaryAllowed, shadowAllowed bool) {
+	c.shadow.WithLabelValues(strconv.FormatBool(primaryAllowed), strconv.FormatBool(shadowAllowed)).Inc()
+}
+
+func (c *Collector) FailedOpen(client string) {
+	c.failedOpen.Inc()
+}
+
+func (c *Collector) KeyGrowthExceeded(created int64) {
+	c.growthAlerts.Inc()
+}
+
+func (c *Collector) KeysEvicted(n int) {
+	c.keysEvicted.Add(float64(n))
+}
+
+func (c *Collector) KeysReclaimed(n int) {
+	c.keysReclaimed.Add(float64(n))
+}
+
+// ServeHTTP serves the registry with promhttp.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.handler.ServeHTTP(w, r)
+}
+
+func result(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+	return "denied"
+}
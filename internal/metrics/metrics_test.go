@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/middleware"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+var (
+	_ limiter.Metrics            = (*Collector)(nil)
+	_ limiter.CardinalityMetrics = (*Collector)(nil)
+	_ memory.Metrics             = (*Collector)(nil)
+	_ middleware.Metrics         = (*Collector)(nil)
+	_ middleware.ShadowMetrics   = (*Collector)(nil)
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	c := NewCollector()
+	store := memory.NewMemoryStore(memory.WithMetrics(c), memory.WithMaxKeys(1))
+	l := limiter.New(store,
+		limiter.WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}),
+		limiter.WithMetrics(c),
+	)
+	mw := middleware.NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(io.Discard, nil)), middleware.WithMetrics(c))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", mw.Handler(func(w http.ResponseWriter, r *http.Request) {}))
+	mux.Handle("/metrics", c)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, client := range []string{"c1", "c1", "c1", "c2"} {
+		req, _ := http.NewRequest("GET", srv.URL+"/api", nil)
+		req.Header.Set("X-Client-ID", client)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		`rate_limiter_requests_total{reason="",result="allowed"} 3`,
+		`rate_limiter_requests_total{reason="rate_limit",result="denied"} 1`,
+		`rate_limiter_keys_evicted_total 1`,
+		`rate_limiter_failed_open_total 0`,
+		`rate_limiter_key_growth_alerts_total 0`,
+		`# TYPE rate_limiter_requests_total counter`,
+	} {
+		if !strings.Contains(string(body), want+"\n") {
+			t.Errorf("expected %q in scrape:\n%s", want, body)
+		}
+	}
+}
+
+// scrape returns what c serves at /metrics.
+func scrape(t *testing.T, c *Collector) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("scrape failed with %d: %s", rec.Code, rec.Body)
+	}
+	return rec.Body.String()
+}
+
+func TestLabelEscaping(t *testing.T) {
+	c := NewCollector()
+	c.RequestDecided(limiter.Result{Reason: limiter.Reason("a\\b \"c\"\nd é")})
+
+	body := scrape(t, c)
+	want := `rate_limiter_requests_total{reason="a\\b \"c\"\nd é",result="denied"} 1`
+	if !strings.Contains(body, want+"\n") {
+		t.Errorf("expected %q in:\n%s", want, body)
+	}
+}
+
+func TestShadowSeries(t *testing.T) {
+	c := NewCollector()
+	c.ShadowDecision("c1", true, false)
+	c.ShadowDecision("c1", true, false)
+	c.ShadowDecision("c1", true, true)
+
+	body := scrape(t, c)
+	for _, want := range []string{
+		`rate_limiter_shadow_decisions_total{allowed="true",shadow_allowed="false"} 2`,
+		`rate_limiter_shadow_decisions_total{allowed="true",shadow_allowed="true"} 1`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"math"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// Allower is a minimal per-client rate limiting algorithm, such as a token
+// bucket, with the same shape as limiter.Limiter.Allow.
+type Allower interface {
+	Allow(client string) (bool, int, time.Time, error)
+}
+
+// Checker is optionally implemented by an Allower that can report a decision
+// without consuming quota.
+type Checker interface {
+	Check(client string) (bool, int, time.Time, error)
+}
+
+// FractionalAllower is optionally implemented by an Allower whose budget is
+// naturally fractional. The adapter then reports the exact value in
+// Result.RemainingFloat.
+type FractionalAllower interface {
+	AllowFloat(client string) (bool, float64, time.Time, error)
+}
+
+// AllowerAdapter lets any Allower back the middleware. Scope, Class and Cost
+// of a request are ignored; each request is one unit against the client.
+// Check-only requests consume quota unless the Allower is also a Checker.
+type AllowerAdapter struct {
+	Allower Allower
+	// Config reports the client's limit for headers; nil uses
+	// config.DefaultConfig for every client.
+	Config func(client string) config.ClientConfig
+}
+
+func (a *AllowerAdapter) AcquireRequest(req limiter.Request) (limiter.Result, func(), error) {
+	res, err := a.allow(req.Client)
+	return res, func() {}, err
+}
+
+func (a *AllowerAdapter) CheckRequest(req limiter.Request) (limiter.Result, error) {
+	if c, ok := a.Allower.(Checker); ok {
+		return a.result(req.Client, c.Check)
+	}
+	return a.allow(req.Client)
+}
+
+func (a *AllowerAdapter) allow(client string) (limiter.Result, error) {
+	fa, ok := a.Allower.(FractionalAllower)
+	if !ok {
+		return a.result(client, a.Allower.Allow)
+	}
+
+	var remaining float64
+	res, err := a.result(client, func(client string) (bool, int, time.Time, error) {
+		allowed, r, resetAt, err := fa.AllowFloat(client)
+		remaining = r
+		return allowed, int(math.Floor(r)), resetAt, err
+	})
+	res.RemainingFloat = remaining
+	return res, err
+}
+
+func (a *AllowerAdapter) ConfigFor(client string) config.ClientConfig {
+	if a.Config == nil {
+		return config.DefaultConfig
+	}
+	return a.Config(client)
+}
+
+func (a *AllowerAdapter) result(client string, decide func(string) (bool, int, time.Time, error)) (limiter.Result, error) {
+	allowed, remaining, resetAt, err := decide(client)
+	res := limiter.Result{
+		Allowed:   allowed,
+		Limit:     a.ConfigFor(client).Limit,
+		Remaining: remaining,
+		ResetAt:   resetAt,
+	}
+	if !allowed {
+		res.Reason = limiter.ReasonRateLimit
+	}
+	return res, err
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// tokenBucket refills rate tokens per second up to capacity.
+type tokenBucket struct {
+	capacity float64
+	rate     float64
+	now      func() time.Time
+	tokens   map[string]float64
+	last     map[string]time.Time
+}
+
+func (b *tokenBucket) Allow(client string) (bool, int, time.Time, error) {
+	allowed, tokens, resetAt, err := b.AllowFloat(client)
+	return allowed, int(tokens), resetAt, err
+}
+
+func (b *tokenBucket) AllowFloat(client string) (bool, float64, time.Time, error) {
+	now := b.now()
+	tokens, ok := b.tokens[client]
+	if !ok {
+		tokens = b.capacity
+	} else {
+		tokens = min(b.capacity, tokens+now.Sub(b.last[client]).Seconds()*b.rate)
+	}
+	b.last[client] = now
+
+	if tokens < 1 {
+		b.tokens[client] = tokens
+		wait := time.Duration((1 - tokens) / b.rate * float64(time.Second))
+		return false, 0, now.Add(wait), nil
+	}
+	tokens--
+	b.tokens[client] = tokens
+	return true, tokens, now, nil
+}
+
+func TestAllowerAdapterTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := &tokenBucket{
+		capacity: 2,
+		rate:     1,
+		now:      func() time.Time { return now },
+		tokens:   map[string]float64{},
+		last:     map[string]time.Time{},
+	}
+	adapter := &AllowerAdapter{
+		Allower: bucket,
+		Config: func(string) config.ClientConfig {
+			return config.ClientConfig{Limit: 2, Window: time.Second}
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mw := NewRateLimitMiddleware(adapter, logger)
+
+	for i := 0; i < 2; i++ {
+		if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := doRequest(mw, "GET", "/test", "c1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the bucket is empty, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "2" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected headers %v", rec.Header())
+	}
+
+	if rec := doRequest(mw, "GET", "/test", "c2"); rec.Code != http.StatusOK {
+		t.Fatalf("expected other client unaffected, got %d", rec.Code)
+	}
+
+	now = now.Add(time.Second)
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected refilled token to admit request, got %d", rec.Code)
+	}
+}
+
+func TestAllowerAdapterDefaults(t *testing.T) {
+	adapter := &AllowerAdapter{Allower: &tokenBucket{capacity: 1, rate: 1, now: time.Now, tokens: map[string]float64{}, last: map[string]time.Time{}}}
+	if got := adapter.ConfigFor("any"); got != config.DefaultConfig {
+		t.Fatalf("expected default config, got %+v", got)
+	}
+
+	mw := NewRateLimitMiddleware(adapter, slog.New(slog.NewTextHandler(os.Stdout, nil)), WithCheckOnlyMethods("GET"))
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+	mw.Handler(func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestAllowerAdapterFractionalRemaining(t *testing.T) {
+	now := time.Now()
+	bucket := &tokenBucket{
+		capacity: 3,
+		rate:     1,
+		now:      func() time.Time { return now },
+		tokens:   map[string]float64{},
+		last:     map[string]time.Time{},
+	}
+	adapter := &AllowerAdapter{Allower: bucket}
+	mw := NewRateLimitMiddleware(adapter, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	doRequest(mw, "GET", "/test", "c1")
+	now = now.Add(500 * time.Millisecond)
+
+	res, _, err := adapter.AcquireRequest(limiter.Request{Client: "c2"})
+	if err != nil || res.RemainingFloat != 2 || res.Remaining != 2 {
+		t.Fatalf("expected 2 remaining for a fresh bucket, got %+v err=%v", res, err)
+	}
+
+	// 2 tokens plus half a second of refill, minus this request.
+	rec := doRequest(mw, "GET", "/test", "c1")
+	if got := bucket.tokens["c1"]; got != 1.5 {
+		t.Fatalf("expected 1.5 tokens left, got %v", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "1" {
+		t.Fatalf("expected floored remaining header 1, got %s", got)
+	}
+
+	res, _, _ = adapter.AcquireRequest(limiter.Request{Client: "c1"})
+	if res.RemainingFloat != 0.5 || res.Remaining != 0 {
+		t.Fatalf("expected 0.5 remaining floored to 0, got %+v", res)
+	}
+}
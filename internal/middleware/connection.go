@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// connectionScope keeps connection counters apart from request counters when
+// both limiters share a store.
+const connectionScope = "connections"
+
+// WithConnectionLimiter limits how often clients may open streaming
+// connections (WebSocket, SSE) with l, which has its own per-client configs.
+// Handlers enforce it by calling AllowConnection before upgrading; ordinary
+// requests are still limited only by the middleware's main limiter.
+func WithConnectionLimiter(l Limiter) Option {
+	return func(m *RateLimitMiddleware) {
+		m.connLimiter = l
+	}
+}
+
+// AllowConnection decides whether the client may open a new streaming
+// connection. When denied it has already written the 429 (or 503 while
+// draining) and the handler must return without upgrading. Otherwise the
+// caller must call release once the connection closes, which frees the slot
+// when the connection config sets MaxConcurrent. Without
+// WithConnectionLimiter every connection is allowed.
+func (m *RateLimitMiddleware) AllowConnection(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	noop := func() {}
+	if m.draining.Load() {
+		m.sendDraining(w)
+		return noop, false
+	}
+	if m.connLimiter == nil || m.hasBypassToken(r) {
+		return noop, true
+	}
+
+	logger := m.requestLogger(w, r)
+	clientID := m.getClientID(r)
+
+	res, release, err := m.connLimiter.AcquireRequest(limiter.Request{
+		Client: clientID,
+		Scope:  connectionScope,
+		Class:  m.getUserAgentClass(r),
+	})
+	if err != nil {
+		release()
+		logger.Error("connection limiter error", "error", err, "client", clientID)
+		m.onError(w, r, err)
+		return noop, false
+	}
+
+	setRateLimitHeaders(w, res)
+
+	if !res.Allowed {
+		release()
+		logger.Warn("connection rate limit exceeded",
+			"client", clientID,
+			"reason", res.Reason,
+			"count", res.Count,
+			"path", r.URL.Path,
+		)
+		m.sendRateLimitError(w, res, clientID, retryMessage(m.connLimiter, clientID))
+		return noop, false
+	}
+
+	logger.Log(r.Context(), m.allowedLevel, "connection allowed",
+		"client", clientID,
+		"count", res.Count,
+		"remaining", res.Remaining,
+		"path", r.URL.Path,
+	)
+	return release, true
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func newConnectionTestMiddleware(reqCfgs, connCfgs map[string]config.ClientConfig) *RateLimitMiddleware {
+	store := memory.NewMemoryStore()
+	l := limiter.NewLimiter(store, reqCfgs)
+	conn := limiter.NewLimiter(store, connCfgs)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return NewRateLimitMiddleware(l, logger, WithConnectionLimiter(conn))
+}
+
+// streamHandler stands in for a WebSocket or SSE endpoint: it only writes
+// 101 when the connection was allowed.
+func streamHandler(mw *RateLimitMiddleware) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		release, ok := mw.AllowConnection(w, r)
+		if !ok {
+			return
+		}
+		defer release()
+		w.WriteHeader(http.StatusSwitchingProtocols)
+	}
+}
+
+func openConnection(h http.HandlerFunc, clientID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("X-Client-ID", clientID)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func TestAllowConnection_RateLimit(t *testing.T) {
+	mw := newConnectionTestMiddleware(
+		map[string]config.ClientConfig{"c1": {Limit: 10, Window: time.Minute}},
+		map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}},
+	)
+	h := streamHandler(mw)
+
+	for i := 0; i < 2; i++ {
+		if rec := openConnection(h, "c1"); rec.Code != http.StatusSwitchingProtocols {
+			t.Fatalf("connection %d: expected 101, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := openConnection(h, "c1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 before upgrade, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Errorf("expected connection limit header 2, got %q", rec.Header().Get("X-RateLimit-Limit"))
+	}
+
+	for i := 0; i < 10; i++ {
+		if rec := doRequest(mw, "GET", "/api/hello", "c1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected regular requests unaffected, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := doRequest(mw, "GET", "/api/hello", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected request limit still enforced, got %d", rec.Code)
+	}
+	if rec := openConnection(h, "c2"); rec.Code != http.StatusSwitchingProtocols {
+		t.Fatalf("expected other clients unaffected, got %d", rec.Code)
+	}
+}
+
+func TestAllowConnection_MaxConcurrent(t *testing.T) {
+	mw := newConnectionTestMiddleware(nil,
+		map[string]config.ClientConfig{"c1": {Limit: 10, Window: time.Minute, MaxConcurrent: 1}},
+	)
+
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("X-Client-ID", "c1")
+	release, ok := mw.AllowConnection(httptest.NewRecorder(), req)
+	if !ok {
+		t.Fatal("expected first connection allowed")
+	}
+
+	if _, ok := mw.AllowConnection(httptest.NewRecorder(), req); ok {
+		t.Fatal("expected second open connection denied")
+	}
+
+	release()
+	if _, ok := mw.AllowConnection(httptest.NewRecorder(), req); !ok {
+		t.Fatal("expected connection allowed after release")
+	}
+}
+
+func TestAllowConnection_Disabled(t *testing.T) {
+	mw := newTestMiddleware(map[string]config.ClientConfig{"c1": {Limit: 0, Window: time.Minute}})
+	if rec := openConnection(streamHandler(mw), "c1"); rec.Code != http.StatusSwitchingProtocols {
+		t.Fatalf("expected connections unlimited without a connection limiter, got %d", rec.Code)
+	}
+}
+
+func TestAllowConnection_Draining(t *testing.T) {
+	mw := newConnectionTestMiddleware(nil, nil)
+	mw.Drain(time.Second)
+	if rec := openConnection(streamHandler(mw), "c1"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while draining, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+const defaultMaxBodyPeek = 1 << 20
+
+// WithBodyCost charges one unit per unitBytes of request body (rounded up,
+// minimum one unit) instead of a flat single unit per request.
+func WithBodyCost(unitBytes int64) Option {
+	return func(m *RateLimitMiddleware) {
+		m.bodyCostUnit = unitBytes
+	}
+}
+
+// WithMaxBodyPeek caps how many body bytes are read to size a request whose
+// Content-Length is unknown. Larger bodies are charged the maximum cost.
+func WithMaxBodyPeek(n int64) Option {
+	return func(m *RateLimitMiddleware) {
+		m.maxBodyPeek = n
+	}
+}
+
+func (m *RateLimitMiddleware) requestCost(r *http.Request) int64 {
+	if m.bodyCostUnit <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return 1
+	}
+
+	size := r.ContentLength
+	if size < 0 {
+		size = m.peekBodySize(r)
+	}
+	if size > m.maxBodyPeek {
+		size = m.maxBodyPeek
+	}
+
+	cost := (size + m.bodyCostUnit - 1) / m.bodyCostUnit
+	if cost < 1 {
+		cost = 1
+	}
+	return cost
+}
+
+// peekBodySize reads at most maxBodyPeek+1 bytes of the body and puts them
+// back in front of the unread remainder so the next handler sees the full body.
+func (m *RateLimitMiddleware) peekBodySize(r *http.Request) int64 {
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, m.maxBodyPeek+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), r.Body), r.Body}
+
+	if err != nil {
+		return m.maxBodyPeek
+	}
+	return int64(len(peeked))
+}
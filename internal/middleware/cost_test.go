@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestRequestCost(t *testing.T) {
+	mw := newTestMiddleware(nil, WithBodyCost(10), WithMaxBodyPeek(50))
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		wantCost      int64
+	}{
+		{"chunked body under peek limit", strings.Repeat("a", 25), -1, 3},
+		{"chunked body over peek limit", strings.Repeat("a", 500), -1, 5},
+		{"known length under limit", strings.Repeat("a", 10), 10, 1},
+		{"known length over limit", strings.Repeat("a", 500), 500, 5},
+		{"empty body", "", 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/upload", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+
+			if cost := mw.requestCost(req); cost != tt.wantCost {
+				t.Errorf("expected cost %d, got %d", tt.wantCost, cost)
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("expected downstream body of %d bytes, got %d", len(tt.body), len(body))
+			}
+		})
+	}
+}
+
+func TestRequestCostDisabled(t *testing.T) {
+	mw := newTestMiddleware(nil)
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(strings.Repeat("a", 100)))
+
+	if cost := mw.requestCost(req); cost != 1 {
+		t.Errorf("expected flat cost 1, got %d", cost)
+	}
+}
+
+func TestHandlerBodyCost(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 10, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs, WithBodyCost(10), WithMaxBodyPeek(50))
+
+	var received string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	body := strings.Repeat("x", 200)
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(body))
+	req.ContentLength = -1
+	req.Header.Set("X-Client-ID", "c1")
+	rec := httptest.NewRecorder()
+
+	mw.Handler(handler)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if received != body {
+		t.Errorf("expected handler to receive full body, got %d bytes", len(received))
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "5" {
+		t.Errorf("expected remaining 5 after max cost, got %s", got)
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// WithCountFailuresOnly switches to counting only requests whose response
+// status is in statuses, e.g. 401 for login brute-force protection. Requests
+// are checked against the limit up front and counted after next returns, so
+// successful requests never consume quota. Concurrency caps are not applied
+// in this mode.
+func WithCountFailuresOnly(statuses ...int) Option {
+	return func(m *RateLimitMiddleware) {
+		m.failureStatuses = make(map[int]bool, len(statuses))
+		for _, status := range statuses {
+			m.failureStatuses[status] = true
+		}
+	}
+}
+
+func (m *RateLimitMiddleware) serveCountingFailures(w http.ResponseWriter, r *http.Request, logger *slog.Logger, clientID, group string, next http.HandlerFunc) {
+	req := m.limiterRequest(r, clientID, group)
+	req.Cost = m.requestCost(r)
+
+	sw := &statusWriter{ResponseWriter: w}
+	next(sw, r)
+
+	if !m.failureStatuses[sw.status()] {
+		return
+	}
+
+	res, release, err := m.limiter.AcquireRequest(req)
+	release()
+	if err != nil {
+		logger.Error("rate limiter error", "error", err, "client", clientID)
+		return
+	}
+	logger.Log(r.Context(), m.allowedLevel, "failed request counted",
+		"client", clientID,
+		"group", group,
+		"status", sw.status(),
+		"count", res.Count,
+		"remaining", res.Remaining,
+	)
+}
+
+// statusWriter records the status code written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestWithCountFailuresOnly(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Minute}}
+	login := func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Password") != "hunter2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("welcome"))
+	}
+	attempt := func(mw *RateLimitMiddleware, password string) int {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.Header.Set("X-Client-ID", "c1")
+		req.Header.Set("X-Password", password)
+		rec := httptest.NewRecorder()
+		mw.Handler(login)(rec, req)
+		return rec.Code
+	}
+
+	t.Run("successful logins are not counted", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithCountFailuresOnly(http.StatusUnauthorized))
+		for i := 0; i < 10; i++ {
+			if code := attempt(mw, "hunter2"); code != http.StatusOK {
+				t.Fatalf("login %d: expected 200, got %d", i+1, code)
+			}
+		}
+		if res, _ := mw.limiter.CheckRequest(mw.limiterRequest(httptest.NewRequest("POST", "/login", nil), "c1", "")); res.Count != 0 {
+			t.Fatalf("expected no quota used, got count %d", res.Count)
+		}
+	})
+	t.Run("failed logins lock out the client", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithCountFailuresOnly(http.StatusUnauthorized))
+		attempt(mw, "hunter2")
+		for i := 0; i < 3; i++ {
+			if code := attempt(mw, "wrong"); code != http.StatusUnauthorized {
+				t.Fatalf("attempt %d: expected 401, got %d", i+1, code)
+			}
+		}
+		if code := attempt(mw, "hunter2"); code != http.StatusTooManyRequests {
+			t.Fatalf("expected lockout after failed attempts, got %d", code)
+		}
+	})
+}
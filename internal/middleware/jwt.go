@@ -0,0 +1,379 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnonymousClient is the client ID of requests without a valid JWT when
+// WithJWT is used, so unauthenticated traffic shares one budget. Configure it
+// like any other client to set the anonymous limit.
+const AnonymousClient = "anonymous"
+
+// jwtCacheMax bounds the verified token cache; expired entries are swept
+// when it fills, and the cache is dropped if that frees nothing.
+const jwtCacheMax = 10000
+
+// jwtFailureTTL is how long a token that failed verification is remembered,
+// so a bad token costs one check rather than several per request.
+const jwtFailureTTL = 5 * time.Second
+
+var (
+	ErrJWTMalformed   = errors.New("jwt: malformed token")
+	ErrJWTAlgorithm   = errors.New("jwt: unsupported algorithm")
+	ErrJWTSignature   = errors.New("jwt: invalid signature")
+	ErrJWTExpired     = errors.New("jwt: token expired or not yet valid")
+	ErrJWTMissingSub  = errors.New("jwt: missing sub claim")
+	ErrJWTKeyNotFound = errors.New("jwt: no key for token")
+)
+
+// JWTKeyFunc returns the verification key for a token's kid header (empty
+// when absent): a []byte secret for HS256/384/512 or an *rsa.PublicKey for
+// RS256/384/512.
+type JWTKeyFunc func(kid string) (any, error)
+
+// StaticJWTKey verifies every token with key, ignoring kid.
+func StaticJWTKey(key any) JWTKeyFunc {
+	return func(string) (any, error) {
+		return key, nil
+	}
+}
+
+// JWTIdentity is what the limiter takes from a verified token.
+type JWTIdentity struct {
+	Subject string
+	Tier    string
+}
+
+// JWTVerifier verifies bearer tokens and derives the client ID from the sub
+// claim and the tier from tierClaim. Verified tokens are cached for up to
+// cacheTTL (never past their exp), so each token is checked once rather than
+// on every request; failures are cached for jwtFailureTTL. Only the signature, exp and nbf are checked; issuer and
+// audience are left to the auth layer.
+type JWTVerifier struct {
+	keys      JWTKeyFunc
+	tierClaim string
+	cacheTTL  time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedIdentity
+}
+
+type cachedIdentity struct {
+	id      JWTIdentity
+	err     error
+	expires time.Time
+}
+
+func NewJWTVerifier(keys JWTKeyFunc, tierClaim string, cacheTTL time.Duration) *JWTVerifier {
+	return &JWTVerifier{
+		keys:      keys,
+		tierClaim: tierClaim,
+		cacheTTL:  cacheTTL,
+		now:       time.Now,
+		cache:     make(map[string]cachedIdentity),
+	}
+}
+
+// WithJWT limits requests under the sub claim of their bearer token and uses
+// its tier claim as the request class, selecting the limiter's per-class
+// defaults (see limiter.WithClassDefaults). Requests with a missing or
+// invalid token are limited as AnonymousClient, classified by user agent if
+// WithUserAgentClasses is set.
+func WithJWT(v *JWTVerifier) Option {
+	return func(m *RateLimitMiddleware) {
+		m.jwt = v
+		m.keyExtractor = v.KeyExtractor()
+	}
+}
+
+// requestClass is the JWT tier, falling back to the user agent class.
+func (m *RateLimitMiddleware) requestClass(r *http.Request) string {
+	if m.jwt != nil {
+		if id, ok := m.jwt.identity(r); ok && id.Tier != "" {
+			return id.Tier
+		}
+	}
+	return m.getUserAgentClass(r)
+}
+
+// KeyExtractor returns the request's sub claim, or AnonymousClient.
+func (v *JWTVerifier) KeyExtractor() KeyExtractor {
+	return func(r *http.Request) string {
+		if id, ok := v.identity(r); ok {
+			return id.Subject
+		}
+		return AnonymousClient
+	}
+}
+
+func (v *JWTVerifier) identity(r *http.Request) (JWTIdentity, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return JWTIdentity{}, false
+	}
+	id, err := v.Verify(strings.TrimSpace(auth[7:]))
+	return id, err == nil
+}
+
+// Verify checks token and returns its identity, using the cache when the
+// token was verified recently.
+func (v *JWTVerifier) Verify(token string) (JWTIdentity, error) {
+	now := v.now()
+
+	v.mu.Lock()
+	c, ok := v.cache[token]
+	v.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.id, c.err
+	}
+
+	id, exp, err := v.verify(token, now)
+	if err != nil {
+		v.remember(token, cachedIdentity{err: err, expires: now.Add(min(jwtFailureTTL, v.cacheTTL))}, now)
+		return JWTIdentity{}, err
+	}
+
+	expires := now.Add(v.cacheTTL)
+	if !exp.IsZero() && exp.Before(expires) {
+		expires = exp
+	}
+	v.remember(token, cachedIdentity{id: id, expires: expires}, now)
+	return id, nil
+}
+
+func (v *JWTVerifier) remember(token string, c cachedIdentity, now time.Time) {
+	v.mu.Lock()
+	if len(v.cache) >= jwtCacheMax {
+		for k, e := range v.cache {
+			if !now.Before(e.expires) {
+				delete(v.cache, k)
+			}
+		}
+		if len(v.cache) >= jwtCacheMax {
+			v.cache = make(map[string]cachedIdentity)
+		}
+	}
+	v.cache[token] = c
+	v.mu.Unlock()
+}
+
+func (v *JWTVerifier) verify(token string, now time.Time) (JWTIdentity, time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return JWTIdentity{}, time.Time{}, ErrJWTMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return JWTIdentity{}, time.Time{}, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return JWTIdentity{}, time.Time{}, ErrJWTMalformed
+	}
+	key, err := v.keys(header.Kid)
+	if err != nil {
+		return JWTIdentity{}, time.Time{}, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return JWTIdentity{}, time.Time{}, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return JWTIdentity{}, time.Time{}, err
+	}
+	var exp time.Time
+	if n, ok := claims["exp"].(float64); ok {
+		exp = time.Unix(int64(n), 0)
+		if !now.Before(exp) {
+			return JWTIdentity{}, time.Time{}, ErrJWTExpired
+		}
+	}
+	if n, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(n), 0)) {
+		return JWTIdentity{}, time.Time{}, ErrJWTExpired
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return JWTIdentity{}, time.Time{}, ErrJWTMissingSub
+	}
+	tier, _ := claims[v.tierClaim].(string)
+	return JWTIdentity{Subject: sub, Tier: tier}, exp, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrJWTMalformed
+	}
+	return nil
+}
+
+func verifySignature(alg string, key any, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "HS256", "RS256":
+		hash = crypto.SHA256
+	case "HS384", "RS384":
+		hash = crypto.SHA384
+	case "HS512", "RS512":
+		hash = crypto.SHA512
+	default:
+		return ErrJWTAlgorithm
+	}
+
+	switch k := key.(type) {
+	case []byte:
+		if alg[0] != 'H' {
+			return ErrJWTAlgorithm
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrJWTSignature
+		}
+		return nil
+	case *rsa.PublicKey:
+		if alg[0] != 'R' {
+			return ErrJWTAlgorithm
+		}
+		h := hash.New()
+		h.Write([]byte(signed))
+		if rsa.VerifyPKCS1v15(k, hash, h.Sum(nil), sig) != nil {
+			return ErrJWTSignature
+		}
+		return nil
+	default:
+		return fmt.Errorf("jwt: unsupported key type %T", key)
+	}
+}
+
+// JWKSKeys fetches RSA keys from a JWKS endpoint, refreshing them after
+// refresh. An unknown kid triggers an early refresh, at most once per
+// refresh/10, so key rotation is picked up without waiting. On fetch errors
+// the previous keys stay in use, and a failing endpoint is retried at most
+// once per refresh/10 as well.
+func JWKSKeys(url string, client *http.Client, refresh time.Duration) JWTKeyFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	s := &jwksSet{url: url, client: client, refresh: refresh, now: time.Now}
+	return s.key
+}
+
+type jwksSet struct {
+	url     string
+	client  *http.Client
+	refresh time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	keys     map[string]*rsa.PublicKey
+	err      error // of the last fetch, returned while there are no keys
+	fetched  time.Time
+	fetching chan struct{} // closed when the fetch in flight is done
+}
+
+func (s *jwksSet) key(kid string) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[kid]
+	age := s.now().Sub(s.fetched)
+	switch {
+	case s.fetching == nil && (age >= s.refresh || (!ok && age >= s.refresh/10)):
+		s.refreshLocked()
+		key, ok = s.keys[kid]
+	case s.fetching != nil && !ok:
+		s.waitLocked()
+		key, ok = s.keys[kid]
+	}
+	if ok {
+		return key, nil
+	}
+	if s.keys == nil && s.err != nil {
+		return nil, s.err
+	}
+	return nil, ErrJWTKeyNotFound
+}
+
+// refreshLocked fetches the key set with s.mu released, so requests whose
+// kid is already known are not held up by a slow endpoint. The fetch time is
+// recorded up front, even if the fetch fails.
+func (s *jwksSet) refreshLocked() {
+	done := make(chan struct{})
+	s.fetching, s.fetched = done, s.now()
+	s.mu.Unlock()
+	keys, err := s.fetch()
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+	}
+	s.err = err
+	s.fetching = nil
+	close(done)
+}
+
+// waitLocked waits for the fetch in flight with s.mu released.
+func (s *jwksSet) waitLocked() {
+	done := s.fetching
+	s.mu.Unlock()
+	<-done
+	s.mu.Lock()
+}
+
+func (s *jwksSet) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+var testJWTSecret = []byte("test-secret")
+
+func signHS256(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	signed := jwtSegment(t, map[string]any{"alg": "HS256", "typ": "JWT"}) + "." + jwtSegment(t, claims)
+	mac := hmac.New(sha256.New, testJWTSecret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	signed := jwtSegment(t, map[string]any{"alg": "RS256", "kid": kid}) + "." + jwtSegment(t, claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func jwtSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func bearerRequest(token string) *http.Request {
+	req := httptest.NewRequest("GET", "/test", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestJWTKeyExtractor(t *testing.T) {
+	v := NewJWTVerifier(StaticJWTKey(testJWTSecret), "tier", time.Minute)
+	mw := newTestMiddleware(nil, WithJWT(v))
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tampered := signHS256(t, map[string]any{"sub": "user-1", "exp": exp})
+	tampered = tampered[:len(tampered)-2] + "AA"
+
+	tests := []struct {
+		name       string
+		token      string
+		wantClient string
+		wantClass  string
+	}{
+		{"valid", signHS256(t, map[string]any{"sub": "user-1", "tier": "pro", "exp": exp}), "user-1", "pro"},
+		{"valid without tier", signHS256(t, map[string]any{"sub": "user-2", "exp": exp}), "user-2", ""},
+		{"expired", signHS256(t, map[string]any{"sub": "user-1", "tier": "pro", "exp": time.Now().Add(-time.Minute).Unix()}), AnonymousClient, ""},
+		{"not yet valid", signHS256(t, map[string]any{"sub": "user-1", "nbf": exp}), AnonymousClient, ""},
+		{"missing sub", signHS256(t, map[string]any{"tier": "pro", "exp": exp}), AnonymousClient, ""},
+		{"bad signature", tampered, AnonymousClient, ""},
+		{"malformed", "not-a-jwt", AnonymousClient, ""},
+		{"missing", "", AnonymousClient, ""},
+	}
+	for _, tt := range tests {
+		req := bearerRequest(tt.token)
+		if got := mw.getClientID(req); got != tt.wantClient {
+			t.Errorf("%s: client = %q, want %q", tt.name, got, tt.wantClient)
+		}
+		if got := mw.limiterRequest(req, mw.getClientID(req), "").Class; got != tt.wantClass {
+			t.Errorf("%s: class = %q, want %q", tt.name, got, tt.wantClass)
+		}
+	}
+}
+
+func TestJWTRejectsAlgorithmMismatch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An HS256 token must not verify against an RSA public key.
+	v := NewJWTVerifier(StaticJWTKey(&key.PublicKey), "tier", time.Minute)
+	if _, err := v.Verify(signHS256(t, map[string]any{"sub": "user-1"})); err == nil {
+		t.Fatal("expected HS256 token rejected for an RSA key")
+	}
+	if id, err := v.Verify(signRS256(t, key, "", map[string]any{"sub": "user-1"})); err != nil || id.Subject != "user-1" {
+		t.Fatalf("expected RS256 token verified, got %+v, %v", id, err)
+	}
+}
+
+func TestJWTCache(t *testing.T) {
+	lookups := 0
+	keys := func(string) (any, error) {
+		lookups++
+		return testJWTSecret, nil
+	}
+	now := time.Unix(1_700_000_000, 0)
+	v := NewJWTVerifier(keys, "tier", time.Minute)
+	v.now = func() time.Time { return now }
+
+	short := signHS256(t, map[string]any{"sub": "user-1", "exp": now.Add(10 * time.Second).Unix()})
+	long := signHS256(t, map[string]any{"sub": "user-2", "exp": now.Add(time.Hour).Unix()})
+	for i := 0; i < 3; i++ {
+		v.Verify(short)
+		v.Verify(long)
+	}
+	if lookups != 2 {
+		t.Fatalf("expected each token verified once, got %d lookups", lookups)
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, err := v.Verify(short); err != ErrJWTExpired {
+		t.Fatalf("expected cached token to expire with its exp, got %v", err)
+	}
+	if _, err := v.Verify(long); err != nil || lookups != 3 {
+		t.Fatalf("expected long token still cached, got %v after %d lookups", err, lookups)
+	}
+
+	now = now.Add(time.Minute)
+	v.Verify(long)
+	if lookups != 4 {
+		t.Fatalf("expected re-verification after the cache TTL, got %d lookups", lookups)
+	}
+}
+
+func TestJWKSKeys(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	published := map[string]*rsa.PrivateKey{"k1": oldKey}
+	fetches := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		var keys []map[string]string
+		for kid, k := range published {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer srv.Close()
+
+	v := NewJWTVerifier(JWKSKeys(srv.URL, srv.Client(), time.Hour), "tier", time.Minute)
+
+	if id, err := v.Verify(signRS256(t, oldKey, "k1", map[string]any{"sub": "user-1", "tier": "free"})); err != nil || id.Tier != "free" {
+		t.Fatalf("expected token verified from JWKS, got %+v, %v", id, err)
+	}
+	v.Verify(signRS256(t, oldKey, "k1", map[string]any{"sub": "user-2"}))
+	if fetches != 1 {
+		t.Fatalf("expected keys reused, got %d fetches", fetches)
+	}
+
+	// A rotated key is unknown until the next fetch, which waits out
+	// refresh/10 since the last one.
+	published["k2"] = newKey
+	rotated := signRS256(t, newKey, "k2", map[string]any{"sub": "user-3"})
+	if _, err := v.Verify(rotated); err != ErrJWTKeyNotFound || fetches != 1 {
+		t.Fatalf("expected unknown kid without an early refetch, got %v after %d fetches", err, fetches)
+	}
+}
+
+func TestJWKSKeysEndpointDown(t *testing.T) {
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1_700_000_000, 0)
+	s := &jwksSet{url: srv.URL, client: srv.Client(), refresh: time.Hour, now: func() time.Time { return now }}
+	for i := 0; i < 5; i++ {
+		if _, err := s.key("k1"); err == nil || err == ErrJWTKeyNotFound {
+			t.Fatalf("expected the fetch error without keys, got %v", err)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("expected a down endpoint fetched once, got %d fetches", fetches)
+	}
+
+	now = now.Add(6 * time.Minute)
+	s.key("k1")
+	if fetches != 2 {
+		t.Fatalf("expected a retry after refresh/10, got %d fetches", fetches)
+	}
+}
+
+func TestJWTCachesFailures(t *testing.T) {
+	lookups := 0
+	keys := func(string) (any, error) {
+		lookups++
+		return []byte("other-secret"), nil
+	}
+	now := time.Unix(1_700_000_000, 0)
+	v := NewJWTVerifier(keys, "tier", time.Minute)
+	v.now = func() time.Time { return now }
+
+	token := signHS256(t, map[string]any{"sub": "user-1"})
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(token); err != ErrJWTSignature {
+			t.Fatalf("expected ErrJWTSignature, got %v", err)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("expected the failure cached, got %d lookups", lookups)
+	}
+
+	now = now.Add(jwtFailureTTL)
+	v.Verify(token)
+	if lookups != 2 {
+		t.Fatalf("expected re-verification once the failure expires, got %d lookups", lookups)
+	}
+}
+
+func TestHandlerJWTTiers(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore(),
+		limiter.WithDefault(config.ClientConfig{Limit: 2, Window: time.Minute}),
+		limiter.WithClassDefaults(map[string]config.ClientConfig{
+			"pro": {Limit: 5, Window: time.Minute},
+		}),
+		limiter.WithConfigs(map[string]config.ClientConfig{
+			AnonymousClient: {Limit: 1, Window: time.Minute},
+		}),
+	)
+	v := NewJWTVerifier(StaticJWTKey(testJWTSecret), "tier", time.Minute)
+	mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(os.Stdout, nil)), WithJWT(v))
+
+	send := func(token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mw.Handler(func(w http.ResponseWriter, r *http.Request) {})(rec, bearerRequest(token))
+		return rec
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	pro := signHS256(t, map[string]any{"sub": "user-1", "tier": "pro", "exp": exp})
+	if rec := send(pro); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "5" {
+		t.Fatalf("expected pro tier limit, got %d limit %s", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	free := signHS256(t, map[string]any{"sub": "user-2", "exp": exp})
+	if rec := send(free); rec.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Fatalf("expected default limit without a tier, got %s", rec.Header().Get("X-RateLimit-Limit"))
+	}
+
+	expired := signHS256(t, map[string]any{"sub": "user-1", "tier": "pro", "exp": time.Now().Add(-time.Minute).Unix()})
+	if rec := send(expired); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("expected anonymous limit for an expired token, got %d limit %s", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	if rec := send(""); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected anonymous requests to share one budget, got %d", rec.Code)
+	}
+}
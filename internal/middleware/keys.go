@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// KeyExtractor derives the client ID a request is limited under.
+type KeyExtractor func(r *http.Request) string
+
+// WithKeyExtractor replaces the default X-Client-ID header extractor.
+func WithKeyExtractor(fn KeyExtractor) Option {
+	return func(m *RateLimitMiddleware) {
+		if fn != nil {
+			m.keyExtractor = fn
+		}
+	}
+}
+
+// WithCaseInsensitiveKeys lowercases extracted client IDs so "Client-1" and
+// "client-1" share a budget. Per-client configs must then use lowercase IDs.
+func WithCaseInsensitiveKeys() Option {
+	return func(m *RateLimitMiddleware) {
+		m.foldKeyCase = true
+	}
+}
+
+// HeaderKeyExtractor reads the client ID from X-Client-ID with surrounding
+// whitespace removed, defaulting to "default" when nothing is left. It is the
+// extractor used when none is configured.
+func HeaderKeyExtractor(r *http.Request) string {
+	clientID := strings.TrimSpace(r.Header.Get("X-Client-ID"))
+	if clientID == "" {
+		clientID = "default"
+	}
+	return clientID
+}
+
+// remoteIP is the host part of r.RemoteAddr. Proxy headers are not trusted;
+// behind a proxy, rewrite RemoteAddr before this middleware.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ClientIPKeyExtractor keys requests by client IP, so anonymous callers get a
+// budget each instead of sharing "default". Requests arriving from one of
+// trustedProxies are keyed by the right-most X-Forwarded-For address that is
+// not itself a trusted proxy; the header is ignored from anyone else, since
+// clients can set it freely. With no trusted proxies only RemoteAddr is used.
+func ClientIPKeyExtractor(trustedProxies []*net.IPNet) KeyExtractor {
+	trusted := func(ip string) bool {
+		parsed := net.ParseIP(ip)
+		for _, n := range trustedProxies {
+			if parsed != nil && n.Contains(parsed) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(r *http.Request) string {
+		ip := remoteIP(r)
+		if !trusted(ip) {
+			return ip
+		}
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if !trusted(hop) {
+				return hop
+			}
+			ip = hop
+		}
+		return ip
+	}
+}
+
+type CertIdentity int
+
+const (
+	CertCommonName CertIdentity = iota
+	CertDNSName
+	CertEmailAddress
+	CertURI
+)
+
+// TLSClientCertKeyExtractor derives the client ID from the peer certificate of
+// an mTLS connection, using the subject CN or the first SAN of the given kind.
+// Verifying the certificate is left to the server's tls.Config. Requests
+// without a client certificate, or whose certificate lacks the field, use
+// fallback (HeaderKeyExtractor when nil).
+func TLSClientCertKeyExtractor(identity CertIdentity, fallback KeyExtractor) KeyExtractor {
+	if fallback == nil {
+		fallback = HeaderKeyExtractor
+	}
+	return func(r *http.Request) string {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return fallback(r)
+		}
+		cert := r.TLS.PeerCertificates[0]
+
+		var id string
+		switch identity {
+		case CertCommonName:
+			id = cert.Subject.CommonName
+		case CertDNSName:
+			if len(cert.DNSNames) > 0 {
+				id = cert.DNSNames[0]
+			}
+		case CertEmailAddress:
+			if len(cert.EmailAddresses) > 0 {
+				id = cert.EmailAddresses[0]
+			}
+		case CertURI:
+			if len(cert.URIs) > 0 {
+				id = cert.URIs[0].String()
+			}
+		}
+
+		if id == "" {
+			return fallback(r)
+		}
+		return id
+	}
+}
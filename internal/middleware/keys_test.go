@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func newClientCert(t *testing.T, cn string, dnsNames ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// serveTLS runs mw behind an mTLS test server that echoes the limited client ID.
+func serveTLS(t *testing.T, mw *RateLimitMiddleware, cert *tls.Certificate) *http.Response {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(mw.Handler(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, mw.getClientID(r))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	if cert != nil {
+		client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{*cert}
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("X-Client-ID", "from-header")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestTLSClientCertKeyExtractor(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"svc-a": {Limit: 1, Window: time.Minute}}
+	cert := newClientCert(t, "svc-a", "svc-a.internal")
+
+	t.Run("common name", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithKeyExtractor(TLSClientCertKeyExtractor(CertCommonName, nil)))
+
+		resp := serveTLS(t, mw, &cert)
+		if got := readBody(t, resp); got != "svc-a" {
+			t.Fatalf("expected client ID from CN, got %q", got)
+		}
+		if resp := serveTLS(t, mw, &cert); resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("expected cert identity to be limited, got %d", resp.StatusCode)
+		}
+	})
+	t.Run("dns san", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithKeyExtractor(TLSClientCertKeyExtractor(CertDNSName, nil)))
+
+		if got := readBody(t, serveTLS(t, mw, &cert)); got != "svc-a.internal" {
+			t.Fatalf("expected client ID from SAN, got %q", got)
+		}
+	})
+	t.Run("missing san falls back", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithKeyExtractor(TLSClientCertKeyExtractor(CertURI, nil)))
+
+		if got := readBody(t, serveTLS(t, mw, &cert)); got != "from-header" {
+			t.Fatalf("expected fallback to header, got %q", got)
+		}
+	})
+	t.Run("no client cert falls back", func(t *testing.T) {
+		fallback := func(r *http.Request) string { return "anonymous" }
+		mw := newTestMiddleware(cfgs, WithKeyExtractor(TLSClientCertKeyExtractor(CertCommonName, fallback)))
+
+		if got := readBody(t, serveTLS(t, mw, nil)); got != "anonymous" {
+			t.Fatalf("expected custom fallback, got %q", got)
+		}
+	})
+	t.Run("plain http uses fallback", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithKeyExtractor(TLSClientCertKeyExtractor(CertCommonName, nil)))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Client-ID", "c1")
+
+		if got := mw.getClientID(req); got != "c1" {
+			t.Fatalf("expected header client ID, got %q", got)
+		}
+	})
+}
+
+func TestHeaderKeyExtractorWhitespace(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"whitespace only", "   ", "default"},
+		{"tabs only", "\t \t", "default"},
+		{"surrounding whitespace", "  client-1 ", "client-1"},
+		{"inner whitespace kept", "client 1", "client 1"},
+		{"mixed case kept", "Client-1", "Client-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("X-Client-ID", tt.header)
+			if got := HeaderKeyExtractor(req); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClientIDBucketing(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{
+		"client-1": {Limit: 3, Window: time.Minute},
+		"default":  {Limit: 2, Window: time.Minute},
+	}
+
+	t.Run("case insensitive", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithCaseInsensitiveKeys())
+		for i, id := range []string{"client-1", " CLIENT-1", "Client-1\t"} {
+			rec := doRequest(mw, "GET", "/test", id)
+			if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "3" {
+				t.Fatalf("request %d (%q): expected client-1's budget, got %d limit %s", i+1, id, rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+			}
+		}
+		if rec := doRequest(mw, "GET", "/test", "cLiEnT-1"); rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected all casings to share one bucket, got %d", rec.Code)
+		}
+	})
+
+	t.Run("case sensitive by default", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs)
+		doRequest(mw, "GET", "/test", " client-1 ")
+		rec := doRequest(mw, "GET", "/test", "CLIENT-1")
+		if rec.Header().Get("X-RateLimit-Limit") != "100" {
+			t.Fatalf("expected other casing to fall back to the default config, got limit %s", rec.Header().Get("X-RateLimit-Limit"))
+		}
+		if rec := doRequest(mw, "GET", "/test", "client-1"); rec.Header().Get("X-RateLimit-Remaining") != "1" {
+			t.Fatalf("expected trimmed ID to share client-1's bucket, got remaining %s", rec.Header().Get("X-RateLimit-Remaining"))
+		}
+	})
+
+	t.Run("whitespace falls back to default", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs)
+		doRequest(mw, "GET", "/test", "   ")
+		doRequest(mw, "GET", "/test", "")
+		if rec := doRequest(mw, "GET", "/test", " "); rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected whitespace and missing IDs to share the default bucket, got %d", rec.Code)
+		}
+	})
+}
+
+func TestHandlerKeyDimensions(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore(),
+		limiter.WithDefault(config.ClientConfig{Limit: 1, Window: time.Minute}),
+		limiter.WithKeyDimensions(limiter.DimensionClient, limiter.DimensionMethod, limiter.DimensionIP),
+	)
+	mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	send := func(method, addr string) int {
+		req := httptest.NewRequest(method, "/test", nil)
+		req.Header.Set("X-Client-ID", "c1")
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		mw.Handler(func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+		return rec.Code
+	}
+
+	if code := send("GET", "10.0.0.1:1234"); code != http.StatusOK {
+		t.Fatalf("expected first GET allowed, got %d", code)
+	}
+	if code := send("GET", "10.0.0.1:5678"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected GET from the same IP denied, got %d", code)
+	}
+	if code := send("POST", "10.0.0.1:1234"); code != http.StatusOK {
+		t.Fatalf("expected POST counted separately, got %d", code)
+	}
+	if code := send("GET", "[2001:db8::1]:1234"); code != http.StatusOK {
+		t.Fatalf("expected another IP counted separately, got %d", code)
+	}
+}
+
+func TestClientIPKeyExtractor(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	trusted := ClientIPKeyExtractor([]*net.IPNet{proxies})
+	direct := ClientIPKeyExtractor(nil)
+
+	tests := []struct {
+		name      string
+		extractor KeyExtractor
+		remote    string
+		xff       []string
+		want      string
+	}{
+		{"remote addr", direct, "203.0.113.7:4711", nil, "203.0.113.7"},
+		{"ipv6 remote addr", direct, "[2001:db8::1]:4711", nil, "2001:db8::1"},
+		{"forwarded for ignored without trusted proxies", direct, "203.0.113.7:4711", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"forwarded for ignored from untrusted peer", trusted, "203.0.113.7:4711", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"forwarded for from trusted proxy", trusted, "10.0.0.2:4711", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed left-most hop skipped", trusted, "10.0.0.2:4711", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"proxy chain", trusted, "10.0.0.2:4711", []string{"198.51.100.1", "10.0.0.9"}, "198.51.100.1"},
+		{"only proxies", trusted, "10.0.0.2:4711", []string{"10.0.0.9"}, "10.0.0.9"},
+		{"trusted proxy without header", trusted, "10.0.0.2:4711", nil, "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remote
+		for _, v := range tt.xff {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		if got := tt.extractor(req); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandlerClientIPBudgets(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore(), limiter.WithDefault(config.ClientConfig{Limit: 1, Window: time.Minute}))
+	mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(os.Stdout, nil)), WithKeyExtractor(ClientIPKeyExtractor(nil)))
+
+	send := func(addr string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		mw.Handler(func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+		return rec.Code
+	}
+
+	if send("203.0.113.7:1000") != http.StatusOK || send("198.51.100.1:1000") != http.StatusOK {
+		t.Fatal("expected anonymous callers on different IPs to get separate budgets")
+	}
+	if code := send("203.0.113.7:2000"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the same IP limited, got %d", code)
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WithGlobalLoadShedding sheds requests from all clients while load() is above
+// threshold, before any per-client budget is spent. Under overload the
+// fraction of requests admitted is threshold/load, so admitted work stays
+// roughly at the threshold; shed requests get 503 with a Retry-After of
+// retryAfter. load is called on every request and should be cheap, e.g.
+// reading a value sampled in the background from runtime metrics.
+func WithGlobalLoadShedding(load func() float64, threshold float64, retryAfter time.Duration) Option {
+	return func(m *RateLimitMiddleware) {
+		m.globalShed = &globalShedder{
+			load:       load,
+			threshold:  threshold,
+			retryAfter: retryAfter,
+		}
+	}
+}
+
+type globalShedder struct {
+	load       func() float64
+	threshold  float64
+	retryAfter time.Duration
+
+	mu     sync.Mutex
+	credit float64
+}
+
+// admit reports whether to let a request through at the current load,
+// spreading admissions evenly instead of drawing them at random.
+func (g *globalShedder) admit() (bool, float64) {
+	load := g.load()
+	if load <= g.threshold {
+		return true, load
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.credit += g.threshold / load
+	if g.credit < 1 {
+		return false, load
+	}
+	g.credit--
+	return true, load
+}
+
+func sendUnavailable(w http.ResponseWriter, retryAfter time.Duration) {
+	secs := int64(retryAfter.Round(time.Second) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+}
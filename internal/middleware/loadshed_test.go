@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestGlobalLoadShedding(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{
+		"c1": {Limit: 1000, Window: time.Minute},
+		"c2": {Limit: 1000, Window: time.Minute},
+	}
+	load := 0.5
+	mw := newTestMiddleware(cfgs, WithGlobalLoadShedding(func() float64 { return load }, 0.8, 5*time.Second))
+
+	for i := 0; i < 20; i++ {
+		if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected no shedding below threshold, got %d", i+1, rec.Code)
+		}
+	}
+
+	// Twice the threshold admits half the requests, whatever the client.
+	load = 1.6
+	admitted, shed := map[string]int{}, 0
+	for i := 0; i < 40; i++ {
+		client := "c1"
+		if i%4 >= 2 {
+			client = "c2"
+		}
+		rec := doRequest(mw, "GET", "/test", client)
+		switch rec.Code {
+		case http.StatusOK:
+			admitted[client]++
+		case http.StatusServiceUnavailable:
+			shed++
+			if rec.Header().Get("Retry-After") != "5" {
+				t.Fatalf("expected Retry-After 5, got %q", rec.Header().Get("Retry-After"))
+			}
+			if rec.Header().Get("X-RateLimit-Limit") != "" {
+				t.Fatal("expected shedding before the per-client limiter")
+			}
+		default:
+			t.Fatalf("unexpected status %d", rec.Code)
+		}
+	}
+	if admitted["c1"] != 10 || admitted["c2"] != 10 || shed != 20 {
+		t.Fatalf("expected half of each client's requests shed, got admitted %v, %d shed", admitted, shed)
+	}
+
+	// Shed requests spent no per-client budget: 20 + 10 + 1 counted for c1.
+	load = 0
+	rec := doRequest(mw, "GET", "/test", "c1")
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "969" {
+		t.Fatalf("expected shed requests not to count, got remaining %s", got)
+	}
+}
+
+func TestGlobalLoadSheddingComposesWithClientLimits(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs, WithGlobalLoadShedding(func() float64 { return 0 }, 0.8, time.Second))
+
+	doRequest(mw, "GET", "/test", "c1")
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected per-client limit still enforced, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import "github.com/Dzaakk/rate-limiter/internal/limiter"
+
+// Metrics receives every rate limit decision the middleware acts on.
+type Metrics interface {
+	RequestDecided(res limiter.Result)
+}
+
+// WithMetrics reports each request's decision to metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(m *RateLimitMiddleware) {
+		m.metrics = metrics
+	}
+}
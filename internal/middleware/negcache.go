@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// maxNegativeEntries bounds the negative cache; denials beyond it are simply
+// not cached until expired entries are pruned.
+const maxNegativeEntries = 10000
+
+// WithNegativeCache remembers window-based denials (rate_limit, group_limit,
+// burst_exhausted) for up to ttl and answers the client's further requests in
+// the same scope with 429 locally, without a store round trip. An entry never
+// outlives the denial's reset time, so clients are not blocked past their
+// window. Requests answered from the cache skip the shadow limiter and spend
+// no quota, so counters undercount spam during that time. Entries are per
+// request dimensions (client, scope, class, IP, method and limit override),
+// so a denial never answers for a request that may count against another
+// budget, and only requests costing at least as much as the denied one are
+// answered from it.
+func WithNegativeCache(ttl time.Duration) Option {
+	return func(m *RateLimitMiddleware) {
+		m.negCache = &negativeCache{
+			ttl:     ttl,
+			now:     time.Now,
+			entries: map[string]negativeEntry{},
+		}
+	}
+}
+
+type negativeEntry struct {
+	res   limiter.Result
+	cost  int64
+	until time.Time
+}
+
+type negativeCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]negativeEntry
+}
+
+func negativeKey(req limiter.Request) string {
+	return strings.Join([]string{req.Client, req.Scope, req.Class, req.IP, req.Method, strconv.Itoa(req.Limit)}, "\x00")
+}
+
+// lookup returns the cached denial for req, if still valid and req costs at
+// least as much as the denied request.
+func (c *negativeCache) lookup(req limiter.Request) (limiter.Result, bool) {
+	key := negativeKey(req)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return limiter.Result{}, false
+	}
+	if !now.Before(e.until) {
+		delete(c.entries, key)
+		return limiter.Result{}, false
+	}
+	if max(req.Cost, 1) < e.cost {
+		return limiter.Result{}, false
+	}
+	return e.res, true
+}
+
+// store caches res for req if it is a denial that lasts until a known reset.
+func (c *negativeCache) store(req limiter.Request, res limiter.Result) {
+	if res.Allowed || res.ResetAt.IsZero() {
+		return
+	}
+	switch res.Reason {
+	case limiter.ReasonRateLimit, limiter.ReasonGroupLimit, limiter.ReasonBurstExhausted:
+	default:
+		return
+	}
+
+	now := c.now()
+	until := now.Add(c.ttl)
+	if res.ResetAt.Before(until) {
+		until = res.ResetAt
+	}
+	if !now.Before(until) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxNegativeEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.until) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxNegativeEntries {
+			return
+		}
+	}
+	c.entries[negativeKey(req)] = negativeEntry{res: res, cost: max(req.Cost, 1), until: until}
+}
+
+// negativeRequest is the request the negative cache keys r by. Checks spend
+// nothing, so their denials stand for requests of any cost.
+func (m *RateLimitMiddleware) negativeRequest(r *http.Request, clientID, group string) limiter.Request {
+	req := m.limiterRequest(r, clientID, group)
+	if !m.checkOnly[r.Method] && len(m.failureStatuses) == 0 {
+		req.Cost = m.requestCost(r)
+	}
+	return req
+}
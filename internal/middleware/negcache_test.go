@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// countingStore counts every store call the limiter makes.
+type countingStore struct {
+	limiter.Store
+	calls int
+}
+
+func (s *countingStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	s.calls++
+	return s.Store.Increment(ctx, key, ttl)
+}
+
+func (s *countingStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	s.calls++
+	return s.Store.Get(ctx, key)
+}
+
+func newNegativeCacheTest(cfg config.ClientConfig, ttl time.Duration) (*RateLimitMiddleware, *countingStore, *time.Time) {
+	now := time.Date(2025, 10, 23, 10, 30, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store := &countingStore{Store: memory.NewMemoryStore(memory.WithClock(clock))}
+	l := limiter.New(store, limiter.WithDefault(cfg), limiter.WithClock(clock))
+	mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(io.Discard, nil)), WithNegativeCache(ttl))
+	mw.negCache.now = clock
+	return mw, store, &now
+}
+
+func TestNegativeCacheSkipsStore(t *testing.T) {
+	mw, store, now := newNegativeCacheTest(config.ClientConfig{Limit: 2, Window: time.Minute}, 10*time.Second)
+
+	for i := 0; i < 3; i++ {
+		doRequest(mw, "GET", "/test", "c1")
+	}
+	calls := store.calls
+
+	for i := 0; i < 5; i++ {
+		rec := doRequest(mw, "GET", "/test", "c1")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected cached 429, got %d", rec.Code)
+		}
+		if rec.Header().Get("X-RateLimit-Remaining") != "0" || rec.Header().Get("Retry-After") == "" {
+			t.Fatalf("expected rate limit headers on cached denial, got %v", rec.Header())
+		}
+	}
+	if store.calls != calls {
+		t.Fatalf("expected no store calls while cached, got %d", store.calls-calls)
+	}
+
+	if rec := doRequest(mw, "GET", "/test", "c2"); rec.Code != http.StatusOK {
+		t.Fatalf("expected other clients unaffected, got %d", rec.Code)
+	}
+
+	calls = store.calls
+	*now = now.Add(10 * time.Second)
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected still denied by the store, got %d", rec.Code)
+	}
+	if store.calls == calls {
+		t.Fatal("expected the store to be asked once the cache entry expired")
+	}
+}
+
+func TestNegativeCacheReleasesAtReset(t *testing.T) {
+	mw, _, now := newNegativeCacheTest(config.ClientConfig{Limit: 1, Window: 5 * time.Second}, time.Minute)
+
+	doRequest(mw, "GET", "/test", "c1")
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+
+	*now = now.Add(5*time.Second + time.Millisecond)
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the cache to release at the window reset, got %d", rec.Code)
+	}
+}
+
+func TestNegativeCacheIgnoresConcurrencyDenials(t *testing.T) {
+	c := &negativeCache{ttl: time.Minute, now: time.Now, entries: map[string]negativeEntry{}}
+	req := limiter.Request{Client: "c1"}
+	c.store(req, limiter.Result{Reason: limiter.ReasonConcurrency, ResetAt: time.Now().Add(time.Minute)})
+	if _, ok := c.lookup(req); ok {
+		t.Fatal("expected concurrency denials not to be cached")
+	}
+}
+
+func TestNegativeCacheCheaperRequests(t *testing.T) {
+	// The sliding log only charges admitted requests, so a denied expensive
+	// request leaves room for cheaper ones.
+	l := limiter.New(memory.NewMemoryStore(),
+		limiter.WithDefault(config.ClientConfig{Limit: 10, Window: time.Minute}),
+		limiter.WithAlgorithm(limiter.AlgorithmSlidingLog))
+	mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithNegativeCache(time.Minute), WithBodyCost(1))
+	handler := mw.Handler(func(w http.ResponseWriter, r *http.Request) {})
+	post := func(size int) int {
+		req := httptest.NewRequest("POST", "/test", strings.NewReader(strings.Repeat("x", size)))
+		req.Header.Set("X-Client-ID", "c1")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	if code := post(20); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the expensive request denied, got %d", code)
+	}
+	if code := post(20); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the same cost denied from the cache, got %d", code)
+	}
+	if code := post(5); code != http.StatusOK {
+		t.Fatalf("expected a cheap request to reach its own counter, got %d", code)
+	}
+}
+
+func TestNegativeCachePerClass(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore(),
+		limiter.WithDefault(config.ClientConfig{Limit: 1, Window: time.Minute}))
+	mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithNegativeCache(time.Minute),
+		WithUserAgentClasses([]UserAgentRule{{Pattern: regexp.MustCompile("bot"), Class: "bot"}}))
+	handler := mw.Handler(func(w http.ResponseWriter, r *http.Request) {})
+	get := func(ua string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Client-ID", "c1")
+		req.Header.Set("User-Agent", ua)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	get("bot/1.0")
+	if code := get("bot/1.0"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the bot class exhausted, got %d", code)
+	}
+	if code := get("browser"); code != http.StatusOK {
+		t.Fatalf("expected another class to keep its own budget, got %d", code)
+	}
+}
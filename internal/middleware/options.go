@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Option func(*RateLimitMiddleware)
+
+type pathGroup struct {
+	prefix string
+	name   string
+}
+
+// WithPathGroups maps path prefixes to budget group names. Requests whose path
+// matches a prefix share that group's budget per client; the longest matching
+// prefix wins. Unmatched paths count against the client's default budget.
+func WithPathGroups(groups map[string]string) Option {
+	return func(m *RateLimitMiddleware) {
+		m.pathGroups = m.pathGroups[:0]
+		for prefix, name := range groups {
+			m.pathGroups = append(m.pathGroups, pathGroup{prefix: prefix, name: name})
+		}
+		sort.Slice(m.pathGroups, func(i, j int) bool {
+			return len(m.pathGroups[i].prefix) > len(m.pathGroups[j].prefix)
+		})
+	}
+}
+
+// WithPathSegments gives the first k path segments their own budget per
+// client when they match one of allowed, e.g. k=2 with "/api/v1" and "/api/v2"
+// keeps /api/v1/* and /api/v2/* apart while everything under /api/v1 shares
+// one counter. Deeper segments are ignored. Other paths, including unknown
+// ones, share the client's default budget, so cycling through made-up paths
+// cannot mint fresh budgets. Paths matching a WithPathGroups prefix use that
+// group instead.
+func WithPathSegments(k int, allowed ...string) Option {
+	return func(m *RateLimitMiddleware) {
+		m.pathSegments = k
+		m.segmentScopes = make(map[string]bool, len(allowed))
+		for _, prefix := range allowed {
+			if scope := segmentScope(prefix, k); scope != "" {
+				m.segmentScopes[scope] = true
+			}
+		}
+	}
+}
+
+func (m *RateLimitMiddleware) getGroup(path string) string {
+	for _, g := range m.pathGroups {
+		if strings.HasPrefix(path, g.prefix) {
+			return g.name
+		}
+	}
+	if m.pathSegments > 0 {
+		if scope := segmentScope(path, m.pathSegments); m.segmentScopes[scope] {
+			return scope
+		}
+	}
+	return ""
+}
+
+// segmentScope is "path=" and the first k non-empty segments of path, or ""
+// for the root. The prefix keeps it apart from path group names.
+func segmentScope(path string, k int) string {
+	segments := make([]string, 0, k)
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" {
+			continue
+		}
+		segments = append(segments, seg)
+		if len(segments) == k {
+			break
+		}
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+	return "path=" + strings.Join(segments, "/")
+}
+
+// WithCheckOnlyMethods makes requests with the given methods check the limit
+// without consuming quota, e.g. for safe methods like GET and HEAD.
+func WithCheckOnlyMethods(methods ...string) Option {
+	return func(m *RateLimitMiddleware) {
+		m.checkOnly = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			m.checkOnly[method] = true
+		}
+	}
+}
+
+// WithSkip bypasses limiting for requests matching skip, e.g. OPTIONS preflights
+// or allowlisted clients. Skipped requests consume no quota.
+func WithSkip(skip func(*http.Request) bool) Option {
+	return func(m *RateLimitMiddleware) {
+		m.skip = skip
+	}
+}
+
+// WithEnforceFor limits only clients for which enforce returns true, e.g. a
+// pilot set during a phased rollout. Other clients pass through like skipped
+// requests, consuming no quota. It is the inverse of an allowlist.
+func WithEnforceFor(enforce func(clientID string) bool) Option {
+	return func(m *RateLimitMiddleware) {
+		m.enforceFor = enforce
+	}
+}
+
+// EnforceForClients returns a WithEnforceFor predicate matching the given
+// client IDs.
+func EnforceForClients(ids ...string) func(clientID string) bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return func(clientID string) bool {
+		return set[clientID]
+	}
+}
+
+// WithAlwaysSetHeaders makes requests that bypass limiting still carry
+// X-RateLimit-Limit and X-RateLimit-Remaining (reported as the full limit).
+func WithAlwaysSetHeaders(enabled bool) Option {
+	return func(m *RateLimitMiddleware) {
+		m.alwaysHeader = enabled
+	}
+}
+
+// WithThrottleDelay delays allowed requests that are past the client's soft
+// limit by d before passing them on, slowing heavy callers ahead of denial.
+func WithThrottleDelay(d time.Duration) Option {
+	return func(m *RateLimitMiddleware) {
+		m.throttleDelay = d
+	}
+}
+
+// WithBypassToken lets requests whose X-RateLimit-Bypass header matches token
+// skip limiting entirely. Mismatched tokens are ignored; an empty token
+// disables bypassing.
+func WithBypassToken(token string) Option {
+	return func(m *RateLimitMiddleware) {
+		m.bypassToken = []byte(token)
+	}
+}
+
+func (m *RateLimitMiddleware) hasBypassToken(r *http.Request) bool {
+	if len(m.bypassToken) == 0 {
+		return false
+	}
+	got := r.Header.Get("X-RateLimit-Bypass")
+	return subtle.ConstantTimeCompare([]byte(got), m.bypassToken) == 1
+}
+
+// WithDocsLink adds a Link header with rel="help" pointing at url to denied
+// responses, so clients can find how limits work and how to request more.
+func WithDocsLink(url string) Option {
+	return func(m *RateLimitMiddleware) {
+		m.docsLink = url
+	}
+}
+
+// WithOnError customizes the response written when the limiter returns an
+// error, e.g. a 503 with Retry-After for clients behind a CDN. The default is
+// a plain 500. Limiters using FailOpen or FailClosed never report store errors,
+// so the hook only runs under the FailError policy.
+func WithOnError(fn func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return func(m *RateLimitMiddleware) {
+		if fn != nil {
+			m.onError = fn
+		}
+	}
+}
+
+// WithAllowedLogLevel sets the level of the per-request "request allowed" log
+// line (Debug by default). Denials stay at Warn and errors at Error.
+func WithAllowedLogLevel(level slog.Level) Option {
+	return func(m *RateLimitMiddleware) {
+		m.allowedLevel = level
+	}
+}
@@ -0,0 +1,354 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func newTestMiddleware(cfgs map[string]config.ClientConfig, opts ...Option) *RateLimitMiddleware {
+	l := limiter.NewLimiter(memory.NewMemoryStore(), cfgs)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return NewRateLimitMiddleware(l, logger, opts...)
+}
+
+func doRequest(mw *RateLimitMiddleware, method, path, clientID string) *httptest.ResponseRecorder {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(method, path, nil)
+	if clientID != "" {
+		req.Header.Set("X-Client-ID", clientID)
+	}
+	rec := httptest.NewRecorder()
+	mw.Handler(handler)(rec, req)
+	return rec
+}
+
+func TestWithPathGroups(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs, WithPathGroups(map[string]string{
+		"/api/v1/reports/":       "reports",
+		"/api/v1/reports/heavy/": "heavy-reports",
+	}))
+
+	if rec := doRequest(mw, "GET", "/api/v1/reports/daily", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec := doRequest(mw, "GET", "/api/v1/reports/weekly", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec := doRequest(mw, "GET", "/api/v1/reports/monthly", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected shared reports budget to be exhausted, got %d", rec.Code)
+	}
+
+	if rec := doRequest(mw, "GET", "/api/v1/users", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected unmatched path to use its own budget, got %d", rec.Code)
+	}
+	if rec := doRequest(mw, "GET", "/api/v1/reports/heavy/export", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected longest prefix group to have its own budget, got %d", rec.Code)
+	}
+}
+
+func TestWithPathSegments(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs, WithPathSegments(2, "/api/v1", "/api/v2"))
+
+	for _, path := range []string{"/api/v1/users", "/api/v1/orders/42/items"} {
+		if rec := doRequest(mw, "GET", path, "c1"); rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, rec.Code)
+		}
+	}
+	if rec := doRequest(mw, "GET", "/api/v1/reports", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected deeper v1 paths to share one budget, got %d", rec.Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := doRequest(mw, "GET", "/api/v2/users", "c1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected v2 unaffected by the v1 spike, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := doRequest(mw, "GET", "/api/v2/users", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected v2 to have its own limit, got %d", rec.Code)
+	}
+}
+
+func TestWithPathSegmentsUnknownPathsShareBudget(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs, WithPathSegments(2, "/api/v1"))
+
+	for _, path := range []string{"/api/x1", "/api/x2/users"} {
+		if rec := doRequest(mw, "GET", path, "c1"); rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, rec.Code)
+		}
+	}
+	for _, path := range []string{"/api/x3", "/missing"} {
+		if rec := doRequest(mw, "GET", path, "c1"); rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("%s: expected unknown segments to share one budget, got %d", path, rec.Code)
+		}
+	}
+	if rec := doRequest(mw, "GET", "/api/v1/users", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the allowed prefix to keep its own budget, got %d", rec.Code)
+	}
+}
+
+func TestSegmentScope(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		k    int
+		want string
+	}{
+		{"/api/v1/users/42", 2, "path=api/v1"},
+		{"/api/v1", 3, "path=api/v1"},
+		{"//api//v1/users", 2, "path=api/v1"},
+		{"/health", 1, "path=health"},
+		{"/", 2, ""},
+	} {
+		if got := segmentScope(tc.path, tc.k); got != tc.want {
+			t.Errorf("segmentScope(%q, %d) = %q, want %q", tc.path, tc.k, got, tc.want)
+		}
+	}
+}
+
+func TestGetGroup(t *testing.T) {
+	mw := newTestMiddleware(nil, WithPathGroups(map[string]string{
+		"/api/v1/reports/": "reports",
+		"/api/v1/":         "v1",
+	}))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/reports/daily", "reports"},
+		{"/api/v1/users", "v1"},
+		{"/api/v2/users", ""},
+	}
+	for _, tt := range tests {
+		if got := mw.getGroup(tt.path); got != tt.want {
+			t.Errorf("getGroup(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWithCheckOnlyMethods(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs, WithCheckOnlyMethods("GET", "HEAD"))
+
+	for i := 0; i < 3; i++ {
+		if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+			t.Fatalf("GET %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+
+	if rec := doRequest(mw, "POST", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected POST to consume the untouched budget, got %d", rec.Code)
+	}
+
+	rec := doRequest(mw, "GET", "/test", "c1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected GET check to deny once exhausted, got %d", rec.Code)
+	}
+}
+
+func TestWithSkip(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	skipOptions := WithSkip(func(r *http.Request) bool { return r.Method == http.MethodOptions })
+
+	t.Run("skipped requests consume no quota", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, skipOptions)
+		for i := 0; i < 3; i++ {
+			if rec := doRequest(mw, "OPTIONS", "/test", "c1"); rec.Code != http.StatusOK {
+				t.Fatalf("expected skipped request to pass, got %d", rec.Code)
+			}
+		}
+		if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+			t.Fatalf("expected budget untouched by skipped requests, got %d", rec.Code)
+		}
+	})
+	t.Run("headers absent by default", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, skipOptions)
+		rec := doRequest(mw, "OPTIONS", "/test", "c1")
+		if rec.Header().Get("X-RateLimit-Limit") != "" || rec.Header().Get("X-RateLimit-Remaining") != "" {
+			t.Fatalf("expected no rate limit headers, got %v", rec.Header())
+		}
+	})
+	t.Run("headers present when enabled", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, skipOptions, WithAlwaysSetHeaders(true))
+		rec := doRequest(mw, "OPTIONS", "/test", "c1")
+		if rec.Header().Get("X-RateLimit-Limit") != "1" {
+			t.Errorf("expected limit header 1, got %q", rec.Header().Get("X-RateLimit-Limit"))
+		}
+		if rec.Header().Get("X-RateLimit-Remaining") != "1" {
+			t.Errorf("expected remaining header 1, got %q", rec.Header().Get("X-RateLimit-Remaining"))
+		}
+		if rec.Header().Get("X-RateLimit-Reset") != "" {
+			t.Errorf("expected no reset header, got %q", rec.Header().Get("X-RateLimit-Reset"))
+		}
+	})
+}
+
+func TestWithEnforceFor(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{
+		"pilot": {Limit: 1, Window: time.Minute},
+		"other": {Limit: 1, Window: time.Minute},
+	}
+	mw := newTestMiddleware(cfgs, WithEnforceFor(EnforceForClients("pilot")), WithAlwaysSetHeaders(true))
+
+	if rec := doRequest(mw, "GET", "/test", "pilot"); rec.Code != http.StatusOK {
+		t.Fatalf("expected first pilot request to pass, got %d", rec.Code)
+	}
+	if rec := doRequest(mw, "GET", "/test", "pilot"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected pilot client to be limited, got %d", rec.Code)
+	}
+
+	for i := 0; i < 5; i++ {
+		rec := doRequest(mw, "GET", "/test", "other")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected non-pilot client never throttled, got %d", i+1, rec.Code)
+		}
+		if rec.Header().Get("X-RateLimit-Remaining") != "1" {
+			t.Fatalf("expected informational headers with the full limit, got %v", rec.Header())
+		}
+	}
+}
+
+func TestWithThrottleDelay(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, SoftLimit: 1, Window: time.Minute}}
+	delay := 50 * time.Millisecond
+	mw := newTestMiddleware(cfgs, WithThrottleDelay(delay))
+
+	start := time.Now()
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Fatalf("expected request under soft limit to be fast, took %v", elapsed)
+	}
+
+	start = time.Now()
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected throttled request to be allowed, got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatalf("expected throttled request to be delayed, took %v", elapsed)
+	}
+
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 at hard limit, got %d", rec.Code)
+	}
+}
+
+func TestWithBypassToken(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	do := func(mw *RateLimitMiddleware, token string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Client-ID", "c1")
+		if token != "" {
+			req.Header.Set("X-RateLimit-Bypass", token)
+		}
+		rec := httptest.NewRecorder()
+		mw.Handler(handler)(rec, req)
+		return rec.Code
+	}
+
+	t.Run("valid token bypasses", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithBypassToken("s3cret"))
+		for i := 0; i < 3; i++ {
+			if code := do(mw, "s3cret"); code != http.StatusOK {
+				t.Fatalf("expected bypassed request to pass, got %d", code)
+			}
+		}
+		if code := do(mw, ""); code != http.StatusOK {
+			t.Fatalf("expected budget untouched by bypassed requests, got %d", code)
+		}
+	})
+	t.Run("invalid token is limited", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithBypassToken("s3cret"))
+		do(mw, "wrong")
+		if code := do(mw, "wrong"); code != http.StatusTooManyRequests {
+			t.Fatalf("expected 429, got %d", code)
+		}
+	})
+	t.Run("no token is limited", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithBypassToken("s3cret"))
+		do(mw, "")
+		if code := do(mw, ""); code != http.StatusTooManyRequests {
+			t.Fatalf("expected 429, got %d", code)
+		}
+	})
+	t.Run("empty configured token never bypasses", func(t *testing.T) {
+		mw := newTestMiddleware(cfgs, WithBypassToken(""))
+		do(mw, "")
+		if code := do(mw, ""); code != http.StatusTooManyRequests {
+			t.Fatalf("expected 429, got %d", code)
+		}
+	})
+}
+
+func TestWithAllowedLogLevel(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	newMW := func(logs *bytes.Buffer, opts ...Option) *RateLimitMiddleware {
+		logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		return NewRateLimitMiddleware(limiter.NewLimiter(memory.NewMemoryStore(), cfgs), logger, opts...)
+	}
+
+	t.Run("debug by default", func(t *testing.T) {
+		var logs bytes.Buffer
+		mw := newMW(&logs)
+
+		doRequest(mw, "GET", "/test", "c1")
+		if strings.Contains(logs.String(), "request allowed") {
+			t.Fatalf("expected allowed request not logged at Info, got %q", logs.String())
+		}
+		doRequest(mw, "GET", "/test", "c1")
+		if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "rate limit exceeded") {
+			t.Fatalf("expected denial logged at Warn, got %q", logs.String())
+		}
+	})
+	t.Run("configured level", func(t *testing.T) {
+		var logs bytes.Buffer
+		mw := newMW(&logs, WithAllowedLogLevel(slog.LevelInfo))
+
+		doRequest(mw, "GET", "/test", "c1")
+		if !strings.Contains(logs.String(), "level=INFO") || !strings.Contains(logs.String(), "request allowed") {
+			t.Fatalf("expected allowed request logged at Info, got %q", logs.String())
+		}
+	})
+}
+
+func TestWithDocsLink(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	const want = `<https://example.com/docs/rate-limits>; rel="help"`
+
+	for _, format := range []ResponseFormat{FormatJSON, FormatProblemJSON} {
+		mw := newTestMiddleware(cfgs, WithDocsLink("https://example.com/docs/rate-limits"), WithResponseFormat(format))
+
+		if rec := doRequest(mw, "GET", "/test", "c1"); rec.Header().Get("Link") != "" {
+			t.Fatalf("expected no Link header on allowed requests, got %q", rec.Header().Get("Link"))
+		}
+		rec := doRequest(mw, "GET", "/test", "c1")
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Link") != want {
+			t.Fatalf("format %d: expected %q on denial, got %d %q", format, want, rec.Code, rec.Header().Get("Link"))
+		}
+	}
+
+	mw := newTestMiddleware(cfgs)
+	doRequest(mw, "GET", "/test", "c1")
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Header().Get("Link") != "" {
+		t.Fatalf("expected no Link header when disabled, got %q", rec.Header().Get("Link"))
+	}
+}
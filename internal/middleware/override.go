@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type limitOverrideKey struct{}
+
+// ContextWithLimitOverride asks for limit to replace the client's configured
+// limit for this request only. Auth middleware sets it for requests it has
+// pre-authorized, e.g. bulk operations; the override only takes effect when
+// WithTrustedLimitOverrides accepts the request.
+func ContextWithLimitOverride(ctx context.Context, limit int) context.Context {
+	return context.WithValue(ctx, limitOverrideKey{}, limit)
+}
+
+// WithTrustedLimitOverrides applies limit overrides from the request context
+// (see ContextWithLimitOverride) to requests for which trusted returns true.
+// Without this option overrides are ignored.
+func WithTrustedLimitOverrides(trusted func(*http.Request) bool) Option {
+	return func(m *RateLimitMiddleware) {
+		m.trustedOverride = trusted
+	}
+}
+
+// limitOverride is the request's trusted override, or 0 for none.
+func (m *RateLimitMiddleware) limitOverride(r *http.Request) int {
+	if m.trustedOverride == nil {
+		return 0
+	}
+	limit, ok := r.Context().Value(limitOverrideKey{}).(int)
+	if !ok || limit <= 0 || !m.trustedOverride(r) {
+		return 0
+	}
+	return limit
+}
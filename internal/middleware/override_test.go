@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestTrustedLimitOverride(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs, WithTrustedLimitOverrides(func(r *http.Request) bool {
+		return r.Header.Get("X-Upstream") == "billing"
+	}))
+
+	send := func(upstream string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/bulk", nil)
+		req.Header.Set("X-Client-ID", "c1")
+		if upstream != "" {
+			req.Header.Set("X-Upstream", upstream)
+		}
+		req = req.WithContext(ContextWithLimitOverride(req.Context(), 10))
+		rec := httptest.NewRecorder()
+		mw.Handler(func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := send(""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+	rec := send("")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Fatalf("expected untrusted override ignored, got %d with limit %q", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+
+	rec = send("billing")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "10" {
+		t.Fatalf("expected trusted request to get the elevated limit, got %d with limit %q", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+
+	if rec := doRequest(mw, "POST", "/bulk", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected later requests back on the configured limit, got %d", rec.Code)
+	}
+}
+
+func TestLimitOverrideIgnoredWithoutOption(t *testing.T) {
+	mw := newTestMiddleware(map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}})
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Client-ID", "c1")
+		req = req.WithContext(ContextWithLimitOverride(req.Context(), 100))
+		rec := httptest.NewRecorder()
+		mw.Handler(func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+		if rec.Code != want {
+			t.Fatalf("request %d: expected %d, got %d", i+1, want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+type ResponseFormat int
+
+const (
+	// FormatJSON is the default {"error": ..., "remaining": ...} body.
+	FormatJSON ResponseFormat = iota
+	// FormatProblemJSON is an RFC 7807 application/problem+json body.
+	FormatProblemJSON
+)
+
+// WithResponseFormat selects the body written for denied requests.
+func WithResponseFormat(f ResponseFormat) Option {
+	return func(m *RateLimitMiddleware) {
+		m.responseFormat = f
+	}
+}
+
+// problem is an RFC 7807 problem details object with rate limit extension
+// members.
+type problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail"`
+	Limit     int            `json:"limit"`
+	Remaining int            `json:"remaining"`
+	Reset     int64          `json:"reset,omitempty"`
+	Reason    limiter.Reason `json:"reason,omitempty"`
+}
+
+func (m *RateLimitMiddleware) sendProblem(w http.ResponseWriter, res limiter.Result, detail string) {
+	p := problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(http.StatusTooManyRequests),
+		Status:    http.StatusTooManyRequests,
+		Detail:    detail,
+		Limit:     max(res.Limit, 0),
+		Remaining: max(res.Remaining, 0),
+		Reason:    res.Reason,
+	}
+	if !res.ResetAt.IsZero() {
+		p.Reset = res.ResetAt.Unix()
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(p)
+}
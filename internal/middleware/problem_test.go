@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestWithResponseFormatProblemJSON(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs, WithResponseFormat(FormatProblemJSON))
+
+	doRequest(mw, "GET", "/test", "c1")
+	rec := doRequest(mw, "GET", "/test", "c1")
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("expected problem+json content type, got %q", ct)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	for _, field := range []string{"type", "title", "status", "detail", "limit", "remaining", "reset"} {
+		if _, ok := body[field]; !ok {
+			t.Errorf("expected field %q in %v", field, body)
+		}
+	}
+	if body["status"] != float64(http.StatusTooManyRequests) || body["title"] != "Too Many Requests" {
+		t.Errorf("unexpected status/title in %v", body)
+	}
+	if body["limit"] != float64(1) || body["remaining"] != float64(0) {
+		t.Errorf("unexpected limit/remaining in %v", body)
+	}
+}
+
+func TestDefaultResponseFormat(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs)
+
+	doRequest(mw, "GET", "/test", "c1")
+	rec := doRequest(mw, "GET", "/test", "c1")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected plain JSON by default, got %q", ct)
+	}
+}
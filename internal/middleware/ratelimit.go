@@ -2,98 +2,342 @@ package middleware
 
 import (
 	"encoding/json"
-	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Dzaakk/rate-limiter/config"
 	"github.com/Dzaakk/rate-limiter/internal/limiter"
 )
 
+// Limiter is what the middleware needs from a rate limiter. *limiter.Limiter
+// implements it; other algorithms can be plugged in with AllowerAdapter.
+type Limiter interface {
+	AcquireRequest(req limiter.Request) (limiter.Result, func(), error)
+	CheckRequest(req limiter.Request) (limiter.Result, error)
+	ConfigFor(client string) config.ClientConfig
+}
+
 type RateLimitMiddleware struct {
-	limiter *limiter.Limiter
-	logger  *slog.Logger
+	limiter          Limiter
+	connLimiter      Limiter
+	logger           *slog.Logger
+	pathGroups       []pathGroup
+	pathSegments     int
+	segmentScopes    map[string]bool
+	bodyCostUnit     int64
+	maxBodyPeek      int64
+	checkOnly        map[string]bool
+	uaRules          []UserAgentRule
+	skip             func(*http.Request) bool
+	enforceFor       func(string) bool
+	alwaysHeader     bool
+	keyExtractor     KeyExtractor
+	foldKeyCase      bool
+	throttleDelay    time.Duration
+	bypassToken      []byte
+	onError          func(http.ResponseWriter, *http.Request, error)
+	requestIDHeader  string
+	allowedLevel     slog.Level
+	failureStatuses  map[int]bool
+	resetStatuses    map[int]bool
+	responseFormat   ResponseFormat
+	responseTemplate *ResponseTemplate
+	docsLink         string
+	globalShed       *globalShedder
+	shadow           Limiter
+	shadowMetrics    ShadowMetrics
+	trailers         bool
+	metrics          Metrics
+	negCache         *negativeCache
+	trustedOverride  func(*http.Request) bool
+	tolerance        int
+	jwt              *JWTVerifier
+
+	draining        atomic.Bool
+	drainRetryAfter atomic.Int64
 }
 
-func NewRateLimitMiddleware(l *limiter.Limiter, logger *slog.Logger) *RateLimitMiddleware {
-	return &RateLimitMiddleware{
-		limiter: l,
-		logger:  logger,
+func NewRateLimitMiddleware(l Limiter, logger *slog.Logger, opts ...Option) *RateLimitMiddleware {
+	m := &RateLimitMiddleware{
+		limiter:      l,
+		logger:       logger,
+		maxBodyPeek:  defaultMaxBodyPeek,
+		keyExtractor: HeaderKeyExtractor,
+		onError:      defaultOnError,
+		allowedLevel: slog.LevelDebug,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 func (m *RateLimitMiddleware) Handler(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if m.draining.Load() {
+			m.sendDraining(w)
+			return
+		}
+
+		logger := m.requestLogger(w, r)
 		clientID := m.getClientID(r)
 
-		allowed, remaining, resetAt, err := m.limiter.Allow(clientID)
+		if m.hasBypassToken(r) {
+			logger.Info("rate limit bypassed", "client", clientID, "path", r.URL.Path)
+			m.passThrough(w, r, clientID, next)
+			return
+		}
+
+		if m.skip != nil && m.skip(r) {
+			m.passThrough(w, r, clientID, next)
+			return
+		}
+
+		if m.enforceFor != nil && !m.enforceFor(clientID) {
+			m.passThrough(w, r, clientID, next)
+			return
+		}
+
+		if m.globalShed != nil {
+			if ok, load := m.globalShed.admit(); !ok {
+				logger.Warn("request shed under load", "client", clientID, "load", load, "path", r.URL.Path)
+				sendUnavailable(w, m.globalShed.retryAfter)
+				return
+			}
+		}
+
+		group := m.getGroup(r.URL.Path)
+
+		var negReq limiter.Request
+		if m.negCache != nil {
+			negReq = m.negativeRequest(r, clientID, group)
+		}
+		if m.negCache != nil && negReq.Limit == 0 {
+			if res, ok := m.negCache.lookup(negReq); ok {
+				logger.Debug("rate limit exceeded (cached)", "client", clientID, "group", group, "path", r.URL.Path)
+				if m.metrics != nil {
+					m.metrics.RequestDecided(res)
+				}
+				setRateLimitHeaders(w, res)
+				m.sendRateLimitError(w, res, clientID, retryMessage(m.limiter, clientID))
+				return
+			}
+		}
+
+		res, release, err := m.decide(m.limiter, r, clientID, group)
+		defer release()
 		if err != nil {
-			m.logger.Error("rate limiter error", "error", err, "client", clientID)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			if r.Context().Err() != nil {
+				// The client went away; there is nobody to answer.
+				return
+			}
+			logger.Error("rate limiter error", "error", err, "client", clientID)
+			m.onError(w, r, err)
 			return
 		}
+		defer m.runShadow(r, logger, clientID, group, res)()
+		if !res.Allowed && m.tolerate(r, res) {
+			logger.Warn("rate limit exceeded within tolerance",
+				"client", clientID,
+				"group", group,
+				"reason", res.Reason,
+				"count", res.Count,
+				"tolerance", m.tolerance,
+				"path", r.URL.Path,
+			)
+			res.Allowed, res.Reason = true, ""
+		}
+		if m.metrics != nil {
+			m.metrics.RequestDecided(res)
+		}
 
-		m.setRateLimitHeaders(w, clientID, remaining, resetAt)
+		setRateLimitHeaders(w, res)
 
-		if !allowed {
-			m.logger.Warn("rate limit exceeded",
+		if !res.Allowed {
+			if m.negCache != nil {
+				m.negCache.store(negReq, res)
+			}
+			logger.Warn("rate limit exceeded",
 				"client", clientID,
-				"remaining", remaining,
+				"group", group,
+				"reason", res.Reason,
+				"count", res.Count,
+				"remaining", res.Remaining,
 				"path", r.URL.Path,
 			)
 
-			m.sendRateLimitError(w, remaining, resetAt)
+			m.sendRateLimitError(w, res, clientID, retryMessage(m.limiter, clientID))
 			return
 		}
 
-		m.logger.Info("request allowed",
+		logger.Log(r.Context(), m.allowedLevel, "request allowed",
 			"client", clientID,
-			"remaining", remaining,
+			"group", group,
+			"count", res.Count,
+			"remaining", res.Remaining,
+			"throttled", res.Throttled,
 			"path", r.URL.Path,
 		)
 
-		next(w, r)
+		if d := m.delay(res); d > 0 {
+			if !m.wait(r, d) {
+				return
+			}
+		}
+
+		serve := next
+		if len(m.resetStatuses) > 0 {
+			serve = m.resetOnStatus(logger, clientID, group, next)
+		}
+
+		if len(m.failureStatuses) > 0 {
+			m.serveCountingFailures(w, r, logger, clientID, group, serve)
+			return
+		}
+
+		if m.trailers {
+			m.serveWithTrailers(w, r, logger, clientID, group, serve)
+			return
+		}
+
+		serve(w, r)
 	}
 }
 
-func (m *RateLimitMiddleware) getClientID(r *http.Request) string {
-	clientID := r.Header.Get("X-Client-ID")
-	if clientID == "" {
-		clientID = "default"
+// Drain stops admitting new requests, answering them with 503 and a
+// Retry-After of retryAfter, while requests already in flight finish. Call it
+// when graceful shutdown starts so no new quota is spent on requests that
+// would be cut off.
+func (m *RateLimitMiddleware) Drain(retryAfter time.Duration) {
+	m.drainRetryAfter.Store(int64(retryAfter))
+	m.draining.Store(true)
+}
+
+func (m *RateLimitMiddleware) sendDraining(w http.ResponseWriter) {
+	w.Header().Set("Connection", "close")
+	sendUnavailable(w, time.Duration(m.drainRetryAfter.Load()))
+}
+
+func defaultOnError(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+}
+
+// passThrough serves a request that bypasses limiting, optionally with
+// informational headers reporting the client's full limit.
+func (m *RateLimitMiddleware) passThrough(w http.ResponseWriter, r *http.Request, clientID string, next http.HandlerFunc) {
+	if m.alwaysHeader {
+		limit := m.getLimit(clientID)
+		setRateLimitHeaders(w, limiter.Result{Allowed: true, Limit: limit, Remaining: limit})
 	}
-	return clientID
+	next(w, r)
 }
 
-func (m *RateLimitMiddleware) setRateLimitHeaders(w http.ResponseWriter, clientID string, remaining int, resetAt time.Time) {
-	limit := m.getLimit(clientID)
+// delay is how long to hold an allowed request: its leaky bucket queueing
+// delay, or the throttle delay if the client is past its soft limit and that
+// is longer.
+func (m *RateLimitMiddleware) delay(res limiter.Result) time.Duration {
+	d := res.Delay
+	if res.Throttled && m.throttleDelay > d {
+		d = m.throttleDelay
+	}
+	return d
+}
 
-	w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
-	w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
+// wait holds the request for d, reporting false if the client went away
+// first.
+func (m *RateLimitMiddleware) wait(r *http.Request, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
 
-	if !resetAt.IsZero() {
-		w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", resetAt.Unix()))
+func (m *RateLimitMiddleware) limiterRequest(r *http.Request, clientID, group string) limiter.Request {
+	return limiter.Request{
+		Client:  clientID,
+		Scope:   group,
+		Class:   m.requestClass(r),
+		IP:      remoteIP(r),
+		Method:  r.Method,
+		Limit:   m.limitOverride(r),
+		Context: r.Context(),
+	}
+}
+
+func (m *RateLimitMiddleware) decide(l Limiter, r *http.Request, clientID, group string) (limiter.Result, func(), error) {
+	req := m.limiterRequest(r, clientID, group)
+
+	if m.checkOnly[r.Method] || len(m.failureStatuses) > 0 {
+		res, err := l.CheckRequest(req)
+		return res, func() {}, err
+	}
+
+	req.Cost = m.requestCost(r)
+	return l.AcquireRequest(req)
+}
+
+func (m *RateLimitMiddleware) getClientID(r *http.Request) string {
+	clientID := m.keyExtractor(r)
+	if m.foldKeyCase {
+		clientID = strings.ToLower(clientID)
+	}
+	return clientID
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, res limiter.Result) {
+	for k, v := range res.Headers() {
+		w.Header().Set(k, v)
 	}
 }
 
 func (m *RateLimitMiddleware) getLimit(clientID string) int {
-	if cfg, ok := config.Clients[clientID]; ok {
-		return cfg.Limit
+	return m.limiter.ConfigFor(clientID).Limit
+}
+
+// defaultRetryMessage is the 429 message for clients without a RetryMessage.
+const defaultRetryMessage = "Rate limit exceeded"
+
+// retryMessage is the client's configured 429 message in l, or the default.
+func retryMessage(l Limiter, clientID string) string {
+	if msg := l.ConfigFor(clientID).RetryMessage; msg != "" {
+		return msg
 	}
-	return config.DefaultConfig.Limit
+	return defaultRetryMessage
 }
 
-func (m *RateLimitMiddleware) sendRateLimitError(w http.ResponseWriter, remaining int, resetAt time.Time) {
+func (m *RateLimitMiddleware) sendRateLimitError(w http.ResponseWriter, res limiter.Result, clientID, message string) {
+	if m.docsLink != "" {
+		w.Header().Add("Link", "<"+m.docsLink+`>; rel="help"`)
+	}
+	if m.responseTemplate != nil && m.sendTemplate(w, res, clientID, message) {
+		return
+	}
+	if m.responseFormat == FormatProblemJSON {
+		m.sendProblem(w, res, message)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusTooManyRequests)
 
 	response := map[string]interface{}{
-		"error":     "Rate limit exceeded",
-		"remaining": remaining,
+		"error":     message,
+		"remaining": res.Remaining,
+	}
+
+	if res.Reason != "" {
+		response["reason"] = res.Reason
 	}
 
-	if !resetAt.IsZero() {
-		response["reset_at"] = resetAt.Unix()
+	if !res.ResetAt.IsZero() {
+		response["reset_at"] = res.ResetAt.Unix()
 	}
 
 	json.NewEncoder(w).Encode(response)
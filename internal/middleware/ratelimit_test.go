@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
@@ -18,14 +19,18 @@ import (
 
 type mockStoreError struct{}
 
-func (m *mockStoreError) Increment(key string, ttl time.Duration) (int64, time.Time, error) {
+func (m *mockStoreError) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
 	return 0, time.Time{}, errors.New("storage error")
 }
 
-func (m *mockStoreError) Get(key string) (int64, time.Time, error) {
+func (m *mockStoreError) Get(ctx context.Context, key string) (int64, time.Time, error) {
 	return 0, time.Time{}, errors.New("storage error")
 }
 
+func (m *mockStoreError) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return false, errors.New("storage error")
+}
+
 func TestNewRateLimitMiddleware(t *testing.T) {
 	store := memory.NewMemoryStore()
 	l := limiter.NewLimiter(store, config.Clients)
@@ -212,6 +217,17 @@ func TestRateLimitMiddleware_Handler_RateLimitExceeded(t *testing.T) {
 		t.Errorf("expected remaining '0', got '%s'", remainingHeader)
 	}
 
+	// Retry-After counts the seconds to X-RateLimit-Reset, here about the
+	// whole one-minute window.
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 59 || retryAfter > 60 {
+		t.Errorf("expected Retry-After of about 60 seconds, got %q", rec.Header().Get("Retry-After"))
+	}
+	reset, _ := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+	if diff := time.Now().Unix() + int64(retryAfter) - reset; diff < -1 || diff > 1 {
+		t.Errorf("expected Retry-After %d to match X-RateLimit-Reset %d", retryAfter, reset)
+	}
+
 	var response map[string]interface{}
 	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
@@ -251,6 +267,35 @@ func TestRateLimitMiddleware_Handler_StorageError(t *testing.T) {
 	}
 }
 
+func TestRateLimitMiddleware_Handler_AbortedRequest(t *testing.T) {
+	l := limiter.NewLimiter(&mockStoreError{}, config.Clients)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	onErrorCalled := false
+	mw := NewRateLimitMiddleware(l, logger, WithOnError(func(w http.ResponseWriter, r *http.Request, err error) {
+		onErrorCalled = true
+	}))
+
+	handlerCalled := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/test", nil).WithContext(ctx)
+	req.Header.Set("X-Client-ID", "client-1")
+	rec := httptest.NewRecorder()
+
+	mw.Handler(handler)(rec, req)
+
+	if handlerCalled || onErrorCalled {
+		t.Fatalf("expected an aborted request to be dropped, handler %v, onError %v", handlerCalled, onErrorCalled)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected nothing written, got %q", rec.Body.String())
+	}
+}
+
 func TestRateLimitMiddleware_Handler_Concurrent(t *testing.T) {
 	store := memory.NewMemoryStore()
 	cfgs := map[string]config.ClientConfig{
@@ -290,3 +335,276 @@ func TestRateLimitMiddleware_Handler_Concurrent(t *testing.T) {
 		t.Errorf("expected %d successful requests, got %d", N, successCount)
 	}
 }
+
+func TestRateLimitMiddleware_Handler_ConcurrencyLimit(t *testing.T) {
+	store := memory.NewMemoryStore()
+	cfgs := map[string]config.ClientConfig{
+		"test-client": {Limit: 10, Window: time.Minute, MaxConcurrent: 1},
+	}
+	l := limiter.NewLimiter(store, cfgs)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mw := NewRateLimitMiddleware(l, logger)
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	blocking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	})
+
+	done := make(chan int)
+	go func() {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Client-ID", "test-client")
+		rec := httptest.NewRecorder()
+		mw.Handler(blocking)(rec, req)
+		done <- rec.Code
+	}()
+	<-entered
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Client-ID", "test-client")
+	rec := httptest.NewRecorder()
+	mw.Handler(blocking)(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 while a request is in flight, got %d", rec.Code)
+	}
+	var response map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response["reason"] != "concurrency" {
+		t.Errorf("expected reason concurrency, got %v", response["reason"])
+	}
+
+	close(unblock)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("expected in-flight request to complete with 200, got %d", code)
+	}
+	if l.InFlight("test-client") != 0 {
+		t.Fatalf("expected slot released after completion, got %d", l.InFlight("test-client"))
+	}
+}
+
+func TestSetRateLimitHeaders_Sanitized(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{
+		"unlimited": {Limit: config.Unlimited, Window: time.Minute},
+		"blocked":   {Limit: 0, Window: time.Minute},
+	}
+	l := limiter.NewLimiter(memory.NewMemoryStore(), cfgs)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mw := NewRateLimitMiddleware(l, logger)
+
+	tests := []struct {
+		name          string
+		limit         int
+		remaining     int
+		wantLimit     string
+		wantRemaining string
+	}{
+		{"unlimited", config.Unlimited, config.Unlimited, "", ""},
+		{"negative remaining", 5, -2, "5", "0"},
+		{"remaining above limit", 5, 1 << 30, "5", "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			setRateLimitHeaders(rec, limiter.Result{Allowed: true, Limit: tt.limit, Remaining: tt.remaining})
+
+			if got := rec.Header().Get("X-RateLimit-Limit"); got != tt.wantLimit {
+				t.Errorf("expected limit %s, got %s", tt.wantLimit, got)
+			}
+			if got := rec.Header().Get("X-RateLimit-Remaining"); got != tt.wantRemaining {
+				t.Errorf("expected remaining %s, got %s", tt.wantRemaining, got)
+			}
+		})
+	}
+
+	for i := 0; i < 3; i++ {
+		rec := doRequest(mw, "GET", "/test", "unlimited")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected unlimited client to be allowed, got %d", rec.Code)
+		}
+		if rec.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("expected no rate limit headers for unlimited client, got %v", rec.Header())
+		}
+	}
+
+	rec := doRequest(mw, "GET", "/test", "blocked")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected zero limit to block, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "0" {
+		t.Errorf("expected limit header 0, got %q", rec.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitMiddleware_Handler_OnError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(mw *RateLimitMiddleware) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Client-ID", "client-1")
+		rec := httptest.NewRecorder()
+		mw.Handler(handler)(rec, req)
+		return rec
+	}
+
+	t.Run("default", func(t *testing.T) {
+		mw := NewRateLimitMiddleware(limiter.NewLimiter(&mockStoreError{}, config.Clients), logger)
+		if rec := serve(mw); rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", rec.Code)
+		}
+	})
+	t.Run("custom renderer", func(t *testing.T) {
+		var gotErr error
+		mw := NewRateLimitMiddleware(limiter.NewLimiter(&mockStoreError{}, config.Clients), logger,
+			WithOnError(func(w http.ResponseWriter, r *http.Request, err error) {
+				gotErr = err
+				w.Header().Set("Retry-After", "5")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+
+		rec := serve(mw)
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "5" {
+			t.Errorf("expected 503 with Retry-After, got %d %v", rec.Code, rec.Header())
+		}
+		if gotErr == nil {
+			t.Error("expected hook to receive the store error")
+		}
+	})
+	t.Run("fail open skips error response", func(t *testing.T) {
+		l := limiter.New(&mockStoreError{}, limiter.WithConfigs(config.Clients), limiter.WithFailurePolicy(limiter.FailOpen))
+		called := false
+		mw := NewRateLimitMiddleware(l, logger, WithOnError(func(w http.ResponseWriter, r *http.Request, err error) {
+			called = true
+		}))
+
+		if rec := serve(mw); rec.Code != http.StatusOK || called {
+			t.Errorf("expected request to pass without error hook, got %d called=%v", rec.Code, called)
+		}
+	})
+}
+
+func TestRateLimitMiddleware_Drain(t *testing.T) {
+	l := limiter.NewLimiter(memory.NewMemoryStore(), config.Clients)
+	mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	slow := mw.Handler(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	})
+
+	inFlight := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Client-ID", "client-1")
+		slow(inFlight, req)
+		close(done)
+	}()
+	<-entered
+
+	mw.Drain(3 * time.Second)
+
+	rec := doRequest(mw, "GET", "/test", "client-1")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while draining, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "3" {
+		t.Fatalf("expected Retry-After 3, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	close(unblock)
+	<-done
+	if inFlight.Code != http.StatusOK {
+		t.Fatalf("expected in-flight request to complete, got %d", inFlight.Code)
+	}
+	if res, _ := l.Peek("client-1"); res.Count != 1 {
+		t.Fatalf("expected drained request to consume no quota, got count %d", res.Count)
+	}
+}
+
+func TestRateLimitMiddleware_ReasonHeader(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Burst: 1, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs)
+
+	for i := 0; i < 2; i++ {
+		rec := doRequest(mw, "GET", "/test", "c1")
+		if rec.Header().Get("X-RateLimit-Reason") != "" || rec.Header().Get("Retry-After") != "" {
+			t.Fatalf("expected no reason or Retry-After on allowed request, got %v", rec.Header())
+		}
+	}
+
+	rec := doRequest(mw, "GET", "/test", "c1")
+	if got := rec.Header().Get("X-RateLimit-Reason"); got != string(limiter.ReasonBurstExhausted) {
+		t.Fatalf("expected reason header %q, got %q", limiter.ReasonBurstExhausted, got)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("expected Retry-After 60, got %q", got)
+	}
+	var body map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if body["reason"] != string(limiter.ReasonBurstExhausted) {
+		t.Fatalf("expected reason in body, got %v", body)
+	}
+}
+
+func TestRateLimitMiddleware_RetryMessage(t *testing.T) {
+	const upgrade = "Free tier limit reached, upgrade at https://example.com/pricing"
+	cfgs := map[string]config.ClientConfig{
+		"free":    {Limit: 1, Window: time.Minute, RetryMessage: upgrade},
+		"premium": {Limit: 1, Window: time.Minute},
+	}
+
+	for _, format := range []ResponseFormat{FormatJSON, FormatProblemJSON} {
+		mw := newTestMiddleware(cfgs, WithResponseFormat(format))
+		field := map[ResponseFormat]string{FormatJSON: "error", FormatProblemJSON: "detail"}[format]
+
+		for client, want := range map[string]string{"free": upgrade, "premium": "Rate limit exceeded"} {
+			doRequest(mw, "GET", "/test", client)
+			rec := doRequest(mw, "GET", "/test", client)
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("%s: expected 429, got %d", client, rec.Code)
+			}
+
+			var body map[string]interface{}
+			json.NewDecoder(rec.Body).Decode(&body)
+			if body[field] != want {
+				t.Errorf("format %d, %s: expected %s %q, got %v", format, client, field, want, body[field])
+			}
+		}
+	}
+}
+
+func TestRateLimitMiddleware_LeakyBucketDelay(t *testing.T) {
+	// Drains one request per 50ms.
+	l := limiter.New(memory.NewMemoryStore(),
+		limiter.WithDefault(config.ClientConfig{Limit: 20, Window: time.Second}),
+		limiter.WithAlgorithm(limiter.AlgorithmLeakyBucket))
+	mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	start := time.Now()
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed >= 25*time.Millisecond {
+		t.Fatalf("expected an empty queue to serve at once, took %v", elapsed)
+	}
+
+	start = time.Now()
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Fatalf("expected the second request to wait its turn, took %v", elapsed)
+	}
+}
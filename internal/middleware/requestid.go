@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+const defaultRequestIDHeader = "X-Request-ID"
+
+// WithRequestID tags every decision log line with a correlation ID read from
+// header (X-Request-ID when empty), generating a UUID when the request has
+// none. The ID is echoed back in the same response header and set on the
+// request for downstream handlers.
+func WithRequestID(header string) Option {
+	return func(m *RateLimitMiddleware) {
+		if header == "" {
+			header = defaultRequestIDHeader
+		}
+		m.requestIDHeader = header
+	}
+}
+
+// requestLogger returns the logger for r's decision logs, carrying its
+// correlation ID when enabled.
+func (m *RateLimitMiddleware) requestLogger(w http.ResponseWriter, r *http.Request) *slog.Logger {
+	if m.requestIDHeader == "" {
+		return m.logger
+	}
+
+	id := r.Header.Get(m.requestIDHeader)
+	if id == "" {
+		id = newUUID()
+		r.Header.Set(m.requestIDHeader, id)
+	}
+	w.Header().Set(m.requestIDHeader, id)
+	return m.logger.With("request_id", id)
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestWithRequestID(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 1, Window: time.Minute}}
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	mw := NewRateLimitMiddleware(limiter.NewLimiter(memory.NewMemoryStore(), cfgs), logger, WithRequestID(""), WithAllowedLogLevel(slog.LevelInfo))
+
+	var seen string
+	handler := mw.Handler(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("X-Request-ID")
+	})
+	serve := func(id string) *httptest.ResponseRecorder {
+		logs.Reset()
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Client-ID", "c1")
+		if id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := serve("abc-123")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Request-ID") != "abc-123" {
+		t.Fatalf("expected allowed request to echo its ID, got %d %v", rec.Code, rec.Header())
+	}
+	if !strings.Contains(logs.String(), "request_id=abc-123") {
+		t.Fatalf("expected ID in allowed log, got %q", logs.String())
+	}
+
+	rec = serve("")
+	id := rec.Header().Get("X-Request-ID")
+	if rec.Code != http.StatusTooManyRequests || !uuidPattern.MatchString(id) {
+		t.Fatalf("expected denied request with generated UUID, got %d %q", rec.Code, id)
+	}
+	if !strings.Contains(logs.String(), "request_id="+id) {
+		t.Fatalf("expected generated ID in denied log, got %q", logs.String())
+	}
+	if seen != "abc-123" {
+		t.Fatalf("expected handler to see the request ID, got %q", seen)
+	}
+}
+
+func TestWithRequestIDCustomHeader(t *testing.T) {
+	mw := newTestMiddleware(nil, WithRequestID("X-Correlation-ID"))
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Correlation-ID", "corr-1")
+	rec := httptest.NewRecorder()
+	mw.Handler(func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+
+	if rec.Header().Get("X-Correlation-ID") != "corr-1" {
+		t.Fatalf("expected custom header echoed, got %v", rec.Header())
+	}
+}
+
+func TestRequestIDDisabledByDefault(t *testing.T) {
+	rec := doRequest(newTestMiddleware(nil), "GET", "/test", "c1")
+	if rec.Header().Get("X-Request-ID") != "" {
+		t.Fatalf("expected no request ID header, got %q", rec.Header().Get("X-Request-ID"))
+	}
+}
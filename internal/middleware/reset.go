@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// RequestResetter is implemented by limiters that can clear the budget a
+// request counts against. *limiter.Limiter implements it.
+type RequestResetter interface {
+	ResetRequest(req limiter.Request) error
+}
+
+// WithResetOnStatus clears the client's budget for the request's group
+// whenever next responds with one of statuses, e.g. 200 from a login handler,
+// so attempts start fresh after a successful authentication. Combine it with
+// WithCountFailuresOnly to lock out only repeated failures. The limiter must
+// implement RequestResetter; otherwise the reset is logged as an error.
+func WithResetOnStatus(statuses ...int) Option {
+	return func(m *RateLimitMiddleware) {
+		m.resetStatuses = make(map[int]bool, len(statuses))
+		for _, status := range statuses {
+			m.resetStatuses[status] = true
+		}
+	}
+}
+
+// resetOnStatus wraps next to reset the client's budget after a response
+// with one of the reset statuses.
+func (m *RateLimitMiddleware) resetOnStatus(logger *slog.Logger, clientID, group string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		next(sw, r)
+
+		if !m.resetStatuses[sw.status()] {
+			return
+		}
+		resetter, ok := m.limiter.(RequestResetter)
+		if !ok {
+			logger.Error("rate limiter cannot reset budgets", "client", clientID)
+			return
+		}
+		if err := resetter.ResetRequest(m.limiterRequest(r, clientID, group)); err != nil {
+			logger.Error("rate limit reset failed", "error", err, "client", clientID)
+			return
+		}
+		logger.Info("rate limit reset after response", "client", clientID, "group", group, "status", sw.status())
+	}
+}
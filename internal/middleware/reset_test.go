@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestWithResetOnStatus(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Minute}}
+	login := func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Password") != "hunter2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("welcome"))
+	}
+	mw := newTestMiddleware(cfgs,
+		WithPathGroups(map[string]string{"/login": "login"}),
+		WithCountFailuresOnly(http.StatusUnauthorized),
+		WithResetOnStatus(http.StatusOK),
+	)
+	attempt := func(password string) int {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.Header.Set("X-Client-ID", "c1")
+		req.Header.Set("X-Password", password)
+		rec := httptest.NewRecorder()
+		mw.Handler(login)(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := attempt("wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: expected 401, got %d", i+1, code)
+		}
+	}
+	if code := attempt("hunter2"); code != http.StatusOK {
+		t.Fatalf("expected the login to succeed, got %d", code)
+	}
+
+	// The success cleared both failures, so a full set of attempts is back.
+	for i := 0; i < 3; i++ {
+		if code := attempt("wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("failure %d after reset: expected 401, got %d", i+1, code)
+		}
+	}
+	if code := attempt("hunter2"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected lockout after 3 failures, got %d", code)
+	}
+}
+
+func TestResetOnStatusUnsupportedLimiter(t *testing.T) {
+	adapter := &AllowerAdapter{Allower: &tokenBucket{capacity: 1, rate: 1, now: time.Now, tokens: map[string]float64{}, last: map[string]time.Time{}}}
+	mw := NewRateLimitMiddleware(adapter, slog.New(slog.NewTextHandler(os.Stdout, nil)), WithResetOnStatus(http.StatusOK))
+
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the response unaffected by a failed reset, got %d", rec.Code)
+	}
+}
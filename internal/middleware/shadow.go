@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// ShadowMetrics receives the shadow limiter's decision for every request next
+// to the primary's, so disagreements can be graphed before a config change.
+type ShadowMetrics interface {
+	ShadowDecision(client string, primaryAllowed, shadowAllowed bool)
+}
+
+// WithShadowLimiter runs every limited request through l as well, logging
+// and reporting to metrics (which may be nil) what l would have decided,
+// while only the main limiter affects the response. l must use its own key
+// namespace (see limiter.WithNamespace) so its counts never add to the main
+// limiter's. Shadow errors are logged and otherwise ignored.
+func WithShadowLimiter(l Limiter, metrics ShadowMetrics) Option {
+	return func(m *RateLimitMiddleware) {
+		m.shadow = l
+		m.shadowMetrics = metrics
+	}
+}
+
+// runShadow asks the shadow limiter about the request the main limiter just
+// decided. The returned release must be called once the request is done.
+func (m *RateLimitMiddleware) runShadow(r *http.Request, logger *slog.Logger, clientID, group string, primary limiter.Result) func() {
+	if m.shadow == nil {
+		return func() {}
+	}
+
+	res, release, err := m.decide(m.shadow, r, clientID, group)
+	if err != nil {
+		logger.Error("shadow limiter error", "error", err, "client", clientID)
+		return release
+	}
+
+	if m.shadowMetrics != nil {
+		m.shadowMetrics.ShadowDecision(clientID, primary.Allowed, res.Allowed)
+	}
+	if res.Allowed != primary.Allowed {
+		logger.Info("shadow limiter disagrees",
+			"client", clientID,
+			"group", group,
+			"allowed", primary.Allowed,
+			"shadow_allowed", res.Allowed,
+			"shadow_reason", res.Reason,
+			"shadow_remaining", res.Remaining,
+			"path", r.URL.Path,
+		)
+	}
+	return release
+}
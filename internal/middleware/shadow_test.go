@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+type shadowRecord struct {
+	client                        string
+	primaryAllowed, shadowAllowed bool
+}
+
+type recordingShadowMetrics struct {
+	records []shadowRecord
+}
+
+func (m *recordingShadowMetrics) ShadowDecision(client string, primaryAllowed, shadowAllowed bool) {
+	m.records = append(m.records, shadowRecord{client, primaryAllowed, shadowAllowed})
+}
+
+func TestShadowLimiterRecordsWouldBeDenials(t *testing.T) {
+	store := memory.NewMemoryStore()
+	primary := limiter.New(store, limiter.WithDefault(config.ClientConfig{Limit: 5, Window: time.Minute}))
+	shadow := limiter.New(store,
+		limiter.WithDefault(config.ClientConfig{Limit: 2, Window: time.Minute}),
+		limiter.WithNamespace("shadow"),
+	)
+	metrics := &recordingShadowMetrics{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mw := NewRateLimitMiddleware(primary, logger, WithShadowLimiter(shadow, metrics))
+
+	for i := 0; i < 5; i++ {
+		rec := doRequest(mw, "GET", "/api/hello", "c1")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected the shadow not to affect responses, got %d", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("X-RateLimit-Limit"); got != "5" {
+			t.Fatalf("request %d: expected primary limit header 5, got %q", i+1, got)
+		}
+	}
+	if rec := doRequest(mw, "GET", "/api/hello", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the primary limit still enforced, got %d", rec.Code)
+	}
+
+	want := []shadowRecord{
+		{"c1", true, true},
+		{"c1", true, true},
+		{"c1", true, false},
+		{"c1", true, false},
+		{"c1", true, false},
+		{"c1", false, false},
+	}
+	if len(metrics.records) != len(want) {
+		t.Fatalf("expected %d shadow decisions, got %+v", len(want), metrics.records)
+	}
+	for i := range want {
+		if metrics.records[i] != want[i] {
+			t.Errorf("decision %d: expected %+v, got %+v", i+1, want[i], metrics.records[i])
+		}
+	}
+
+	if count, _, _ := store.Get(context.Background(), "rate:c1"); count != 6 {
+		t.Errorf("expected primary counter 6, got %d", count)
+	}
+	if count, _, _ := store.Get(context.Background(), "shadow:rate:c1"); count != 6 {
+		t.Errorf("expected shadow counter 6 in its own namespace, got %d", count)
+	}
+}
+
+func TestShadowLimiterErrorIgnored(t *testing.T) {
+	primary := limiter.New(memory.NewMemoryStore())
+	shadow := limiter.New(&mockStoreError{}, limiter.WithNamespace("shadow"))
+	metrics := &recordingShadowMetrics{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mw := NewRateLimitMiddleware(primary, logger, WithShadowLimiter(shadow, metrics))
+
+	if rec := doRequest(mw, "GET", "/api/hello", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected shadow errors not to affect responses, got %d", rec.Code)
+	}
+	if len(metrics.records) != 0 {
+		t.Errorf("expected no decision reported on shadow error, got %+v", metrics.records)
+	}
+}
+
+func TestShadowLimiterSkippedOnPrimaryError(t *testing.T) {
+	primary := limiter.New(&mockStoreError{})
+	shadow := limiter.New(memory.NewMemoryStore(), limiter.WithNamespace("shadow"))
+	metrics := &recordingShadowMetrics{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mw := NewRateLimitMiddleware(primary, logger,
+		WithShadowLimiter(shadow, metrics),
+		WithOnError(func(w http.ResponseWriter, r *http.Request, err error) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+
+	if rec := doRequest(mw, "GET", "/api/hello", "c1"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the primary error handler, got %d", rec.Code)
+	}
+	if len(metrics.records) != 0 {
+		t.Errorf("expected no shadow decision without a primary one, got %+v", metrics.records)
+	}
+}
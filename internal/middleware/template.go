@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// TemplateData is the decision a ResponseTemplate renders, e.g.
+// {{.Client}} or {{.RetryAfter}}.
+type TemplateData struct {
+	Client    string
+	Limit     int
+	Remaining int
+	// RetryAfter is the Retry-After value in seconds, 0 when the reset is
+	// unknown.
+	RetryAfter int64
+	// ResetAt is the window reset as a Unix time, 0 when unknown.
+	ResetAt int64
+	Reason  string
+	// Message is the client's retry message or the default one.
+	Message string
+}
+
+// templateFuncs are available to every ResponseTemplate on top of the
+// text/template builtins such as html and urlquery. Fields like .Client come
+// from the request, so JSON bodies should render them with json, e.g.
+// {"client":{{json .Client}}}.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// ResponseTemplate renders the body of denied responses from an
+// operator-supplied text/template.
+type ResponseTemplate struct {
+	tmpl        *template.Template
+	contentType string
+}
+
+// NewResponseTemplate parses text and executes it once against sample data,
+// so both syntax errors and references to unknown fields are reported here
+// rather than per request. An empty contentType means text/plain.
+func NewResponseTemplate(text, contentType string) (*ResponseTemplate, error) {
+	tmpl, err := template.New("429").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse response template: %w", err)
+	}
+	if err := tmpl.Execute(io.Discard, TemplateData{}); err != nil {
+		return nil, fmt.Errorf("check response template: %w", err)
+	}
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	return &ResponseTemplate{tmpl: tmpl, contentType: contentType}, nil
+}
+
+// WithResponseTemplate renders denied responses with t instead of the
+// ResponseFormat body. A nil t keeps the format.
+func WithResponseTemplate(t *ResponseTemplate) Option {
+	return func(m *RateLimitMiddleware) {
+		m.responseTemplate = t
+	}
+}
+
+// sendTemplate writes the templated 429, reporting false without writing
+// anything if the template fails so the caller can fall back.
+func (m *RateLimitMiddleware) sendTemplate(w http.ResponseWriter, res limiter.Result, clientID, message string) bool {
+	data := TemplateData{
+		Client:     clientID,
+		Limit:      max(res.Limit, 0),
+		Remaining:  max(res.Remaining, 0),
+		RetryAfter: res.RetryAfterSeconds(),
+		Reason:     string(res.Reason),
+		Message:    message,
+	}
+	if !res.ResetAt.IsZero() {
+		data.ResetAt = res.ResetAt.Unix()
+	}
+
+	var buf bytes.Buffer
+	if err := m.responseTemplate.tmpl.Execute(&buf, data); err != nil {
+		m.logger.Error("rendering rate limit response template", "error", err, "client", clientID)
+		return false
+	}
+	w.Header().Set("Content-Type", m.responseTemplate.contentType)
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write(buf.Bytes())
+	return true
+}
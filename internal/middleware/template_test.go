@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestWithResponseTemplate(t *testing.T) {
+	tmpl, err := NewResponseTemplate(
+		`{"code":"RATE_LIMITED","client":{{json .Client}},"limit":{{.Limit}},"retry_after":{{.RetryAfter}},"reason":{{json .Reason}},"message":{{json .Message}}}`,
+		"application/vnd.error+json",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfgs := map[string]config.ClientConfig{
+		"c1":         {Limit: 1, Window: time.Minute, RetryMessage: "Slow down"},
+		`c"3","x":"`: {Limit: 1, Window: time.Minute, RetryMessage: `say "please"`},
+	}
+	mw := newTestMiddleware(cfgs, WithResponseTemplate(tmpl))
+
+	doRequest(mw, "GET", "/test", "c1")
+	rec := doRequest(mw, "GET", "/test", "c1")
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/vnd.error+json" {
+		t.Fatalf("expected the template's content type, got %q", ct)
+	}
+	want := `{"code":"RATE_LIMITED","client":"c1","limit":1,"retry_after":60,"reason":"rate_limit","message":"Slow down"}`
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("unexpected body\n got: %s\nwant: %s", got, want)
+	}
+	if rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected the usual headers alongside the template, got %v", rec.Header())
+	}
+
+	doRequest(mw, "GET", "/test", `c"3","x":"`)
+	rec = doRequest(mw, "GET", "/test", `c"3","x":"`)
+	want = `{"code":"RATE_LIMITED","client":"c\"3\",\"x\":\"","limit":1,"retry_after":60,"reason":"rate_limit","message":"say \"please\""}`
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("expected request values escaped by json\n got: %s\nwant: %s", got, want)
+	}
+
+	if rec := doRequest(mw, "GET", "/test", "c2"); rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("expected allowed requests untouched, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNewResponseTemplateErrors(t *testing.T) {
+	for _, text := range []string{
+		`{{.Client`,
+		`{{.Unknown}}`,
+	} {
+		if _, err := NewResponseTemplate(text, ""); err == nil {
+			t.Errorf("expected an error for %q", text)
+		}
+	}
+
+	tmpl, err := NewResponseTemplate("retry in {{.RetryAfter}}s", "")
+	if err != nil || tmpl.contentType != "text/plain; charset=utf-8" {
+		t.Fatalf("expected a text/plain default, got %+v, %v", tmpl, err)
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+// WithViolationTolerance lets a client go n requests past its limit (burst
+// included) within a window before it is denied, logging each of those soft
+// violations at Warn. Only denials by the client's own counter are tolerated;
+// group, concurrency and load shedding denials stand. Algorithms that do not
+// count denials (limiter.Result.Uncounted) are never tolerated, since their
+// count never grows past the tolerance.
+func WithViolationTolerance(n int) Option {
+	return func(m *RateLimitMiddleware) {
+		m.tolerance = n
+	}
+}
+
+// tolerate reports whether the denied res is within the violation tolerance.
+func (m *RateLimitMiddleware) tolerate(r *http.Request, res limiter.Result) bool {
+	if m.tolerance <= 0 || res.Limit < 0 || res.Uncounted {
+		return false
+	}
+	if res.Reason != limiter.ReasonRateLimit && res.Reason != limiter.ReasonBurstExhausted {
+		return false
+	}
+	used := res.Count
+	if m.checkOnly[r.Method] || len(m.failureStatuses) > 0 {
+		// Checks report the count before this request.
+		used++
+	}
+	return used <= int64(res.Limit)+int64(m.tolerance)
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestWithViolationTolerance(t *testing.T) {
+	var buf bytes.Buffer
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Minute}}
+	mw := NewRateLimitMiddleware(limiter.NewLimiter(memory.NewMemoryStore(), cfgs),
+		slog.New(slog.NewTextHandler(&buf, nil)), WithViolationTolerance(2))
+
+	for i := 1; i <= 5; i++ {
+		rec := doRequest(mw, "GET", "/test", "c1")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+		}
+		if i > 3 && rec.Header().Get("Retry-After") != "" {
+			t.Fatalf("request %d: expected no Retry-After on a tolerated request", i)
+		}
+	}
+	if n := strings.Count(buf.String(), "within tolerance"); n != 2 {
+		t.Fatalf("expected 2 soft violations logged, got %d: %s", n, buf.String())
+	}
+
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected request limit+tolerance+1 to be denied, got %d", rec.Code)
+	}
+}
+
+func TestViolationToleranceCheckOnly(t *testing.T) {
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}}
+	mw := newTestMiddleware(cfgs, WithCheckOnlyMethods("GET"), WithViolationTolerance(1))
+
+	doRequest(mw, "POST", "/test", "c1")
+	doRequest(mw, "POST", "/test", "c1")
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected a check over the limit to be tolerated, got %d", rec.Code)
+	}
+	if rec := doRequest(mw, "POST", "/test", "c1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the counted request to use the tolerance, got %d", rec.Code)
+	}
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected a check past the tolerance to be denied, got %d", rec.Code)
+	}
+}
+
+func TestViolationToleranceIgnoresGroupLimits(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore(),
+		limiter.WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 10, Window: time.Minute}}),
+		limiter.WithGroup("team", config.ClientConfig{Limit: 1, Window: time.Minute}, "c1"),
+	)
+	mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), WithViolationTolerance(5))
+
+	doRequest(mw, "GET", "/test", "c1")
+	if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the group limit to deny regardless of tolerance, got %d", rec.Code)
+	}
+}
+
+func TestViolationToleranceByAlgorithm(t *testing.T) {
+	// Algorithms that only count admitted requests are never tolerated: their
+	// count stops at limit+1, so every retry would look like the first
+	// violation. The leaky bucket shares the token bucket's counting.
+	tests := []struct {
+		algorithm limiter.Algorithm
+		want      int
+	}{
+		{limiter.AlgorithmFixedWindow, 5},
+		{limiter.AlgorithmSlidingWindow, 5},
+		{limiter.AlgorithmSlidingLog, 3},
+		{limiter.AlgorithmTokenBucket, 3},
+		{limiter.AlgorithmGCRA, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm.String(), func(t *testing.T) {
+			l := limiter.New(memory.NewMemoryStore(),
+				limiter.WithConfigs(map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Minute}}),
+				limiter.WithAlgorithm(tt.algorithm))
+			mw := NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), WithViolationTolerance(2))
+
+			allowed := 0
+			for i := 0; i < 50; i++ {
+				if rec := doRequest(mw, "GET", "/test", "c1"); rec.Code == http.StatusOK {
+					allowed++
+				}
+			}
+			if allowed != tt.want {
+				t.Fatalf("expected %d of 50 requests allowed, got %d", tt.want, allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// trailerHeaders are the quota headers repeated as trailers.
+var trailerHeaders = []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
+
+// WithTrailers repeats X-RateLimit-Limit, -Remaining and -Reset as HTTP
+// trailers carrying the client's quota once the handler has finished, for
+// streaming responses whose headers were flushed long before. Apply it to the
+// middleware wrapping streaming handlers. Trailers are skipped for HTTP/1.0
+// clients, which cannot receive them, and dropped by net/http when the
+// handler sets a Content-Length; the regular headers are sent either way.
+func WithTrailers() Option {
+	return func(m *RateLimitMiddleware) {
+		m.trailers = true
+	}
+}
+
+func (m *RateLimitMiddleware) serveWithTrailers(w http.ResponseWriter, r *http.Request, logger *slog.Logger, clientID, group string, next http.HandlerFunc) {
+	if !r.ProtoAtLeast(1, 1) {
+		next(w, r)
+		return
+	}
+
+	for _, name := range trailerHeaders {
+		w.Header().Add("Trailer", name)
+	}
+
+	next(w, r)
+
+	res, err := m.limiter.CheckRequest(m.limiterRequest(r, clientID, group))
+	if err != nil {
+		logger.Error("rate limiter error", "error", err, "client", clientID)
+		return
+	}
+	// The declared names still hold the values sent as headers; net/http
+	// sends whatever they hold once the handler returns, so overwrite them
+	// rather than adding TrailerPrefix copies, which would send both.
+	headers := res.Headers()
+	for _, name := range trailerHeaders {
+		if v, ok := headers[name]; ok {
+			w.Header().Set(name, v)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestWithTrailers(t *testing.T) {
+	l := limiter.NewLimiter(memory.NewMemoryStore(), map[string]config.ClientConfig{"c1": {Limit: 5, Window: time.Minute}})
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mw := NewRateLimitMiddleware(l, logger, WithTrailers())
+
+	srv := httptest.NewServer(mw.Handler(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "chunk 1\n")
+		w.(http.Flusher).Flush()
+		// Quota spent elsewhere while the stream is open shows in the trailers.
+		l.Allow("c1")
+		io.WriteString(w, "chunk 2\n")
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("X-Client-ID", "c1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("X-RateLimit-Remaining"); got != "4" {
+		t.Errorf("expected header remaining 4, got %q", got)
+	}
+	if _, ok := resp.Trailer["X-Ratelimit-Remaining"]; !ok {
+		t.Fatalf("expected X-RateLimit-Remaining to be declared as a trailer, got %v", resp.Trailer)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "chunk 1\nchunk 2\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	for _, name := range trailerHeaders {
+		if got := resp.Trailer.Values(name); len(got) != 1 {
+			t.Errorf("expected one %s trailer, got %v", name, got)
+		}
+	}
+	if got := resp.Trailer.Get("X-RateLimit-Remaining"); got != "3" {
+		t.Errorf("expected trailer remaining 3 after the body, got %q", got)
+	}
+	if got := resp.Trailer.Get("X-RateLimit-Limit"); got != "5" {
+		t.Errorf("expected trailer limit 5, got %q", got)
+	}
+	if resp.Trailer.Get("X-RateLimit-Reset") == "" {
+		t.Error("expected a reset trailer")
+	}
+}
+
+func TestWithTrailersHTTP10(t *testing.T) {
+	l := limiter.NewLimiter(memory.NewMemoryStore(), map[string]config.ClientConfig{"c1": {Limit: 5, Window: time.Minute}})
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mw := NewRateLimitMiddleware(l, logger, WithTrailers())
+
+	req := httptest.NewRequest("GET", "/stream", nil)
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/1.0", 1, 0
+	req.Header.Set("X-Client-ID", "c1")
+	rec := httptest.NewRecorder()
+	mw.Handler(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "body")
+	})(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "body" {
+		t.Fatalf("expected the response served normally, got %d %q", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Values("Trailer"); len(got) != 0 {
+		t.Errorf("expected no trailers declared for HTTP/1.0, got %v", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "4" {
+		t.Errorf("expected regular headers still set, got remaining %q", got)
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+)
+
+const DefaultUserAgentClass = "default"
+
+// UserAgentRule assigns Class to requests whose User-Agent matches Pattern.
+type UserAgentRule struct {
+	Pattern *regexp.Regexp
+	Class   string
+}
+
+// WithUserAgentClasses classifies requests by User-Agent using the first
+// matching rule; unmatched agents get DefaultUserAgentClass. The class becomes
+// part of the limiter key and selects the limiter's per-class defaults.
+func WithUserAgentClasses(rules []UserAgentRule) Option {
+	return func(m *RateLimitMiddleware) {
+		m.uaRules = rules
+	}
+}
+
+func (m *RateLimitMiddleware) getUserAgentClass(r *http.Request) string {
+	if len(m.uaRules) == 0 {
+		return ""
+	}
+
+	ua := r.UserAgent()
+	for _, rule := range m.uaRules {
+		if rule.Pattern.MatchString(ua) {
+			return rule.Class
+		}
+	}
+	return DefaultUserAgentClass
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+var testUserAgentRules = []UserAgentRule{
+	{Pattern: regexp.MustCompile(`(?i)bot|crawler|spider`), Class: "bot"},
+	{Pattern: regexp.MustCompile(`(?i)curl|python-requests`), Class: "script"},
+}
+
+func TestGetUserAgentClass(t *testing.T) {
+	mw := newTestMiddleware(nil, WithUserAgentClasses(testUserAgentRules))
+
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{"Mozilla/5.0 (compatible; Googlebot/2.1)", "bot"},
+		{"curl/8.4.0", "script"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) Firefox/120.0", DefaultUserAgentClass},
+		{"", DefaultUserAgentClass},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("User-Agent", tt.ua)
+		if got := mw.getUserAgentClass(req); got != tt.want {
+			t.Errorf("class for %q = %q, want %q", tt.ua, got, tt.want)
+		}
+	}
+
+	if got := newTestMiddleware(nil).getUserAgentClass(httptest.NewRequest("GET", "/", nil)); got != "" {
+		t.Errorf("expected no class without rules, got %q", got)
+	}
+}
+
+func TestHandlerUserAgentLimits(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore(),
+		limiter.WithDefault(config.ClientConfig{Limit: 5, Window: time.Minute}),
+		limiter.WithClassDefaults(map[string]config.ClientConfig{
+			"bot": {Limit: 1, Window: time.Minute},
+		}),
+	)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mw := NewRateLimitMiddleware(l, logger, WithUserAgentClasses(testUserAgentRules))
+
+	send := func(ua string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Client-ID", "shared")
+		req.Header.Set("User-Agent", ua)
+		rec := httptest.NewRecorder()
+		mw.Handler(func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+		return rec
+	}
+
+	bot := "Mozilla/5.0 (compatible; Googlebot/2.1)"
+	browser := "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) Safari/605.1.15"
+
+	if rec := send(bot); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("expected bot to get the stricter limit, got %d limit %s", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	if rec := send(bot); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected second bot request denied, got %d", rec.Code)
+	}
+
+	for i := 0; i < 5; i++ {
+		rec := send(browser)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("browser request %d: expected 200, got %d", i, rec.Code)
+		}
+		if rec.Header().Get("X-RateLimit-Limit") != "5" {
+			t.Fatalf("expected browser to get the normal limit, got %s", rec.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package ratelimittest provides helpers for tests that need a working rate
+// limiter or middleware without a real store or logger.
+package ratelimittest
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/middleware"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// Logger discards everything written to it.
+var Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// NewTestLimiter returns a memory-backed limiter for cfgs that logs nowhere.
+// Extra options are applied after the defaults.
+func NewTestLimiter(cfgs map[string]config.ClientConfig, opts ...limiter.Option) *limiter.Limiter {
+	opts = append([]limiter.Option{limiter.WithConfigs(cfgs), limiter.WithLogger(Logger)}, opts...)
+	return limiter.New(memory.NewMemoryStore(), opts...)
+}
+
+// NewTestMiddleware returns a middleware over NewTestLimiter(cfgs).
+func NewTestMiddleware(cfgs map[string]config.ClientConfig, opts ...middleware.Option) *middleware.RateLimitMiddleware {
+	return middleware.NewRateLimitMiddleware(NewTestLimiter(cfgs), Logger, opts...)
+}
+
+// OK is a handler that always responds 200.
+func OK(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// Do sends a GET for path as clientID through mw in front of OK and returns
+// the recorded response.
+func Do(mw *middleware.RateLimitMiddleware, clientID, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if clientID != "" {
+		req.Header.Set("X-Client-ID", clientID)
+	}
+	rec := httptest.NewRecorder()
+	mw.Handler(OK)(rec, req)
+	return rec
+}
@@ -0,0 +1,43 @@
+package ratelimittest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/middleware"
+)
+
+func TestNewTestMiddleware(t *testing.T) {
+	mw := NewTestMiddleware(map[string]config.ClientConfig{
+		"c1": {Limit: 2, Window: time.Minute},
+	}, middleware.WithPathGroups(map[string]string{"/reports/": "reports"}))
+
+	for i := 0; i < 2; i++ {
+		if rec := Do(mw, "c1", "/test"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+	rec := Do(mw, "c1", "/test")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("expected remaining 0, got %q", rec.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	if rec := Do(mw, "c1", "/reports/daily"); rec.Code != http.StatusOK {
+		t.Fatalf("expected middleware options applied, got %d", rec.Code)
+	}
+}
+
+func TestNewTestLimiter(t *testing.T) {
+	l := NewTestLimiter(nil)
+	if got := l.ConfigFor("anyone"); got != config.DefaultConfig {
+		t.Fatalf("expected default config, got %+v", got)
+	}
+	if res, err := l.AllowResult("anyone"); err != nil || !res.Allowed {
+		t.Fatalf("expected allowed, got %+v err=%v", res, err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package simulator replays a traffic pattern against a candidate config to
+// show how the limiter would treat it, without touching live counters.
+package simulator
+
+import (
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// Pattern is evenly spaced traffic from a single client.
+type Pattern struct {
+	Requests int
+	Spacing  time.Duration
+}
+
+// Summary counts the decisions for a pattern. FirstDenialAt is the offset of
+// the first denied request from the first request, and is only meaningful
+// when Denied is positive.
+type Summary struct {
+	Allowed       int
+	Denied        int
+	FirstDenialAt time.Duration
+}
+
+// start is an arbitrary fixed instant so runs are reproducible.
+var start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Run replays p against cfg using a private in-memory store on a simulated
+// clock, so it finishes instantly regardless of the pattern's duration.
+func Run(cfg config.ClientConfig, p Pattern) Summary {
+	now := start
+	clock := func() time.Time { return now }
+
+	store := memory.NewMemoryStore(memory.WithClock(clock))
+	defer store.Close()
+	l := limiter.New(store, limiter.WithDefault(cfg), limiter.WithClock(clock))
+
+	var s Summary
+	for i := 0; i < p.Requests; i++ {
+		now = start.Add(time.Duration(i) * p.Spacing)
+		if ok, _, _, _ := l.Allow("simulated"); ok {
+			s.Allowed++
+			continue
+		}
+		if s.Denied == 0 {
+			s.FirstDenialAt = now.Sub(start)
+		}
+		s.Denied++
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.ClientConfig
+		pattern Pattern
+		want    Summary
+	}{
+		{
+			// The first window spans 0s..1s inclusive and holds 11 requests,
+			// the second starts at 1.1s and holds the remaining 9.
+			name:    "two windows",
+			cfg:     config.ClientConfig{Limit: 5, Window: time.Second},
+			pattern: Pattern{Requests: 20, Spacing: 100 * time.Millisecond},
+			want:    Summary{Allowed: 10, Denied: 10, FirstDenialAt: 500 * time.Millisecond},
+		},
+		{
+			name:    "under the limit",
+			cfg:     config.ClientConfig{Limit: 10, Window: time.Minute},
+			pattern: Pattern{Requests: 10, Spacing: time.Second},
+			want:    Summary{Allowed: 10},
+		},
+		{
+			name:    "burst at once",
+			cfg:     config.ClientConfig{Limit: 3, Burst: 2, Window: time.Minute},
+			pattern: Pattern{Requests: 8},
+			want:    Summary{Allowed: 5, Denied: 3},
+		},
+		{
+			name:    "spacing wider than the window",
+			cfg:     config.ClientConfig{Limit: 1, Window: time.Second},
+			pattern: Pattern{Requests: 5, Spacing: 2 * time.Second},
+			want:    Summary{Allowed: 5},
+		},
+		{
+			name:    "blocked",
+			cfg:     config.ClientConfig{Limit: 0, Window: time.Second},
+			pattern: Pattern{Requests: 3, Spacing: time.Second},
+			want:    Summary{Denied: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Run(tt.cfg, tt.pattern); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRunIsIsolated(t *testing.T) {
+	cfg := config.ClientConfig{Limit: 2, Window: time.Minute}
+	p := Pattern{Requests: 3, Spacing: time.Millisecond}
+	if a, b := Run(cfg, p), Run(cfg, p); a != b {
+		t.Fatalf("expected repeated runs to start fresh, got %+v and %+v", a, b)
+	}
+}
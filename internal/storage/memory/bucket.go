@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// tokenBucket holds the tokens of one bucket as of updated.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	ttl     time.Duration
+}
+
+// refill adds the tokens gained since the last update, up to capacity. Tokens
+// are kept to a millionth so refills summed over many updates still land on
+// whole tokens.
+func (b *tokenBucket) refill(now time.Time, capacity int64, rate float64) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		tokens := math.Round((b.tokens+elapsed.Seconds()*rate)*1e6) / 1e6
+		b.tokens = min(float64(capacity), tokens)
+		b.updated = now
+	}
+}
+
+// end is when the bucket is full again, as far as its ttl tells.
+func (b *tokenBucket) end() time.Time {
+	return b.updated.Add(b.ttl)
+}
+
+// TakeTokens implements limiter.BucketStore. Like logs, buckets live apart
+// from counters, share MaxKeys with them and are swept once idle for their
+// ttl.
+func (s *MemoryStore) TakeTokens(ctx context.Context, key string, n, capacity int64, rate float64, ttl time.Duration) (float64, bool, error) {
+	now := s.now()
+	var reclaimed, evicted int
+	defer func() { s.report(reclaimed, evicted) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		reclaimed, evicted = s.makeRoomLocked(now)
+		b = &tokenBucket{tokens: float64(capacity), updated: now}
+		s.buckets[key] = b
+	}
+	b.ttl = ttl
+	b.refill(now, capacity, rate)
+
+	if b.tokens < float64(n) {
+		return b.tokens, false, nil
+	}
+	b.tokens -= float64(n)
+	return b.tokens, true, nil
+}
+
+// PeekTokens implements limiter.BucketStore.
+func (s *MemoryStore) PeekTokens(ctx context.Context, key string, capacity int64, rate float64) (float64, error) {
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		return float64(capacity), nil
+	}
+	peek := *b
+	peek.refill(now, capacity, rate)
+	return peek.tokens, nil
+}
+
+func (s *MemoryStore) removeIdleBucketsLocked(now time.Time) int {
+	removed := 0
+	for k, b := range s.buckets {
+		if !b.end().After(now) {
+			delete(s.buckets, k)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"time"
+)
+
+// ArriveGCRA implements limiter.GCRAStore. TATs live apart from counters,
+// share MaxKeys with them and are swept once they have passed.
+func (s *MemoryStore) ArriveGCRA(ctx context.Context, key string, n int64, interval, tolerance time.Duration) (time.Time, bool, error) {
+	now := s.now()
+	var reclaimed, evicted int
+	defer func() { s.report(reclaimed, evicted) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tat, ok := s.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(time.Duration(n) * interval)
+	if next.Sub(now) > tolerance {
+		return tat, false, nil
+	}
+	if !ok {
+		reclaimed, evicted = s.makeRoomLocked(now)
+	}
+	s.tats[key] = next
+	return next, true, nil
+}
+
+// GetTAT implements limiter.GCRAStore.
+func (s *MemoryStore) GetTAT(ctx context.Context, key string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tats[key], nil
+}
+
+func (s *MemoryStore) removePassedTATsLocked(now time.Time) int {
+	removed := 0
+	for k, tat := range s.tats {
+		if !tat.After(now) {
+			delete(s.tats, k)
+			removed++
+		}
+	}
+	return removed
+}
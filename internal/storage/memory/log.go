@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"time"
+)
+
+// requestLog holds the request times of one sliding log, oldest first.
+type requestLog struct {
+	times  []time.Time
+	window time.Duration
+}
+
+// trim drops the times that left the window ending at now.
+func (l *requestLog) trim(now time.Time) {
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(l.times) && !l.times[i].After(cutoff) {
+		i++
+	}
+	l.times = l.times[i:]
+}
+
+// end is when the log's last request leaves its window.
+func (l *requestLog) end() time.Time {
+	if len(l.times) == 0 {
+		return time.Time{}
+	}
+	return l.times[len(l.times)-1].Add(l.window)
+}
+
+func (l *requestLog) oldest() time.Time {
+	if len(l.times) == 0 {
+		return time.Time{}
+	}
+	return l.times[0]
+}
+
+// AppendLog implements limiter.LogStore. Logs live apart from counters but
+// share MaxKeys with them; the sweep drops them once their window is empty.
+func (s *MemoryStore) AppendLog(ctx context.Context, key string, n, limit int64, window time.Duration) (int64, time.Time, bool, error) {
+	now := s.now()
+	var reclaimed, evicted int
+	defer func() { s.report(reclaimed, evicted) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.logs[key]
+	if !ok {
+		reclaimed, evicted = s.makeRoomLocked(now)
+		l = &requestLog{}
+		s.logs[key] = l
+	}
+	l.window = window
+	l.trim(now)
+
+	count := int64(len(l.times))
+	if count+n > limit {
+		return count, l.oldest(), false, nil
+	}
+	for i := int64(0); i < n; i++ {
+		l.times = append(l.times, now)
+	}
+	return count + n, l.oldest(), true, nil
+}
+
+// CountLog implements limiter.LogStore.
+func (s *MemoryStore) CountLog(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	cutoff := s.now().Add(-window)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.logs[key]
+	if !ok {
+		return 0, time.Time{}, nil
+	}
+	var count int64
+	var oldest time.Time
+	for _, t := range l.times {
+		if t.After(cutoff) {
+			if count == 0 {
+				oldest = t
+			}
+			count++
+		}
+	}
+	return count, oldest, nil
+}
+
+func (s *MemoryStore) removeExpiredLogsLocked(now time.Time) int {
+	removed := 0
+	for k, l := range s.logs {
+		if !l.end().After(now) {
+			delete(s.logs, k)
+			removed++
+		}
+	}
+	return removed
+}
@@ -1,73 +1,461 @@
 package memory
 
 import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
 type Entry struct {
-	Count  int64
-	Expiry time.Time
+	Count       int64
+	Expiry      time.Time
+	WindowStart time.Time
 }
 
 type MemoryStore struct {
-	mu sync.RWMutex
-	m  map[string]*Entry
+	mu      sync.RWMutex
+	m       map[string]*Entry
+	logs    map[string]*requestLog
+	buckets map[string]*tokenBucket
+	tats    map[string]time.Time
+	sliding bool
+	rolling bool
+	aligned bool
+	stagger bool
+	maxKeys int
+	metrics Metrics
+	now     func() time.Time
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// Metrics receives store housekeeping events, separating pressure-driven
+// evictions from normal expiry.
+type Metrics interface {
+	// KeysEvicted reports keys dropped early to stay under the MaxKeys cap.
+	KeysEvicted(n int)
+	// KeysReclaimed reports expired keys removed by the background sweep.
+	KeysReclaimed(n int)
+}
+
+type Option func(*MemoryStore)
+
+// WithMaxKeys caps the number of tracked keys, counting the logs, buckets
+// and TATs of other algorithms too. When full, a new key evicts the one
+// closest to expiry among evictionSample keys of each kind picked at random,
+// so expired keys usually go first. Making room takes constant time, but the
+// evicted key is only approximately the closest to expiry.
+func WithMaxKeys(n int) Option {
+	return func(s *MemoryStore) {
+		s.maxKeys = n
+	}
+}
+
+// WithMetrics reports evictions and sweeps to m. Calls happen outside the
+// store's lock.
+func WithMetrics(m Metrics) Option {
+	return func(s *MemoryStore) {
+		s.metrics = m
+	}
+}
+
+// WithSlidingExpiry replaces the hard reset at the end of a window with a
+// proportional decay: a window that ended a fraction f of its length ago
+// carries (1-f) of its count into the next one. This smooths the burst
+// allowed at window boundaries, approximating a sliding window.
+func WithSlidingExpiry() Option {
+	return func(s *MemoryStore) {
+		s.sliding = true
+	}
+}
+
+// WithRollingExpiry pushes a key's expiry to now+ttl on every increment, so
+// the window only ends after ttl without requests, like a session timeout.
+// The entry's WindowStart moves with it. By default the expiry is fixed at
+// first request plus ttl.
+func WithRollingExpiry() Option {
+	return func(s *MemoryStore) {
+		s.rolling = true
+	}
+}
+
+// WithAlignedWindows starts each new window at now truncated to the ttl
+// instead of at the first request, so concurrent creators of a window, and
+// stores on other instances, agree on its start and reset time. Windows stay
+// aligned only until WithRollingExpiry moves them.
+func WithAlignedWindows() Option {
+	return func(s *MemoryStore) {
+		s.aligned = true
+	}
 }
 
-func NewMemoryStore() *MemoryStore {
-	s := &MemoryStore{m: map[string]*Entry{}}
+// WithStaggeredWindows aligns windows like WithAlignedWindows but shifts each
+// key's boundaries by an offset derived from hashing the key, so clients
+// reset at different phases instead of all at once across a fleet. The offset
+// is stable, so every instance computes the same window for a key.
+func WithStaggeredWindows() Option {
+	return func(s *MemoryStore) {
+		s.aligned = true
+		s.stagger = true
+	}
+}
+
+// WithClock replaces the store's clock, e.g. to replay traffic at simulated
+// times. Its times are converted to UTC.
+func WithClock(now func() time.Time) Option {
+	return func(s *MemoryStore) {
+		s.now = func() time.Time { return now().UTC() }
+	}
+}
+
+func NewMemoryStore(opts ...Option) *MemoryStore {
+	s := &MemoryStore{
+		m:       map[string]*Entry{},
+		logs:    map[string]*requestLog{},
+		buckets: map[string]*tokenBucket{},
+		tats:    map[string]time.Time{},
+		now:     func() time.Time { return time.Now().UTC() },
+		stop:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
 	go s.cleanupLoop()
 
 	return s
 }
 
+// Close stops the background sweep. The store keeps working, but expired keys
+// are then only dropped when MaxKeys makes room.
+func (s *MemoryStore) Close() {
+	s.closeOnce.Do(func() { close(s.stop) })
+}
+
 func (s *MemoryStore) cleanupLoop() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
-	for range ticker.C {
-		now := time.Now()
-		s.mu.Lock()
-		for k, e := range s.m {
-			if e == nil {
-				delete(s.m, k)
-				continue
-			}
-			if e.Expiry.Before(now) {
-				delete(s.m, k)
-			}
+	for {
+		select {
+		case <-ticker.C:
+			s.sweep()
+		case <-s.stop:
+			return
 		}
-		s.mu.Unlock()
 	}
 }
 
-func (s *MemoryStore) Increment(key string, ttl time.Duration) (int64, time.Time, error) {
-	now := time.Now()
+func (s *MemoryStore) sweep() {
+	now := s.now()
+	s.mu.Lock()
+	reclaimed := s.removeExpiredLocked(now) + s.removeExpiredLogsLocked(now) +
+		s.removeIdleBucketsLocked(now) + s.removePassedTATsLocked(now)
+	s.mu.Unlock()
+
+	s.report(reclaimed, 0)
+}
+
+func (s *MemoryStore) removeExpiredLocked(now time.Time) int {
+	removed := 0
+	for k, e := range s.m {
+		if e == nil || (s.carried(e, now) == 0 && e.Expiry.Before(now)) {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed
+}
+
+// evictionSample is how many keys of each kind makeRoomLocked compares per
+// eviction.
+const evictionSample = 5
+
+// lenLocked counts every tracked key, live or not.
+func (s *MemoryStore) lenLocked() int {
+	return len(s.m) + len(s.logs) + len(s.buckets) + len(s.tats)
+}
+
+// makeRoomLocked frees a slot for a new key when the store is at its cap,
+// evicting the key closest to expiry in a random sample. Expired keys it
+// drops count as reclaimed.
+func (s *MemoryStore) makeRoomLocked(now time.Time) (reclaimed, evicted int) {
+	if s.maxKeys <= 0 {
+		return 0, 0
+	}
+	for s.lenLocked() >= s.maxKeys {
+		key, end := s.evictionCandidateLocked()
+		s.deleteLocked(key)
+		if end.Before(now) {
+			reclaimed++
+		} else {
+			evicted++
+		}
+	}
+	return reclaimed, evicted
+}
+
+// evictionCandidateLocked returns the key whose state ends first among up to
+// evictionSample keys of each kind.
+func (s *MemoryStore) evictionCandidateLocked() (key string, end time.Time) {
+	found := false
+	consider := func(k string, e time.Time) {
+		if !found || e.Before(end) {
+			key, end, found = k, e, true
+		}
+	}
+	sample(s.m, func(k string, e *Entry) {
+		if e == nil {
+			consider(k, time.Time{})
+			return
+		}
+		consider(k, e.Expiry)
+	})
+	sample(s.logs, func(k string, l *requestLog) { consider(k, l.end()) })
+	sample(s.buckets, func(k string, b *tokenBucket) { consider(k, b.end()) })
+	sample(s.tats, func(k string, tat time.Time) { consider(k, tat) })
+	return key, end
+}
+
+// sample calls fn for up to evictionSample entries of m. Map iteration starts
+// at a random key, which makes them a random sample.
+func sample[V any](m map[string]V, fn func(string, V)) {
+	n := 0
+	for k, v := range m {
+		fn(k, v)
+		if n++; n == evictionSample {
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) report(reclaimed, evicted int) {
+	if s.metrics == nil {
+		return
+	}
+	if reclaimed > 0 {
+		s.metrics.KeysReclaimed(reclaimed)
+	}
+	if evicted > 0 {
+		s.metrics.KeysEvicted(evicted)
+	}
+}
+
+// carried is the part of an expired entry's count that still applies at now;
+// always 0 in hard-expiry mode.
+func (s *MemoryStore) carried(e *Entry, now time.Time) int64 {
+	if !s.sliding || !e.Expiry.Before(now) {
+		return 0
+	}
+	window := e.Expiry.Sub(e.WindowStart)
+	past := now.Sub(e.Expiry)
+	if window <= 0 || past >= window {
+		return 0
+	}
+	remaining := float64(window-past) / float64(window)
+	return int64(float64(atomic.LoadInt64(&e.Count)) * remaining)
+}
+
+// Increment adds one to key. Like every method here it ignores ctx: the
+// store never blocks on I/O.
+func (s *MemoryStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	return s.IncrementBy(ctx, key, 1, ttl)
+}
+
+func (s *MemoryStore) IncrementBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, error) {
+	count, e, _ := s.increment(key, n, ttl)
+	return count, e.Expiry, nil
+}
+
+// IncrementWindow is like IncrementBy but reports when the window started and
+// whether key was absent. An expired entry restarted in place was not.
+func (s *MemoryStore) IncrementWindow(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, bool, error) {
+	count, e, created := s.increment(key, n, ttl)
+	return count, e.WindowStart, created, nil
+}
+
+// increment returns the new count, a copy of the entry taken under the lock,
+// since rolling expiry mutates live entries, and whether key was absent.
+func (s *MemoryStore) increment(key string, n int64, ttl time.Duration) (int64, Entry, bool) {
+	now := s.now()
+	var reclaimed, evicted int
+	defer func() { s.report(reclaimed, evicted) }()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	e, ok := s.m[key]
 	if !ok || e == nil || e.Expiry.Before(now) { //create new entry
+		created := !ok || e == nil
+		var carried int64
+		if created {
+			reclaimed, evicted = s.makeRoomLocked(now)
+		} else {
+			carried = s.carried(e, now)
+		}
 
-		e = &Entry{Count: 1, Expiry: now.Add(ttl)}
+		start := s.windowStart(key, now, ttl)
+		e = &Entry{Count: carried + n, Expiry: start.Add(ttl), WindowStart: start}
 		s.m[key] = e
 
-		return 1, e.Expiry, nil
+		return e.Count, *e, created
+	}
+
+	if s.rolling {
+		e.Expiry, e.WindowStart = now.Add(ttl), now
+	}
+	newv := atomic.AddInt64(&e.Count, n)
+	return newv, *e, false
+}
+
+// Delete removes key, its request log, token bucket and GCRA TAT. Deleting a
+// missing key is a no-op.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteLocked(key)
+	return nil
+}
+
+func (s *MemoryStore) deleteLocked(key string) {
+	delete(s.m, key)
+	delete(s.logs, key)
+	delete(s.buckets, key)
+	delete(s.tats, key)
+}
+
+// ResetKey sets key's count to 0 in a fresh window of ttl starting now.
+func (s *MemoryStore) ResetKey(ctx context.Context, key string, ttl time.Duration) error {
+	now := s.now()
+	var reclaimed, evicted int
+	defer func() { s.report(reclaimed, evicted) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.m[key]; !ok {
+		reclaimed, evicted = s.makeRoomLocked(now)
 	}
+	s.m[key] = &Entry{Expiry: now.Add(ttl), WindowStart: now}
+	return nil
+}
 
-	newv := atomic.AddInt64(&e.Count, 1)
-	return newv, e.Expiry, nil
+// SetIfAbsent creates key with count in a new window of ttl unless a live
+// entry exists. Expired entries count as absent, but with sliding expiry an
+// entry whose count still carries over is live.
+func (s *MemoryStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	now := s.now()
+	start := s.windowStart(key, now, ttl)
+	return s.createIfAbsent(key, count, now, start, start.Add(ttl)), nil
 }
 
-func (s *MemoryStore) Get(key string) (int64, time.Time, error) {
-	now := time.Now()
+// Restore is like SetIfAbsent but the window ends at expiry, as it did in the
+// store the count came from, rather than on a boundary of this store's
+// windows. The window is taken to start now.
+func (s *MemoryStore) Restore(ctx context.Context, key string, count int64, expiry time.Time) (bool, error) {
+	now := s.now()
+	if !expiry.After(now) {
+		return false, nil
+	}
+	return s.createIfAbsent(key, count, now, now, expiry.UTC()), nil
+}
+
+func (s *MemoryStore) createIfAbsent(key string, count int64, now, start, expiry time.Time) bool {
+	var reclaimed, evicted int
+	defer func() { s.report(reclaimed, evicted) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.m[key]
+	if ok && e != nil && (!e.Expiry.Before(now) || s.carried(e, now) > 0) {
+		return false
+	}
+	if !ok {
+		reclaimed, evicted = s.makeRoomLocked(now)
+	}
+
+	s.m[key] = &Entry{Count: count, Expiry: expiry, WindowStart: start}
+	return true
+}
+
+// windowStart is when a window created at now begins: now itself unless
+// windows are aligned, in which case it is the latest boundary, shifted by
+// the key's offset when staggered.
+func (s *MemoryStore) windowStart(key string, now time.Time, ttl time.Duration) time.Time {
+	if !s.aligned || ttl <= 0 {
+		return now
+	}
+	var offset time.Duration
+	if s.stagger {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		offset = time.Duration(h.Sum64() % uint64(ttl))
+	}
+	return now.Add(-offset).Truncate(ttl).Add(offset)
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	now := s.now()
 	s.mu.RLock()
+	defer s.mu.RUnlock()
 	e, ok := s.m[key]
-	s.mu.RUnlock()
-	if !ok || e == nil || e.Expiry.Before(now) {
+	if !ok || e == nil {
+		return 0, time.Time{}, nil
+	}
+	if e.Expiry.Before(now) {
+		if carried := s.carried(e, now); carried > 0 {
+			return carried, now.Add(e.Expiry.Sub(e.WindowStart)), nil
+		}
 		return 0, time.Time{}, nil
 	}
 
 	return atomic.LoadInt64(&e.Count), e.Expiry, nil
 }
+
+// Len returns the number of keys holding a live window, log, bucket or TAT,
+// not counting expired state the sweep has yet to remove.
+func (s *MemoryStore) Len() int {
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.liveKeysLocked(now, ""))
+}
+
+// Keys returns the sorted keys starting with prefix that hold a live window,
+// log, bucket or TAT.
+func (s *MemoryStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := s.liveKeysLocked(now, prefix)
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// liveKeysLocked returns the distinct keys starting with prefix that hold
+// live state of any kind, in no particular order.
+func (s *MemoryStore) liveKeysLocked(now time.Time, prefix string) []string {
+	var keys []string
+	seen := map[string]bool{}
+	add := func(key string, live bool) {
+		if live && !seen[key] && strings.HasPrefix(key, prefix) {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for key, e := range s.m {
+		add(key, e != nil && (!e.Expiry.Before(now) || s.carried(e, now) > 0))
+	}
+	for key, l := range s.logs {
+		add(key, l.end().After(now))
+	}
+	for key, b := range s.buckets {
+		add(key, b.end().After(now))
+	}
+	for key, tat := range s.tats {
+		add(key, tat.After(now))
+	}
+	return keys
+}
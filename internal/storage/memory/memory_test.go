@@ -0,0 +1,563 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newStoreAt(now *time.Time, opts ...Option) *MemoryStore {
+	return NewMemoryStore(append(opts, WithClock(func() time.Time { return *now }))...)
+}
+
+// burstAfterBoundary fills a window of limit requests, steps just past its end
+// and reports how many further requests fit under limit.
+func burstAfterBoundary(s *MemoryStore, now *time.Time, limit int64) int64 {
+	const window = 10 * time.Second
+	for i := int64(0); i < limit; i++ {
+		s.Increment(context.Background(), "k", window)
+	}
+	*now = now.Add(window + window/10)
+
+	var admitted int64
+	for {
+		count, _, _ := s.Increment(context.Background(), "k", window)
+		if count > limit {
+			return admitted
+		}
+		admitted++
+	}
+}
+
+func TestHardExpiryBurst(t *testing.T) {
+	now := time.Now()
+	s := newStoreAt(&now)
+
+	if got := burstAfterBoundary(s, &now, 10); got != 10 {
+		t.Fatalf("expected a full new window after hard reset, got %d", got)
+	}
+}
+
+func TestSlidingExpiryBurst(t *testing.T) {
+	now := time.Now()
+	s := newStoreAt(&now, WithSlidingExpiry())
+
+	// 10% past the window, 90% of the previous count (9) still applies.
+	if got := burstAfterBoundary(s, &now, 10); got != 1 {
+		t.Fatalf("expected decayed count to leave 1 request, got %d", got)
+	}
+}
+
+func TestSlidingExpiryDecay(t *testing.T) {
+	now := time.Now()
+	s := newStoreAt(&now, WithSlidingExpiry())
+	window := 10 * time.Second
+
+	for i := 0; i < 10; i++ {
+		s.Increment(context.Background(), "k", window)
+	}
+
+	now = now.Add(window + window/2)
+	if count, _, _ := s.Get(context.Background(), "k"); count != 5 {
+		t.Fatalf("expected half the count to remain, got %d", count)
+	}
+
+	now = now.Add(window)
+	if count, expiry, _ := s.Get(context.Background(), "k"); count != 0 || !expiry.IsZero() {
+		t.Fatalf("expected entry fully decayed, got %d %v", count, expiry)
+	}
+	if count, _, _ := s.Increment(context.Background(), "k", window); count != 1 {
+		t.Fatalf("expected a fresh window, got %d", count)
+	}
+}
+
+type countingMetrics struct {
+	evicted, reclaimed int
+}
+
+func (m *countingMetrics) KeysEvicted(n int)   { m.evicted += n }
+func (m *countingMetrics) KeysReclaimed(n int) { m.reclaimed += n }
+
+func TestMaxKeysEviction(t *testing.T) {
+	now := time.Now()
+	metrics := &countingMetrics{}
+	s := newStoreAt(&now, WithMaxKeys(3), WithMetrics(metrics))
+
+	for i, key := range []string{"a", "b", "c"} {
+		s.Increment(context.Background(), key, time.Duration(i+1)*time.Minute)
+	}
+	if metrics.evicted != 0 {
+		t.Fatalf("expected no evictions under the cap, got %d", metrics.evicted)
+	}
+
+	s.Increment(context.Background(), "d", time.Minute)
+	s.Increment(context.Background(), "e", time.Minute)
+	if metrics.evicted != 2 || metrics.reclaimed != 0 {
+		t.Fatalf("expected 2 evictions, got %+v", metrics)
+	}
+	if count, _, _ := s.Get(context.Background(), "a"); count != 0 {
+		t.Fatal("expected the key closest to expiry to be evicted")
+	}
+	if count, _, _ := s.Get(context.Background(), "c"); count != 1 {
+		t.Fatal("expected the longest-lived key to survive")
+	}
+
+	if count, _, _ := s.Increment(context.Background(), "c", time.Minute); count != 2 || metrics.evicted != 2 {
+		t.Fatalf("expected existing keys to be incremented without eviction, got %d %+v", count, metrics)
+	}
+}
+
+func TestMaxKeysSampledEviction(t *testing.T) {
+	now := time.Now()
+	metrics := &countingMetrics{}
+	s := newStoreAt(&now, WithMaxKeys(100), WithMetrics(metrics))
+
+	for i := 0; i < 100; i++ {
+		s.Increment(context.Background(), fmt.Sprintf("k%d", i), time.Second)
+	}
+	now = now.Add(2 * time.Second)
+
+	// A new key at the cap frees one slot rather than scanning for every
+	// expired key; the sweep reclaims the rest.
+	s.Increment(context.Background(), "new", time.Minute)
+	if len(s.m) != 100 || metrics.reclaimed != 1 || metrics.evicted != 0 {
+		t.Fatalf("expected one expired key reclaimed, got %d keys, %+v", len(s.m), metrics)
+	}
+}
+
+func TestMaxKeysCoversAllAlgorithms(t *testing.T) {
+	now := time.Now()
+	metrics := &countingMetrics{}
+	s := newStoreAt(&now, WithMaxKeys(3), WithMetrics(metrics))
+
+	s.Increment(context.Background(), "counter", time.Hour)
+	s.AppendLog(context.Background(), "log", 1, 10, time.Hour)
+	s.TakeTokens(context.Background(), "bucket", 1, 10, 1, time.Minute)
+	if s.Len() != 3 {
+		t.Fatalf("expected every kind of key counted, got %d", s.Len())
+	}
+
+	// The bucket is full again soonest, so it goes first.
+	s.ArriveGCRA(context.Background(), "tat", 1, time.Second, time.Minute)
+	if s.lenLocked() != 3 || metrics.evicted != 1 {
+		t.Fatalf("expected one eviction to stay under the cap, got %d keys, %+v", s.lenLocked(), metrics)
+	}
+	if _, ok := s.buckets["bucket"]; ok {
+		t.Fatal("expected the key closest to expiry evicted")
+	}
+	for i := 0; i < 10; i++ {
+		s.AppendLog(context.Background(), fmt.Sprintf("log%d", i), 1, 10, time.Hour)
+	}
+	if s.lenLocked() != 3 {
+		t.Fatalf("expected logs held to the cap, got %d keys", s.lenLocked())
+	}
+}
+
+func TestSweepReclaimsExpiredKeys(t *testing.T) {
+	now := time.Now()
+	metrics := &countingMetrics{}
+	s := newStoreAt(&now, WithMaxKeys(10), WithMetrics(metrics))
+
+	s.Increment(context.Background(), "a", time.Second)
+	s.Increment(context.Background(), "b", time.Second)
+	s.Increment(context.Background(), "c", time.Hour)
+
+	now = now.Add(2 * time.Second)
+	s.sweep()
+	if metrics.reclaimed != 2 || metrics.evicted != 0 {
+		t.Fatalf("expected 2 reclaimed keys and no evictions, got %+v", metrics)
+	}
+}
+
+func TestFixedVersusRollingExpiry(t *testing.T) {
+	const ttl = 10 * time.Second
+	start := time.Now()
+
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		expires time.Time
+	}{
+		{"fixed", nil, start.Add(ttl)},
+		{"rolling", []Option{WithRollingExpiry()}, start.Add(8*time.Second + ttl)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			now := start
+			s := newStoreAt(&now, tc.opts...)
+
+			var expiry time.Time
+			for i := 0; i < 5; i++ {
+				_, expiry, _ = s.Increment(context.Background(), "k", ttl)
+				now = now.Add(2 * time.Second)
+			}
+			if !expiry.Equal(tc.expires) {
+				t.Fatalf("expected expiry %v, got %v", tc.expires, expiry)
+			}
+
+			// Past the fixed window but within ttl of the last increment.
+			now = start.Add(ttl + time.Second)
+			count, _, _ := s.Increment(context.Background(), "k", ttl)
+			if tc.name == "fixed" && count != 1 {
+				t.Fatalf("expected fixed window to reset, got count %d", count)
+			}
+			if tc.name == "rolling" && count != 6 {
+				t.Fatalf("expected rolling window to keep counting, got %d", count)
+			}
+		})
+	}
+}
+
+func TestRollingExpiryWindowStart(t *testing.T) {
+	now := time.Now()
+	s := newStoreAt(&now, WithRollingExpiry())
+
+	s.IncrementWindow(context.Background(), "k", 1, time.Minute)
+	now = now.Add(30 * time.Second)
+	if _, start, _, _ := s.IncrementWindow(context.Background(), "k", 1, time.Minute); !start.Equal(now) {
+		t.Fatalf("expected window start to follow the latest increment, got %v", start)
+	}
+}
+
+func TestIncrementWindowCreated(t *testing.T) {
+	now := time.Now()
+	s := newStoreAt(&now)
+	created := func() bool {
+		_, _, c, _ := s.IncrementWindow(context.Background(), "k", 1, time.Minute)
+		return c
+	}
+
+	if !created() {
+		t.Fatal("expected the first increment to create the key")
+	}
+	if created() {
+		t.Fatal("expected an increment within the window not to create the key")
+	}
+	now = now.Add(2 * time.Minute)
+	if created() {
+		t.Fatal("expected a window restarted in place not to create the key")
+	}
+	s.Delete(context.Background(), "k")
+	if !created() {
+		t.Fatal("expected an increment after a delete to create the key")
+	}
+}
+
+func TestAlignedWindowsConcurrentCreators(t *testing.T) {
+	const ttl = time.Minute
+	base := time.Date(2025, 10, 23, 10, 30, 0, 0, time.UTC)
+	s := NewMemoryStore(WithAlignedWindows())
+
+	// Every caller sees a slightly later clock, as racers would.
+	var tick int64
+	s.now = func() time.Time {
+		return base.Add(time.Duration(atomic.AddInt64(&tick, 1)) * time.Millisecond)
+	}
+
+	const racers = 50
+	starts := make([]time.Time, racers)
+	var wg sync.WaitGroup
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, starts[i], _, _ = s.IncrementWindow(context.Background(), "k", 1, ttl)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, start := range starts {
+		if !start.Equal(base) {
+			t.Fatalf("racer %d: expected window start %v, got %v", i, base, start)
+		}
+	}
+	if count, expiry, _ := s.Get(context.Background(), "k"); count != racers || !expiry.Equal(base.Add(ttl)) {
+		t.Fatalf("expected %d hits expiring at %v, got %d at %v", racers, base.Add(ttl), count, expiry)
+	}
+}
+
+func TestAlignedWindowsAcrossStores(t *testing.T) {
+	const ttl = 10 * time.Second
+	first := time.Date(2025, 10, 23, 10, 30, 3, 0, time.UTC)
+	second := first.Add(4 * time.Second)
+
+	a := newStoreAt(&first, WithAlignedWindows())
+	b := newStoreAt(&second, WithAlignedWindows())
+
+	_, ea, _ := a.Increment(context.Background(), "k", ttl)
+	_, eb, _ := b.Increment(context.Background(), "k", ttl)
+	if !ea.Equal(eb) {
+		t.Fatalf("expected stores to agree on reset, got %v and %v", ea, eb)
+	}
+	if want := time.Date(2025, 10, 23, 10, 30, 10, 0, time.UTC); !ea.Equal(want) {
+		t.Fatalf("expected reset at %v, got %v", want, ea)
+	}
+}
+
+func TestStaggeredWindowPhases(t *testing.T) {
+	const ttl = time.Minute
+	base := time.Date(2025, 10, 23, 10, 30, 0, 0, time.UTC)
+	phase := func(start time.Time) time.Duration {
+		return start.Sub(start.Truncate(ttl))
+	}
+
+	now := base
+	s := newStoreAt(&now, WithStaggeredWindows())
+
+	_, a, _, _ := s.IncrementWindow(context.Background(), "rate:client-a", 1, ttl)
+	_, b, _, _ := s.IncrementWindow(context.Background(), "rate:client-b", 1, ttl)
+	if phase(a) == phase(b) {
+		t.Fatalf("expected clients to get different phases, both got %v", phase(a))
+	}
+	for _, start := range []time.Time{a, b} {
+		if start.After(base) || base.Sub(start) >= ttl {
+			t.Fatalf("expected window start %v within the last ttl before %v", start, base)
+		}
+	}
+
+	// Later windows and other instances keep the same phase per client.
+	now = base.Add(3*ttl + 17*time.Second)
+	_, a2, _, _ := s.IncrementWindow(context.Background(), "rate:client-a", 1, ttl)
+	other := newStoreAt(&now, WithStaggeredWindows())
+	_, a3, _, _ := other.IncrementWindow(context.Background(), "rate:client-a", 1, ttl)
+	if phase(a2) != phase(a) || !a3.Equal(a2) {
+		t.Fatalf("expected a stable phase %v, got %v and %v", phase(a), phase(a2), phase(a3))
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2025, 10, 23, 10, 30, 0, 0, time.FixedZone("UTC+7", 7*3600))
+	s := newStoreAt(&now)
+	defer s.Close()
+
+	_, expiry, _ := s.Increment(context.Background(), "k", time.Minute)
+	if want := now.Add(time.Minute); !expiry.Equal(want) || expiry.Location() != time.UTC {
+		t.Fatalf("expected expiry %v in UTC, got %v", want.UTC(), expiry)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if count, _, _ := s.Increment(context.Background(), "k", time.Minute); count != 1 {
+		t.Fatalf("expected a new window on the simulated clock, got count %d", count)
+	}
+}
+
+func TestCloseKeepsStoreUsable(t *testing.T) {
+	s := NewMemoryStore()
+	s.Close()
+	s.Close()
+
+	if count, _, err := s.Increment(context.Background(), "k", time.Minute); err != nil || count != 1 {
+		t.Fatalf("expected increment after Close to work, got %d, %v", count, err)
+	}
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	now := time.Date(2025, 10, 23, 10, 30, 0, 0, time.UTC)
+	s := newStoreAt(&now)
+
+	created, err := s.SetIfAbsent(context.Background(), "k", 3, time.Minute)
+	if err != nil || !created {
+		t.Fatalf("expected the entry to be created, got %v, %v", created, err)
+	}
+	if count, expiry, _ := s.Get(context.Background(), "k"); count != 3 || !expiry.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected count 3 expiring in a minute, got %d at %v", count, expiry)
+	}
+
+	s.Increment(context.Background(), "k", time.Minute)
+	if created, err := s.SetIfAbsent(context.Background(), "k", 0, time.Minute); err != nil || created {
+		t.Fatalf("expected an existing window to be kept, got %v, %v", created, err)
+	}
+	if count, _, _ := s.Get(context.Background(), "k"); count != 4 {
+		t.Fatalf("expected count 4 untouched, got %d", count)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if created, _ := s.SetIfAbsent(context.Background(), "k", 1, time.Minute); !created {
+		t.Fatal("expected an expired window to count as absent")
+	}
+	if count, _, _ := s.Get(context.Background(), "k"); count != 1 {
+		t.Fatalf("expected a fresh count of 1, got %d", count)
+	}
+}
+
+func TestSetIfAbsentConcurrent(t *testing.T) {
+	s := NewMemoryStore()
+
+	var created atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := s.SetIfAbsent(context.Background(), "k", 1, time.Minute); ok {
+				created.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created.Load() != 1 {
+		t.Fatalf("expected exactly one creator, got %d", created.Load())
+	}
+}
+
+func TestLen(t *testing.T) {
+	now := time.Now()
+	s := newStoreAt(&now)
+
+	s.Increment(context.Background(), "a", time.Second)
+	s.Increment(context.Background(), "b", time.Minute)
+	s.Increment(context.Background(), "b", time.Minute)
+	if n := s.Len(); n != 2 {
+		t.Fatalf("expected 2 live keys, got %d", n)
+	}
+
+	now = now.Add(2 * time.Second)
+	if n := s.Len(); n != 1 {
+		t.Fatalf("expected the expired key not to count, got %d", n)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	now := time.Now()
+	s := newStoreAt(&now)
+
+	s.Increment(context.Background(), "rate:b", time.Minute)
+	s.Increment(context.Background(), "rate:a", time.Minute)
+	s.Increment(context.Background(), "rate:old", time.Second)
+	s.Increment(context.Background(), "ratepool:g", time.Minute)
+	now = now.Add(2 * time.Second)
+
+	keys, err := s.Keys(context.Background(), "rate:")
+	if err != nil || len(keys) != 2 || keys[0] != "rate:a" || keys[1] != "rate:b" {
+		t.Fatalf("expected the sorted live keys under the prefix, got %v, %v", keys, err)
+	}
+}
+
+func TestRequestLog(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	start := now
+	s := newStoreAt(&now)
+	const window = 10 * time.Second
+
+	if count, oldest, ok, _ := s.AppendLog(context.Background(), "k", 2, 3, window); !ok || count != 2 || !oldest.Equal(start) {
+		t.Fatalf("expected 2 logged at %v, got %d, %v, %v", start, count, oldest, ok)
+	}
+	now = now.Add(4 * time.Second)
+	if count, _, ok, _ := s.AppendLog(context.Background(), "k", 2, 3, window); ok || count != 2 {
+		t.Fatalf("expected a cost of 2 not to fit, got %d, %v", count, ok)
+	}
+	if count, _, ok, _ := s.AppendLog(context.Background(), "k", 1, 3, window); !ok || count != 3 {
+		t.Fatalf("expected a cost of 1 to fit, got %d, %v", count, ok)
+	}
+
+	// Times exactly one window old have left it.
+	now = start.Add(window)
+	if count, oldest, _ := s.CountLog(context.Background(), "k", window); count != 1 || !oldest.Equal(start.Add(4*time.Second)) {
+		t.Fatalf("expected only the later request counted, got %d oldest %v", count, oldest)
+	}
+	if count, _, _ := s.CountLog(context.Background(), "missing", window); count != 0 {
+		t.Fatalf("expected an empty log, got %d", count)
+	}
+	if keys, err := s.Keys(context.Background(), ""); err != nil || s.Len() != 1 || len(keys) != 1 || keys[0] != "k" {
+		t.Fatalf("expected the live log listed, got %v, len %d", keys, s.Len())
+	}
+
+	now = start.Add(window + 4*time.Second)
+	s.sweep()
+	if len(s.logs) != 0 {
+		t.Fatalf("expected the sweep to drop the emptied log, got %d", len(s.logs))
+	}
+
+	s.AppendLog(context.Background(), "k", 1, 3, window)
+	s.Delete(context.Background(), "k")
+	if count, _, _ := s.CountLog(context.Background(), "k", window); count != 0 {
+		t.Fatalf("expected Delete to clear the log, got %d", count)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	start := now
+	s := newStoreAt(&now)
+	const ttl = 5 * time.Second
+
+	if tokens, ok, _ := s.TakeTokens(context.Background(), "k", 3, 5, 1, ttl); !ok || tokens != 2 {
+		t.Fatalf("expected 3 taken from a full bucket, got %v, %v", tokens, ok)
+	}
+	if tokens, ok, _ := s.TakeTokens(context.Background(), "k", 3, 5, 1, ttl); ok || tokens != 2 {
+		t.Fatalf("expected 3 not to fit, got %v, %v", tokens, ok)
+	}
+	now = now.Add(1500 * time.Millisecond)
+	if tokens, ok, _ := s.TakeTokens(context.Background(), "k", 3, 5, 1, ttl); !ok || tokens != 0.5 {
+		t.Fatalf("expected the refill to fit 3, got %v, %v", tokens, ok)
+	}
+
+	now = now.Add(time.Hour)
+	if tokens, _ := s.PeekTokens(context.Background(), "k", 5, 1); tokens != 5 {
+		t.Fatalf("expected the refill capped at capacity, got %v", tokens)
+	}
+	if tokens, _ := s.PeekTokens(context.Background(), "missing", 5, 1); tokens != 5 {
+		t.Fatalf("expected a missing bucket full, got %v", tokens)
+	}
+	if _, err := s.Keys(context.Background(), ""); err != nil || s.Len() != 0 {
+		t.Fatalf("expected buckets kept apart from counters, got len %d", s.Len())
+	}
+
+	s.sweep()
+	if len(s.buckets) != 0 {
+		t.Fatalf("expected the sweep to drop the idle bucket, got %d", len(s.buckets))
+	}
+
+	now = start
+	s.TakeTokens(context.Background(), "k", 5, 5, 1, ttl)
+	s.Delete(context.Background(), "k")
+	if tokens, _ := s.PeekTokens(context.Background(), "k", 5, 1); tokens != 5 {
+		t.Fatalf("expected Delete to refill the bucket, got %v", tokens)
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	start := now
+	s := newStoreAt(&now)
+	const interval, tolerance = time.Second, 3 * time.Second
+
+	if tat, ok, _ := s.ArriveGCRA(context.Background(), "k", 2, interval, tolerance); !ok || !tat.Equal(start.Add(2*time.Second)) {
+		t.Fatalf("expected 2 admitted, got %v, %v", tat, ok)
+	}
+	if tat, ok, _ := s.ArriveGCRA(context.Background(), "k", 2, interval, tolerance); ok || !tat.Equal(start.Add(2*time.Second)) {
+		t.Fatalf("expected 2 more not to fit, got %v, %v", tat, ok)
+	}
+	if _, ok, _ := s.ArriveGCRA(context.Background(), "k", 1, interval, tolerance); !ok {
+		t.Fatal("expected 1 more to fit")
+	}
+	if tat, _ := s.GetTAT(context.Background(), "k"); !tat.Equal(start.Add(3 * time.Second)) {
+		t.Fatalf("expected the TAT 3s out, got %v", tat)
+	}
+	if tat, _ := s.GetTAT(context.Background(), "missing"); !tat.IsZero() {
+		t.Fatalf("expected no TAT, got %v", tat)
+	}
+	if keys, err := s.Keys(context.Background(), ""); err != nil || s.Len() != 1 || len(keys) != 1 || keys[0] != "k" {
+		t.Fatalf("expected the live TAT listed, got %v, len %d", keys, s.Len())
+	}
+
+	// A passed TAT counts from now.
+	now = start.Add(time.Minute)
+	if tat, ok, _ := s.ArriveGCRA(context.Background(), "k", 1, interval, tolerance); !ok || !tat.Equal(now.Add(interval)) {
+		t.Fatalf("expected a TAT one interval from now, got %v, %v", tat, ok)
+	}
+	now = now.Add(interval)
+	s.sweep()
+	if len(s.tats) != 0 {
+		t.Fatalf("expected the sweep to drop the passed TAT, got %d", len(s.tats))
+	}
+
+	s.ArriveGCRA(context.Background(), "k", 1, interval, tolerance)
+	s.Delete(context.Background(), "k")
+	if tat, _ := s.GetTAT(context.Background(), "k"); !tat.IsZero() {
+		t.Fatalf("expected Delete to clear the TAT, got %v", tat)
+	}
+}
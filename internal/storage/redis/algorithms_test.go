@@ -0,0 +1,199 @@
+package redis
+
+// The algorithm matrix lives here rather than next to the memory store
+// because scriptHook, the in-process Redis stand-in, is only visible in this
+// package.
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+	"github.com/redis/go-redis/v9"
+)
+
+type algorithm struct {
+	name string
+	// store builds a fresh backend; clock is nil for real time.
+	store func(clock func() time.Time) limiter.Store
+	// boundary is true for backends whose windows can be stepped through
+	// with a fake clock, which the accuracy test needs.
+	boundary bool
+	// opts are extra limiter options, such as the counting algorithm.
+	opts []limiter.Option
+}
+
+var slidingCounter = []limiter.Option{limiter.WithAlgorithm(limiter.AlgorithmSlidingWindow)}
+
+var algorithms = []algorithm{
+	{"memory/fixed", memoryStore(), true, nil},
+	{"memory/aligned", memoryStore(memory.WithAlignedWindows()), true, nil},
+	{"memory/sliding", memoryStore(memory.WithSlidingExpiry()), true, nil},
+	{"memory/sliding-counter", memoryStore(), true, slidingCounter},
+	{"memory/gcra", memoryStore(), true, []limiter.Option{limiter.WithAlgorithm(limiter.AlgorithmGCRA)}},
+	{"redis/fixed", func(func() time.Time) limiter.Store {
+		store, _ := newHookedStore()
+		return store
+	}, false, nil},
+	// The sliding counter names its keys from the limiter's clock and never
+	// relies on Redis expiring them within a test, so it can be stepped.
+	{"redis/sliding-counter", func(func() time.Time) limiter.Store {
+		return newCounterStore()
+	}, true, slidingCounter},
+}
+
+// counterHook answers incrementScript and GET/TTL pipelines in-process, the
+// commands the sliding counter sends. Keys never expire.
+type counterHook struct {
+	counts map[string]int64
+}
+
+func newCounterStore() *RedisStore {
+	client := redis.NewClient(&redis.Options{Addr: "redis.invalid:6379"})
+	client.AddHook(&counterHook{counts: map[string]int64{}})
+	return NewRedisStore(client)
+}
+
+func (h *counterHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, fmt.Errorf("unexpected dial to %s", addr)
+	}
+}
+
+func (h *counterHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() != "evalsha" {
+			return fmt.Errorf("unexpected command %s", cmd.Name())
+		}
+		// evalsha sha numkeys key n ttl
+		args := cmd.Args()
+		key := args[3].(string)
+		h.counts[key] += args[4].(int64)
+		cmd.(*redis.Cmd).SetVal([]interface{}{h.counts[key], args[5]})
+		return nil
+	}
+}
+
+func (h *counterHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		var err error
+		for _, cmd := range cmds {
+			count, ok := h.counts[cmd.Args()[1].(string)]
+			switch c := cmd.(type) {
+			case *redis.StringCmd:
+				if !ok {
+					c.SetErr(redis.Nil)
+					err = redis.Nil
+					continue
+				}
+				c.SetVal(strconv.FormatInt(count, 10))
+			case *redis.DurationCmd:
+				c.SetVal(time.Hour)
+			default:
+				return fmt.Errorf("unexpected pipelined command %s", cmd.Name())
+			}
+		}
+		return err
+	}
+}
+
+func memoryStore(opts ...memory.Option) func(func() time.Time) limiter.Store {
+	return func(clock func() time.Time) limiter.Store {
+		if clock != nil {
+			opts = append(opts[:len(opts):len(opts)], memory.WithClock(clock))
+		}
+		return memory.NewMemoryStore(opts...)
+	}
+}
+
+// BenchmarkAlgorithms runs every algorithm over the same round-robin traffic
+// at several key cardinalities. Limits are high enough that every decision is
+// an admit, so only the cost of deciding is measured.
+func BenchmarkAlgorithms(b *testing.B) {
+	cfg := config.ClientConfig{Limit: 1 << 30, Window: time.Minute}
+
+	for _, alg := range algorithms {
+		for _, keys := range []int{1, 1000, 100000} {
+			b.Run(alg.name+"/keys="+strconv.Itoa(keys), func(b *testing.B) {
+				l := limiter.New(alg.store(nil), append([]limiter.Option{limiter.WithDefault(cfg)}, alg.opts...)...)
+				clients := make([]string, keys)
+				for i := range clients {
+					clients[i] = "client-" + strconv.Itoa(i)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					l.AllowResult(clients[i%keys])
+				}
+				b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "decisions/s")
+			})
+		}
+	}
+}
+
+// TestAlgorithmBoundaryBurst quantifies how far each algorithm lets a client
+// exceed its limit around a window boundary: one request opens the window,
+// the rest of the limit arrives just before it ends, and the client retries
+// as fast as it can just after. The burst factor is what was admitted in that
+// short span over the limit. Above 1 the client beat its limit within far
+// less than a window; fixed windows allow almost 2.
+func TestAlgorithmBoundaryBurst(t *testing.T) {
+	const limit = 10
+	const window = 10 * time.Second
+	want := map[string]float64{
+		"memory/fixed":   1.9,
+		"memory/aligned": 1.9,
+		"memory/sliding": 0.9,
+		// Denied retries count too, so the previous window's weighted count
+		// keeps the client out just after the boundary.
+		"memory/sliding-counter": 0.9,
+		"redis/sliding-counter":  0.9,
+		// GCRA lets an idle client spend its whole limit at once, but then
+		// spaces it out regardless of window boundaries.
+		"memory/gcra": 1.0,
+	}
+
+	for _, alg := range algorithms {
+		if !alg.boundary {
+			continue
+		}
+		t.Run(alg.name, func(t *testing.T) {
+			// Start on a window boundary so aligned windows line up too.
+			start := time.Date(2025, 10, 23, 10, 30, 0, 0, time.UTC)
+			now := start
+			clock := func() time.Time { return now }
+			l := limiter.New(alg.store(clock), append([]limiter.Option{
+				limiter.WithDefault(config.ClientConfig{Limit: limit, Window: window}),
+				limiter.WithClock(clock),
+			}, alg.opts...)...)
+
+			l.Allow("c1")
+			now = start.Add(window - 10*time.Millisecond)
+			admitted := 0
+			for i := 0; i < limit; i++ {
+				if ok, _, _, _ := l.Allow("c1"); ok {
+					admitted++
+				}
+			}
+			now = start.Add(window + 10*time.Millisecond)
+			for i := 0; i < 2*limit; i++ {
+				if ok, _, _, _ := l.Allow("c1"); ok {
+					admitted++
+				}
+			}
+
+			factor := float64(admitted) / limit
+			t.Logf("%s: %d admitted in 20ms around the boundary, burst factor %.2f", alg.name, admitted, factor)
+			if factor != want[alg.name] {
+				t.Errorf("expected burst factor %.2f, got %.2f", want[alg.name], factor)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeTokensScript refills the bucket hash {tokens, ts} (ts in Unix ms) and
+// takes ARGV[4] tokens if available. A missing bucket starts full, and a ts
+// ahead of now (another caller's clock) refills nothing. Tokens are kept to a
+// millionth, as in the memory store. Tokens are returned
+// as a string since Lua numbers are truncated to integers in replies. It
+// returns {tokens, taken}.
+var takeTokensScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if not tokens or not ts then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.floor((tokens + (now - ts) / 1000 * rate) * 1e6 + 0.5) / 1e6
+	tokens = math.min(capacity, tokens)
+	ts = now
+end
+local taken = 0
+if tokens >= n then
+	tokens = tokens - n
+	taken = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", ts)
+redis.call("PEXPIRE", KEYS[1], ARGV[5])
+return {tostring(tokens), taken}
+`)
+
+// bucketKey holds key's token bucket, outside the limiter's key space like
+// logKey.
+func bucketKey(key string) string {
+	return "tb:" + key
+}
+
+// TakeTokens implements limiter.BucketStore with a hash per key.
+func (r *RedisStore) TakeTokens(ctx context.Context, key string, n, capacity int64, rate float64, ttl time.Duration) (float64, bool, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	args := []interface{}{capacity, rate, now.UnixMilli(), n, max(ttl.Milliseconds(), 1)}
+	vals, err := takeTokensScript.Run(ctx, r.client, []string{bucketKey(key)}, args...).Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("redis bucket script error: %w", err)
+	}
+	if len(vals) != 2 {
+		return 0, false, fmt.Errorf("redis bucket script returned %d values", len(vals))
+	}
+	s, _ := vals[0].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("redis bucket script returned tokens %q: %w", s, err)
+	}
+	taken, _ := vals[1].(int64)
+	return tokens, taken == 1, nil
+}
+
+// PeekTokens implements limiter.BucketStore, refilling on the client side so
+// the bucket is left untouched.
+func (r *RedisStore) PeekTokens(ctx context.Context, key string, capacity int64, rate float64) (float64, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	state, err := r.client.HMGet(ctx, bucketKey(key), "tokens", "ts").Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis get error: %w", err)
+	}
+	s, _ := state[0].(string)
+	ms, _ := state[1].(string)
+	tokens, errT := strconv.ParseFloat(s, 64)
+	ts, errTS := strconv.ParseInt(ms, 10, 64)
+	if errT != nil || errTS != nil {
+		return float64(capacity), nil
+	}
+	if elapsed := now.UnixMilli() - ts; elapsed > 0 {
+		tokens = min(float64(capacity), math.Round((tokens+float64(elapsed)/1000*rate)*1e6)/1e6)
+	}
+	return tokens, nil
+}
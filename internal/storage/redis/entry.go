@@ -0,0 +1,133 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const maxTxRetries = 10
+
+type Option func(*RedisStore)
+
+// WithSerializer stores each window as a serialized Entry (count and window
+// start) instead of a bare INCR counter with a side TTL. Stores sharing keys
+// must agree on the format.
+func WithSerializer(s Serializer) Option {
+	return func(r *RedisStore) {
+		r.serializer = s
+	}
+}
+
+// WithServerTime derives window starts and reset times from the Redis
+// server's clock (the TIME command) instead of each instance's local clock, so
+// instances with skewed clocks agree on windows. It costs one extra round trip
+// per store call.
+func WithServerTime() Option {
+	return func(r *RedisStore) {
+		r.serverTime = true
+	}
+}
+
+// WithStaggeredWindows starts each new window on a boundary shifted by an
+// offset derived from hashing the key, like the memory store's option of the
+// same name, so clients reset at different phases instead of all at once.
+// Every instance, and a memory store with the option, computes the same
+// window for a key. Windows are otherwise created at the first request.
+func WithStaggeredWindows() Option {
+	return func(r *RedisStore) {
+		r.stagger = true
+	}
+}
+
+// newWindow is when a window created at now for key begins and how long it
+// has left: now and the full ttl unless windows are staggered.
+func (r *RedisStore) newWindow(key string, now time.Time, ttl time.Duration) (time.Time, time.Duration) {
+	if !r.stagger || ttl <= 0 {
+		return now, ttl
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	offset := time.Duration(h.Sum64() % uint64(ttl))
+	start := now.Add(-offset).Truncate(ttl).Add(offset)
+	// Redis expiries are in milliseconds and must be positive.
+	return start, max(start.Add(ttl).Sub(now), time.Millisecond)
+}
+
+// incrementEntry adds n to the serialized entry at key inside an optimistic
+// WATCH transaction, starting a new window when the key is missing or expired.
+// It also reports whether the key was missing.
+func (r *RedisStore) incrementEntry(ctx context.Context, key string, n int64, ttl time.Duration) (Entry, time.Duration, bool, error) {
+	var (
+		entry   Entry
+		left    time.Duration
+		created bool
+	)
+
+	txf := func(tx *redis.Tx) error {
+		now, err := r.now(ctx)
+		if err != nil {
+			return err
+		}
+
+		existing, pttl, found, err := r.readEntry(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+
+		entry, left, created = existing, pttl, !found
+		if left <= 0 {
+			var start time.Time
+			start, left = r.newWindow(key, now, ttl)
+			entry = Entry{WindowStart: start.UnixMilli()}
+		}
+		entry.Count += n
+
+		data, err := r.serializer.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("encode entry: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, left)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < maxTxRetries; i++ {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return entry, left, created, err
+	}
+	return Entry{}, 0, false, fmt.Errorf("redis increment of %s: too much contention", key)
+}
+
+// readEntry returns the entry at key, its remaining TTL and whether key
+// exists; a missing key yields a zero entry and a non-positive TTL.
+func (r *RedisStore) readEntry(ctx context.Context, c redis.Cmdable, key string) (Entry, time.Duration, bool, error) {
+	data, err := c.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return Entry{}, 0, false, nil
+	}
+	if err != nil {
+		return Entry{}, 0, false, err
+	}
+
+	entry, err := r.serializer.Unmarshal(data)
+	if err != nil {
+		return Entry{}, 0, false, err
+	}
+
+	pttl, err := c.PTTL(ctx, key).Result()
+	if err != nil {
+		return Entry{}, 0, false, err
+	}
+	return entry, pttl, true, nil
+}
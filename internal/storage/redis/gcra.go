@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// arriveGCRAScript advances the TAT (whole Unix µs) by ARGV[3] intervals of
+// ARGV[2] µs from no earlier than now, and stores it with a TTL up to the
+// TAT if it stays within ARGV[4] µs of now. Values are formatted with %.0f
+// since tostring would round them to 14 digits. It returns {tat, admitted}.
+var arriveGCRAScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local tat = tonumber(redis.call("GET", KEYS[1])) or now
+if tat < now then
+	tat = now
+end
+local next = tat + math.ceil(tonumber(ARGV[3]) * tonumber(ARGV[2]))
+if next - now > tonumber(ARGV[4]) then
+	return {string.format("%.0f", tat), 0}
+end
+redis.call("SET", KEYS[1], string.format("%.0f", next), "PX", math.max(math.ceil((next - now) / 1000), 1))
+return {string.format("%.0f", next), 1}
+`)
+
+// gcraKey holds key's TAT, outside the limiter's key space like logKey.
+func gcraKey(key string) string {
+	return "gcra:" + key
+}
+
+// ArriveGCRA implements limiter.GCRAStore with a string per key.
+func (r *RedisStore) ArriveGCRA(ctx context.Context, key string, n int64, interval, tolerance time.Duration) (time.Time, bool, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	args := []interface{}{now.UnixMicro(), float64(interval) / float64(time.Microsecond), n, tolerance.Microseconds()}
+	vals, err := arriveGCRAScript.Run(ctx, r.client, []string{gcraKey(key)}, args...).Slice()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("redis gcra script error: %w", err)
+	}
+	if len(vals) != 2 {
+		return time.Time{}, false, fmt.Errorf("redis gcra script returned %d values", len(vals))
+	}
+	s, _ := vals[0].(string)
+	tat, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("redis gcra script returned tat %q: %w", s, err)
+	}
+	admitted, _ := vals[1].(int64)
+	return time.UnixMicro(tat).UTC(), admitted == 1, nil
+}
+
+// GetTAT implements limiter.GCRAStore.
+func (r *RedisStore) GetTAT(ctx context.Context, key string) (time.Time, error) {
+	s, err := r.client.Get(ctx, gcraKey(key)).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("redis get error: %w", err)
+	}
+	tat, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse tat error: %w", err)
+	}
+	return time.UnixMicro(tat).UTC(), nil
+}
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// appendLogScript trims the sorted set of request times (scored in Unix ms)
+// to the window and adds n members at now if they fit under the limit. ARGV[5]
+// makes members unique across callers logging in the same millisecond. It
+// returns {count, appended, oldest_ms}, with oldest_ms -1 for an empty log.
+var appendLogScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local appended = 0
+if count + n <= tonumber(ARGV[4]) then
+	for i = 1, n do
+		redis.call("ZADD", KEYS[1], now, ARGV[5] .. ":" .. i)
+	end
+	redis.call("PEXPIRE", KEYS[1], window)
+	count = count + n
+	appended = 1
+end
+local oldest = -1
+local first = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if first[2] then
+	oldest = tonumber(first[2])
+end
+return {count, appended, oldest}
+`)
+
+// logKey holds key's request log. Like windowStartKey it lives outside the
+// limiter's key space, so Keys and Get never meet a sorted set.
+func logKey(key string) string {
+	return "log:" + key
+}
+
+// AppendLog implements limiter.LogStore with a sorted set per key.
+func (r *RedisStore) AppendLog(ctx context.Context, key string, n, limit int64, window time.Duration) (int64, time.Time, bool, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+
+	nonce := strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.FormatInt(rand.Int63(), 36)
+	args := []interface{}{now.UnixMilli(), window.Milliseconds(), n, limit, nonce}
+	vals, err := appendLogScript.Run(ctx, r.client, []string{logKey(key)}, args...).Int64Slice()
+	if err != nil {
+		return 0, time.Time{}, false, fmt.Errorf("redis log script error: %w", err)
+	}
+	if len(vals) != 3 {
+		return 0, time.Time{}, false, fmt.Errorf("redis log script returned %d values", len(vals))
+	}
+	return vals[0], logTime(vals[2]), vals[1] == 1, nil
+}
+
+// CountLog implements limiter.LogStore. It only reads, so it leaves trimming
+// to the next AppendLog.
+func (r *RedisStore) CountLog(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	from := "(" + strconv.FormatInt(now.Add(-window).UnixMilli(), 10)
+	pipe := r.client.Pipeline()
+	countCmd := pipe.ZCount(ctx, logKey(key), from, "+inf")
+	firstCmd := pipe.ZRangeByScoreWithScores(ctx, logKey(key), &redis.ZRangeBy{Min: from, Max: "+inf", Count: 1})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, time.Time{}, fmt.Errorf("redis pipeline error: %w", err)
+	}
+
+	oldest := int64(-1)
+	if first := firstCmd.Val(); len(first) > 0 {
+		oldest = int64(first[0].Score)
+	}
+	return countCmd.Val(), logTime(oldest), nil
+}
+
+func logTime(ms int64) time.Time {
+	if ms < 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
@@ -3,59 +3,287 @@ package redis
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
 	"github.com/redis/go-redis/v9"
 )
 
+// decisionScript increments the counter, sets the window TTL on first hit and
+// evaluates the limit server-side. The window start is kept in a companion
+// key (KEYS[2]) so reset times do not drift with the TTL; windows without one
+// derive it from the TTL. A new window starts at ARGV[6] and expires after
+// ARGV[5], which differ from now and the window length only when staggered.
+// It returns {count, pttl, allowed, remaining, window_start_ms, created},
+// created being 1 when the counter did not exist.
+var decisionScript = redis.NewScript(`
+local created = 1 - redis.call("EXISTS", KEYS[1])
+local count = redis.call("INCRBY", KEYS[1], ARGV[1])
+local ttl = redis.call("PTTL", KEYS[1])
+local start = redis.call("GET", KEYS[2])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[5])
+	ttl = tonumber(ARGV[5])
+	start = ARGV[6]
+	redis.call("SET", KEYS[2], start, "PX", ARGV[5])
+end
+if start then
+	start = tonumber(start)
+else
+	start = tonumber(ARGV[4]) - (tonumber(ARGV[3]) - ttl)
+end
+local limit = tonumber(ARGV[2])
+local allowed = 0
+if count <= limit then
+	allowed = 1
+end
+local remaining = limit - count
+if remaining < 0 then
+	remaining = 0
+end
+return {count, ttl, allowed, remaining, start, created}
+`)
+
+// incrementScript increments the counter and sets its TTL on first hit in one
+// atomic step, so a crash or error between the two can never leave a counter
+// without expiry. Counters already missing a TTL get one on their next hit.
+// It returns {count, pttl}.
+var incrementScript = redis.NewScript(`
+local count = redis.call("INCRBY", KEYS[1], ARGV[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	ttl = tonumber(ARGV[2])
+end
+return {count, ttl}
+`)
+
+// initScript creates the counter and its window start key only when the
+// counter is absent, returning 1 when it did.
+var initScript = redis.NewScript(`
+if not redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[3], "PX", ARGV[2])
+return 1
+`)
+
+// windowStartKey lives outside the limiter's "rate:" key space so it cannot
+// collide with a scoped counter.
+func windowStartKey(key string) string {
+	return "ws:" + key
+}
+
 type RedisStore struct {
-	client *redis.Client
+	client     *redis.Client
+	serializer Serializer
+	serverTime bool
+	stagger    bool
 }
 
-func NewRedisStore(client *redis.Client) *RedisStore {
-	return &RedisStore{client: client}
+func NewRedisStore(client *redis.Client, opts ...Option) *RedisStore {
+	r := &RedisStore{client: client}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-func (r *RedisStore) Increment(key string, ttl time.Duration) (int64, time.Time, error) {
-	ctx := context.Background()
-	now := time.Now()
+// Increment adds one to key, abandoning the call once ctx is done.
+func (r *RedisStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	return r.IncrementBy(ctx, key, 1, ttl)
+}
 
-	pipe := r.client.Pipeline()
+func (r *RedisStore) IncrementBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
 
-	incrCmd := pipe.Incr(ctx, key)
+	if r.serializer != nil {
+		entry, left, _, err := r.incrementEntry(ctx, key, n, ttl)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("redis increment error: %w", err)
+		}
+		return entry.Count, now.Add(left), nil
+	}
 
-	ttlCmd := pipe.TTL(ctx, key)
+	_, left := r.newWindow(key, now, ttl)
+	vals, err := incrementScript.Run(ctx, r.client, []string{key}, n, left.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("redis increment script error: %w", err)
+	}
+	if len(vals) != 2 {
+		return 0, time.Time{}, fmt.Errorf("redis increment script returned %d values", len(vals))
+	}
+	return vals[0], now.Add(time.Duration(vals[1]) * time.Millisecond), nil
+}
 
-	_, err := pipe.Exec(ctx)
+func (r *RedisStore) IncrementWithResult(ctx context.Context, key string, n int64, limit int, ttl time.Duration) (limiter.StoreDecision, error) {
+	now, err := r.now(ctx)
 	if err != nil {
-		return 0, time.Time{}, fmt.Errorf("redis pipeline error: %w", err)
+		return limiter.StoreDecision{}, err
 	}
 
-	counter := incrCmd.Val()
-	currentTTL := ttlCmd.Val()
+	if r.serializer != nil {
+		entry, left, created, err := r.incrementEntry(ctx, key, n, ttl)
+		if err != nil {
+			return limiter.StoreDecision{}, fmt.Errorf("redis increment error: %w", err)
+		}
+		remaining := int64(limit) - entry.Count
+		if remaining < 0 {
+			remaining = 0
+		}
+		return limiter.StoreDecision{
+			Allowed:     entry.Count <= int64(limit),
+			Count:       entry.Count,
+			Remaining:   remaining,
+			Expiry:      now.Add(left),
+			WindowStart: time.UnixMilli(entry.WindowStart).UTC(),
+			Created:     created,
+		}, nil
+	}
+
+	start, left := r.newWindow(key, now, ttl)
+	keys := []string{key, windowStartKey(key)}
+	vals, err := decisionScript.Run(ctx, r.client, keys, n, limit, ttl.Milliseconds(), now.UnixMilli(),
+		left.Milliseconds(), start.UnixMilli()).Int64Slice()
+	if err != nil {
+		return limiter.StoreDecision{}, fmt.Errorf("redis decision script error: %w", err)
+	}
+	if len(vals) != 6 {
+		return limiter.StoreDecision{}, fmt.Errorf("redis decision script returned %d values", len(vals))
+	}
+
+	return limiter.StoreDecision{
+		Allowed:     vals[2] == 1,
+		Count:       vals[0],
+		Remaining:   vals[3],
+		Expiry:      now.Add(time.Duration(vals[1]) * time.Millisecond),
+		WindowStart: time.UnixMilli(vals[4]).UTC(),
+		Created:     vals[5] == 1,
+	}, nil
+}
+
+// Delete removes key, its window start, request log, token bucket and GCRA
+// TAT in a single DEL.
+func (r *RedisStore) Delete(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, key, windowStartKey(key), logKey(key), bucketKey(key), gcraKey(key)).Err(); err != nil {
+		return fmt.Errorf("redis delete error: %w", err)
+	}
+	return nil
+}
+
+// ResetKey sets key's count to 0 in a fresh window of ttl starting now. In
+// counter mode the window start key is written in the same MULTI so no
+// replica sees one without the other.
+func (r *RedisStore) ResetKey(ctx context.Context, key string, ttl time.Duration) error {
+	now, err := r.now(ctx)
+	if err != nil {
+		return err
+	}
 
-	if currentTTL == -1 || currentTTL == -2 {
-		if err := r.client.Expire(ctx, key, ttl).Err(); err != nil {
-			return counter, time.Time{}, fmt.Errorf("redis expire error: %w", err)
+	var value interface{} = 0
+	if r.serializer != nil {
+		data, err := r.serializer.Marshal(Entry{WindowStart: now.UnixMilli()})
+		if err != nil {
+			return fmt.Errorf("redis reset error: encode entry: %w", err)
 		}
-		return counter, now.Add(ttl), nil
+		value = data
 	}
 
-	expiry := now.Add(currentTTL)
-	return counter, expiry, nil
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, value, ttl)
+		if r.serializer == nil {
+			pipe.Set(ctx, windowStartKey(key), now.UnixMilli(), ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis reset error: %w", err)
+	}
+	return nil
+}
+
+// SetIfAbsent creates key with count in a new window of ttl unless it exists,
+// in a single atomic command. In counter mode the window start key is written
+// by the same script.
+func (r *RedisStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	start, left := r.newWindow(key, now, ttl)
+	return r.createIfAbsent(ctx, key, count, start, left)
+}
+
+// Restore is like SetIfAbsent but the window ends at expiry, as it did in the
+// store the count came from, rather than on a staggered boundary. The window
+// is taken to start now.
+func (r *RedisStore) Restore(ctx context.Context, key string, count int64, expiry time.Time) (bool, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return false, err
+	}
+	left := expiry.Sub(now)
+	if left <= 0 {
+		return false, nil
+	}
+	// Redis expiries are in milliseconds and must be positive.
+	return r.createIfAbsent(ctx, key, count, now, max(left, time.Millisecond))
+}
+
+// createIfAbsent writes count under key for a window starting at start with
+// left to run, unless key exists.
+func (r *RedisStore) createIfAbsent(ctx context.Context, key string, count int64, start time.Time, left time.Duration) (bool, error) {
+	if r.serializer != nil {
+		data, err := r.serializer.Marshal(Entry{Count: count, WindowStart: start.UnixMilli()})
+		if err != nil {
+			return false, fmt.Errorf("redis set error: encode entry: %w", err)
+		}
+		created, err := r.client.SetNX(ctx, key, data, left).Result()
+		if err != nil {
+			return false, fmt.Errorf("redis set error: %w", err)
+		}
+		return created, nil
+	}
+
+	keys := []string{key, windowStartKey(key)}
+	created, err := initScript.Run(ctx, r.client, keys, count, left.Milliseconds(), start.UnixMilli()).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis init script error: %w", err)
+	}
+	return created == 1, nil
 }
 
-func (r *RedisStore) Get(key string) (int64, time.Time, error) {
-	ctx := context.Background()
-	now := time.Now()
+// Get reads key's count and expiry, abandoning the call once ctx is done.
+func (r *RedisStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	if r.serializer != nil {
+		entry, left, _, err := r.readEntry(ctx, r.client, key)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("redis get error: %w", err)
+		}
+		if left <= 0 {
+			return 0, time.Time{}, nil
+		}
+		return entry.Count, now.Add(left), nil
+	}
 
 	pipe := r.client.Pipeline()
 
 	getCmd := pipe.Get(ctx, key)
 	ttlCmd := pipe.TTL(ctx, key)
 
-	_, err := pipe.Exec(ctx)
+	_, err = pipe.Exec(ctx)
 	if err == redis.Nil {
 		return 0, time.Time{}, nil
 	}
@@ -77,3 +305,134 @@ func (r *RedisStore) Get(key string) (int64, time.Time, error) {
 	expiry := now.Add(currentTTL)
 	return counter, expiry, nil
 }
+
+func (r *RedisStore) GetMany(ctx context.Context, keys []string) ([]limiter.StoreEntry, error) {
+	now, err := r.now(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := r.client.Pipeline()
+	getCmds := make([]*redis.StringCmd, len(keys))
+	ttlCmds := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		getCmds[i] = pipe.Get(ctx, key)
+		ttlCmds[i] = pipe.PTTL(ctx, key)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("redis pipeline error: %w", err)
+	}
+
+	entries := make([]limiter.StoreEntry, len(keys))
+	for i := range keys {
+		data, err := getCmds[i].Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redis get error: %w", err)
+		}
+
+		left := ttlCmds[i].Val()
+		if left <= 0 {
+			continue
+		}
+
+		var count int64
+		if r.serializer != nil {
+			entry, err := r.serializer.Unmarshal(data)
+			if err != nil {
+				return nil, fmt.Errorf("redis get error: %w", err)
+			}
+			count = entry.Count
+		} else {
+			count, err = strconv.ParseInt(string(data), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse counter error: %w", err)
+			}
+		}
+
+		entries[i] = limiter.StoreEntry{Count: count, Expiry: now.Add(left)}
+	}
+	return entries, nil
+}
+
+// internalKey reports whether key holds a window start, request log, token
+// bucket or GCRA TAT rather than a counter.
+func internalKey(key string) bool {
+	for _, prefix := range []string{windowStartKey(""), logKey(""), bucketKey(""), gcraKey("")} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys returns the sorted keys starting with prefix, found with SCAN so the
+// server is never blocked. Internal keys such as request logs are left out,
+// and keys that expire during the scan may still be listed.
+func (r *RedisStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	seen := map[string]bool{}
+	iter := r.client.Scan(ctx, 0, escapeGlob(prefix)+"*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		if key := iter.Val(); !internalKey(key) {
+			seen[key] = true
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("redis scan error: %w", err)
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// scanCount is the SCAN batch size hint.
+const scanCount = 1000
+
+// escapeGlob quotes the characters SCAN's MATCH treats as a pattern.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Time returns the Redis server's clock.
+func (r *RedisStore) Time(ctx context.Context) (time.Time, error) {
+	t, err := r.client.Time(ctx).Result()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("redis time error: %w", err)
+	}
+	return t.UTC(), nil
+}
+
+// ClockSkew reports how far the local clock is ahead of the Redis server's,
+// ignoring the round trip. Instances whose skew exceeds the tolerance they
+// can afford should use WithServerTime.
+func (r *RedisStore) ClockSkew(ctx context.Context) (time.Duration, error) {
+	server, err := r.Time(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return time.Now().Sub(server), nil
+}
+
+// now is the time window math is based on: the Redis server's clock with
+// WithServerTime, the local clock otherwise.
+func (r *RedisStore) now(ctx context.Context) (time.Time, error) {
+	if !r.serverTime {
+		return time.Now().UTC(), nil
+	}
+	return r.Time(ctx)
+}
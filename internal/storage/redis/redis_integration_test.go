@@ -0,0 +1,425 @@
+//go:build integration
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skipf("redis not available at %s: %v", addr, err)
+	}
+	if err := client.FlushDB(ctx).Err(); err != nil {
+		t.Fatalf("failed to flush redis: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// genericStore hides IncrementWithResult so the limiter takes the generic path.
+type genericStore struct {
+	store *RedisStore
+}
+
+func (g genericStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	return g.store.Increment(ctx, key, ttl)
+}
+
+func (g genericStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	return g.store.Get(ctx, key)
+}
+
+func (g genericStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return g.store.SetIfAbsent(ctx, key, count, ttl)
+}
+
+func TestIncrementWithResultMatchesGenericPath(t *testing.T) {
+	client := newTestClient(t)
+	store := NewRedisStore(client)
+	cfgs := map[string]config.ClientConfig{
+		"script":  {Limit: 3, Window: time.Minute},
+		"generic": {Limit: 3, Window: time.Minute},
+	}
+
+	scripted := limiter.NewLimiter(store, cfgs)
+	generic := limiter.NewLimiter(genericStore{store: store}, cfgs)
+
+	for i := 0; i < 5; i++ {
+		a, err := scripted.AllowResult("script")
+		if err != nil {
+			t.Fatalf("scripted path error: %v", err)
+		}
+		b, err := generic.AllowResult("generic")
+		if err != nil {
+			t.Fatalf("generic path error: %v", err)
+		}
+
+		if a.Allowed != b.Allowed || a.Remaining != b.Remaining || a.Count != b.Count {
+			t.Fatalf("request %d: scripted %+v differs from generic %+v", i, a, b)
+		}
+		if a.ResetAt.Sub(b.ResetAt).Abs() > time.Second {
+			t.Fatalf("request %d: reset times diverge: %v vs %v", i, a.ResetAt, b.ResetAt)
+		}
+	}
+}
+
+func TestSerializedEntries(t *testing.T) {
+	client := newTestClient(t)
+
+	for name, s := range map[string]Serializer{"json": JSONSerializer{}, "binary": BinarySerializer{}} {
+		t.Run(name, func(t *testing.T) {
+			store := NewRedisStore(client, WithSerializer(s))
+			key := "rate:serialized-" + name
+
+			for i := int64(1); i <= 3; i++ {
+				count, expiry, err := store.Increment(context.Background(), key, time.Minute)
+				if err != nil {
+					t.Fatalf("increment: %v", err)
+				}
+				if count != i || expiry.IsZero() {
+					t.Fatalf("expected count %d with expiry, got %d %v", i, count, expiry)
+				}
+			}
+
+			raw, err := client.Get(context.Background(), key).Bytes()
+			if err != nil {
+				t.Fatalf("get raw: %v", err)
+			}
+			entry, err := s.Unmarshal(raw)
+			if err != nil {
+				t.Fatalf("decode raw: %v", err)
+			}
+			if entry.Count != 3 || entry.WindowStart == 0 {
+				t.Fatalf("unexpected stored entry %+v", entry)
+			}
+
+			count, _, err := store.Get(context.Background(), key)
+			if err != nil || count != 3 {
+				t.Fatalf("expected Get to return 3, got %d %v", count, err)
+			}
+		})
+	}
+}
+
+func TestGetManyMatchesGet(t *testing.T) {
+	client := newTestClient(t)
+	store := NewRedisStore(client)
+
+	store.Increment(context.Background(), "rate:a", time.Minute)
+	store.Increment(context.Background(), "rate:a", time.Minute)
+	store.Increment(context.Background(), "rate:b", time.Minute)
+
+	keys := []string{"rate:b", "rate:missing", "rate:a"}
+	entries, err := store.GetMany(context.Background(), keys)
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	for i, key := range keys {
+		count, expiry, err := store.Get(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Get %s: %v", key, err)
+		}
+		if entries[i].Count != count || entries[i].Expiry.Sub(expiry).Abs() > time.Second {
+			t.Errorf("%s: GetMany %+v differs from Get (%d, %v)", key, entries[i], count, expiry)
+		}
+	}
+}
+
+func TestResetAtStableWithinWindow(t *testing.T) {
+	client := newTestClient(t)
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 10, Window: time.Minute}}
+
+	stores := map[string]*RedisStore{
+		"counter": NewRedisStore(client),
+		"json":    NewRedisStore(client, WithSerializer(JSONSerializer{})),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			client.FlushDB(context.Background())
+			l := limiter.NewLimiter(store, cfgs)
+
+			first, err := l.AllowResult("c1")
+			if err != nil {
+				t.Fatalf("allow: %v", err)
+			}
+			for i := 0; i < 3; i++ {
+				time.Sleep(20 * time.Millisecond)
+				res, err := l.AllowResult("c1")
+				if err != nil {
+					t.Fatalf("allow: %v", err)
+				}
+				if !res.ResetAt.Equal(first.ResetAt) {
+					t.Fatalf("expected reset time %v to stay constant, got %v", first.ResetAt, res.ResetAt)
+				}
+			}
+		})
+	}
+}
+
+func TestResetAcrossReplicas(t *testing.T) {
+	client := newTestClient(t)
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Minute}}
+
+	for _, opts := range [][]Option{nil, {WithSerializer(JSONSerializer{})}} {
+		for name, reset := range map[string]func(*limiter.Limiter, string) error{
+			"delete": (*limiter.Limiter).Reset,
+			"zero":   (*limiter.Limiter).ResetWindow,
+		} {
+			client.FlushDB(context.Background())
+			a := limiter.NewLimiter(NewRedisStore(client, opts...), cfgs)
+			b := limiter.NewLimiter(NewRedisStore(client, opts...), cfgs)
+
+			for i := 0; i < 4; i++ {
+				a.Allow("c1")
+			}
+
+			var wg sync.WaitGroup
+			for _, l := range []*limiter.Limiter{a, b, a, b} {
+				wg.Add(1)
+				go func(l *limiter.Limiter) {
+					defer wg.Done()
+					if err := reset(l, "c1"); err != nil {
+						t.Errorf("%s: reset: %v", name, err)
+					}
+				}(l)
+			}
+			wg.Wait()
+
+			for _, l := range []*limiter.Limiter{a, b} {
+				if res, err := l.Peek("c1"); err != nil || res.Remaining != 3 {
+					t.Fatalf("%s: expected every replica to see a full quota, got %+v err=%v", name, res, err)
+				}
+			}
+			if res, err := b.AllowResult("c1"); err != nil || !res.Allowed || res.Count != 1 {
+				t.Fatalf("%s: expected counting to restart, got %+v err=%v", name, res, err)
+			}
+		}
+	}
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	client := newTestClient(t)
+
+	for _, tc := range []struct {
+		name  string
+		store *RedisStore
+	}{
+		{"counter", NewRedisStore(client)},
+		{"json", NewRedisStore(client, WithSerializer(JSONSerializer{}))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			key := "rate:init-" + tc.name
+
+			created, err := tc.store.SetIfAbsent(context.Background(), key, 3, time.Minute)
+			if err != nil || !created {
+				t.Fatalf("expected the window to be created, got %v, %v", created, err)
+			}
+			if count, expiry, _ := tc.store.Get(context.Background(), key); count != 3 || time.Until(expiry) <= 0 {
+				t.Fatalf("expected count 3 in a live window, got %d at %v", count, expiry)
+			}
+
+			d, err := tc.store.IncrementWithResult(context.Background(), key, 1, 10, time.Minute)
+			if err != nil || d.Count != 4 {
+				t.Fatalf("expected increments to continue the window, got %+v, %v", d, err)
+			}
+
+			created, err = tc.store.SetIfAbsent(context.Background(), key, 0, time.Minute)
+			if err != nil || created {
+				t.Fatalf("expected the existing window to be kept, got %v, %v", created, err)
+			}
+			if count, _, _ := tc.store.Get(context.Background(), key); count != 4 {
+				t.Fatalf("expected count 4 untouched, got %d", count)
+			}
+		})
+	}
+}
+
+func TestIncrementAlwaysSetsTTL(t *testing.T) {
+	client := newTestClient(t)
+	store := NewRedisStore(client)
+	ctx := context.Background()
+
+	for _, n := range []int64{1, 3} {
+		key := fmt.Sprintf("rate:ttl-%d", n)
+		count, expiry, err := store.IncrementBy(context.Background(), key, n, time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if count != n {
+			t.Fatalf("expected count %d, got %d", n, count)
+		}
+		ttl, err := client.PTTL(ctx, key).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ttl <= 0 || ttl > time.Minute {
+			t.Fatalf("expected a positive TTL after the first increment, got %v", ttl)
+		}
+		if left := time.Until(expiry); left <= 0 || left > time.Minute {
+			t.Fatalf("expected expiry within the window, got %v", left)
+		}
+	}
+
+	// A counter left without a TTL by an older, non-atomic version heals on
+	// its next hit.
+	if err := client.Set(ctx, "rate:stuck", 7, 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count, _, err := store.Increment(context.Background(), "rate:stuck", time.Minute); err != nil || count != 8 {
+		t.Fatalf("expected count 8, got %d, %v", count, err)
+	}
+	if ttl := client.PTTL(ctx, "rate:stuck").Val(); ttl <= 0 {
+		t.Fatalf("expected the stuck counter to get a TTL, got %v", ttl)
+	}
+
+	// Flushing the script cache exercises the NOSCRIPT fallback to EVAL.
+	if err := client.ScriptFlush(ctx).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count, _, err := store.Increment(context.Background(), "rate:flushed", time.Minute); err != nil || count != 1 {
+		t.Fatalf("expected EVAL fallback after SCRIPT FLUSH, got %d, %v", count, err)
+	}
+	if ttl := client.PTTL(ctx, "rate:flushed").Val(); ttl <= 0 {
+		t.Fatalf("expected a TTL after the EVAL fallback, got %v", ttl)
+	}
+}
+
+func TestRequestLog(t *testing.T) {
+	client := newTestClient(t)
+	store := NewRedisStore(client)
+	ctx := context.Background()
+	const window = time.Minute
+
+	if count, oldest, ok, err := store.AppendLog(context.Background(), "rate:c1", 2, 3, window); err != nil || !ok || count != 2 || oldest.IsZero() {
+		t.Fatalf("expected 2 logged, got %d, %v, %v, %v", count, oldest, ok, err)
+	}
+	if count, _, ok, err := store.AppendLog(context.Background(), "rate:c1", 2, 3, window); err != nil || ok || count != 2 {
+		t.Fatalf("expected a cost of 2 not to fit, got %d, %v, %v", count, ok, err)
+	}
+	if count, _, ok, err := store.AppendLog(context.Background(), "rate:c1", 1, 3, window); err != nil || !ok || count != 3 {
+		t.Fatalf("expected a cost of 1 to fit, got %d, %v, %v", count, ok, err)
+	}
+	if count, oldest, err := store.CountLog(context.Background(), "rate:c1", window); err != nil || count != 3 || time.Since(oldest) > time.Second {
+		t.Fatalf("expected 3 counted, got %d oldest %v, %v", count, oldest, err)
+	}
+	if ttl := client.PTTL(ctx, "log:rate:c1").Val(); ttl <= 0 || ttl > window {
+		t.Fatalf("expected the log to expire with its window, got %v", ttl)
+	}
+
+	// A short window empties as its requests age out.
+	store.AppendLog(context.Background(), "rate:c2", 1, 5, 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	if count, oldest, err := store.CountLog(context.Background(), "rate:c2", 50*time.Millisecond); err != nil || count != 0 || !oldest.IsZero() {
+		t.Fatalf("expected an empty window, got %d, %v, %v", count, oldest, err)
+	}
+
+	keys, err := store.Keys(context.Background(), "")
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("expected logs hidden from Keys, got %v, %v", keys, err)
+	}
+	if err := store.Delete(context.Background(), "rate:c1"); err != nil {
+		t.Fatal(err)
+	}
+	if count, _, _ := store.CountLog(context.Background(), "rate:c1", window); count != 0 {
+		t.Fatalf("expected Delete to clear the log, got %d", count)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	client := newTestClient(t)
+	store := NewRedisStore(client)
+	ctx := context.Background()
+	const ttl = 5 * time.Second
+
+	if tokens, ok, err := store.TakeTokens(context.Background(), "rate:c1", 3, 5, 0.5, ttl); err != nil || !ok || tokens != 2 {
+		t.Fatalf("expected 3 taken from a full bucket, got %v, %v, %v", tokens, ok, err)
+	}
+	if tokens, ok, err := store.TakeTokens(context.Background(), "rate:c1", 3, 5, 0.5, ttl); err != nil || ok || tokens < 2 || tokens > 2.1 {
+		t.Fatalf("expected 3 not to fit, got %v, %v, %v", tokens, ok, err)
+	}
+	if tokens, err := store.PeekTokens(context.Background(), "rate:c1", 5, 0.5); err != nil || tokens < 2 || tokens > 2.1 {
+		t.Fatalf("expected about 2 tokens, got %v, %v", tokens, err)
+	}
+	if ttl := client.PTTL(ctx, "tb:rate:c1").Val(); ttl <= 0 || ttl > 5*time.Second {
+		t.Fatalf("expected the bucket to expire once full, got %v", ttl)
+	}
+
+	// A fast refill brings the bucket back within the test.
+	store.TakeTokens(context.Background(), "rate:c2", 5, 5, 100, ttl)
+	time.Sleep(100 * time.Millisecond)
+	if tokens, err := store.PeekTokens(context.Background(), "rate:c2", 5, 100); err != nil || tokens != 5 {
+		t.Fatalf("expected a refilled bucket, got %v, %v", tokens, err)
+	}
+
+	keys, err := store.Keys(context.Background(), "")
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("expected buckets hidden from Keys, got %v, %v", keys, err)
+	}
+	if err := store.Delete(context.Background(), "rate:c1"); err != nil {
+		t.Fatal(err)
+	}
+	if tokens, _ := store.PeekTokens(context.Background(), "rate:c1", 5, 0.5); tokens != 5 {
+		t.Fatalf("expected Delete to refill the bucket, got %v", tokens)
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	client := newTestClient(t)
+	store := NewRedisStore(client)
+	ctx := context.Background()
+	const interval, tolerance = 10 * time.Second, 30 * time.Second
+
+	before := time.Now()
+	tat, ok, err := store.ArriveGCRA(context.Background(), "rate:c1", 2, interval, tolerance)
+	if err != nil || !ok || tat.Before(before.Add(20*time.Second).Truncate(time.Microsecond)) || tat.After(time.Now().Add(20*time.Second)) {
+		t.Fatalf("expected 2 admitted with the TAT 20s out, got %v, %v, %v", tat, ok, err)
+	}
+	if again, ok, err := store.ArriveGCRA(context.Background(), "rate:c1", 2, interval, tolerance); err != nil || ok || !again.Equal(tat) {
+		t.Fatalf("expected 2 more not to fit, got %v, %v, %v", again, ok, err)
+	}
+	if got, err := store.GetTAT(context.Background(), "rate:c1"); err != nil || !got.Equal(tat) {
+		t.Fatalf("expected the stored TAT %v, got %v, %v", tat, got, err)
+	}
+	if ttl := client.PTTL(ctx, "gcra:rate:c1").Val(); ttl <= 0 || ttl > 20*time.Second {
+		t.Fatalf("expected the TAT to expire when it passes, got %v", ttl)
+	}
+
+	// Fractional microsecond intervals still add up.
+	for i := 0; i < 3; i++ {
+		store.ArriveGCRA(context.Background(), "rate:c2", 1, time.Second/3, time.Second)
+	}
+	if tat, _ := store.GetTAT(context.Background(), "rate:c2"); tat.Sub(time.Now()) > time.Second || tat.Sub(before) < time.Second {
+		t.Fatalf("expected the TAT about 1s out, got %v", tat.Sub(before))
+	}
+
+	keys, err := store.Keys(context.Background(), "")
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("expected TATs hidden from Keys, got %v, %v", keys, err)
+	}
+	if err := store.Delete(context.Background(), "rate:c1"); err != nil {
+		t.Fatal(err)
+	}
+	if tat, _ := store.GetTAT(context.Background(), "rate:c1"); !tat.IsZero() {
+		t.Fatalf("expected Delete to clear the TAT, got %v", tat)
+	}
+}
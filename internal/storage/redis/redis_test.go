@@ -0,0 +1,472 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/middleware"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+	"github.com/redis/go-redis/v9"
+)
+
+// scriptHook answers decisionScript calls in-process, counting every command
+// and pipeline that would have been a round trip to Redis.
+type scriptHook struct {
+	counts     map[string]int64
+	roundTrips int
+	serverTime time.Time
+}
+
+func (h *scriptHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, fmt.Errorf("unexpected dial to %s", addr)
+	}
+}
+
+func (h *scriptHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.roundTrips++
+		if cmd.Name() == "time" && !h.serverTime.IsZero() {
+			cmd.(*redis.TimeCmd).SetVal(h.serverTime)
+			return nil
+		}
+		if cmd.Name() != "evalsha" {
+			return fmt.Errorf("unexpected command %s", cmd.Name())
+		}
+
+		// evalsha sha numkeys key startKey n limit ttl now newTTL newStart
+		args := cmd.Args()
+		key := args[3].(string)
+		n, limit := args[5].(int64), int64(args[6].(int))
+		ttl, now := args[9].(int64), args[10].(int64)
+
+		created := int64(0)
+		if _, ok := h.counts[key]; !ok {
+			created = 1
+		}
+		h.counts[key] += n
+		count := h.counts[key]
+		allowed := int64(0)
+		if count <= limit {
+			allowed = 1
+		}
+		cmd.(*redis.Cmd).SetVal([]interface{}{count, ttl, allowed, max(limit-count, 0), now, created})
+		return nil
+	}
+}
+
+func (h *scriptHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.roundTrips++
+		return fmt.Errorf("unexpected pipeline of %d commands", len(cmds))
+	}
+}
+
+func newHookedStore(opts ...Option) (*RedisStore, *scriptHook) {
+	hook := &scriptHook{counts: map[string]int64{}}
+	client := redis.NewClient(&redis.Options{Addr: "redis.invalid:6379"})
+	client.AddHook(hook)
+	return NewRedisStore(client, opts...), hook
+}
+
+func TestSingleRoundTripPerRequest(t *testing.T) {
+	store, hook := newHookedStore()
+	l := limiter.NewLimiter(store, map[string]config.ClientConfig{"c1": {Limit: 2, Window: time.Minute}})
+	mw := middleware.NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	handler := mw.Handler(func(w http.ResponseWriter, r *http.Request) {})
+
+	wantCodes := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	for i, want := range wantCodes {
+		before := hook.roundTrips
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Client-ID", "c1")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != want {
+			t.Fatalf("request %d: expected %d, got %d", i+1, want, rec.Code)
+		}
+		if got := hook.roundTrips - before; got != 1 {
+			t.Fatalf("request %d: expected one round trip, got %d", i+1, got)
+		}
+		for _, h := range []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"} {
+			if rec.Header().Get(h) == "" {
+				t.Fatalf("request %d: expected %s to be populated", i+1, h)
+			}
+		}
+	}
+}
+
+func TestServerTimeWindows(t *testing.T) {
+	// Far enough from the local clock that any mix-up shows.
+	server := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	store, hook := newHookedStore(WithServerTime())
+	hook.serverTime = server
+
+	d, err := store.IncrementWithResult(context.Background(), "rate:c1", 1, 5, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !d.WindowStart.Equal(server) {
+		t.Errorf("expected window start from Redis time %v, got %v", server, d.WindowStart)
+	}
+	if want := server.Add(time.Minute); !d.Expiry.Equal(want) {
+		t.Errorf("expected expiry %v, got %v", want, d.Expiry)
+	}
+
+	skew, err := store.ClockSkew(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if skew >= 0 {
+		t.Errorf("expected the local clock to be behind the server's, got skew %v", skew)
+	}
+}
+
+func TestStaggeredWindows(t *testing.T) {
+	const ttl = time.Minute
+	server := time.Date(2030, 1, 1, 12, 0, 30, 0, time.UTC)
+	store, hook := newHookedStore(WithServerTime(), WithStaggeredWindows())
+	hook.serverTime = server
+	mem := memory.NewMemoryStore(memory.WithStaggeredWindows(), memory.WithClock(func() time.Time { return server }))
+
+	var phases []time.Duration
+	for _, key := range []string{"rate:client-a", "rate:client-b"} {
+		d, err := store.IncrementWithResult(context.Background(), key, 1, 5, ttl)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if d.WindowStart.After(server) || server.Sub(d.WindowStart) >= ttl || !d.Expiry.Equal(d.WindowStart.Add(ttl)) {
+			t.Fatalf("expected a window of %v covering %v, got %v to %v", ttl, server, d.WindowStart, d.Expiry)
+		}
+		// Redis keeps window starts in milliseconds.
+		_, memStart, _, _ := mem.IncrementWindow(context.Background(), key, 1, ttl)
+		if memStart = memStart.Truncate(time.Millisecond); !d.WindowStart.Equal(memStart) {
+			t.Errorf("expected %s to start at %v like the memory store, got %v", key, memStart, d.WindowStart)
+		}
+		phases = append(phases, d.WindowStart.Sub(d.WindowStart.Truncate(ttl)))
+	}
+	if phases[0] == phases[1] {
+		t.Errorf("expected clients to get different phases, both got %v", phases[0])
+	}
+}
+
+func TestLocalTimeWindowsByDefault(t *testing.T) {
+	store, hook := newHookedStore()
+	hook.serverTime = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	before := time.Now()
+	d, err := store.IncrementWithResult(context.Background(), "rate:c1", 1, 5, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.WindowStart.Before(before.Truncate(time.Millisecond)) || d.WindowStart.After(time.Now()) {
+		t.Errorf("expected window start from the local clock, got %v", d.WindowStart)
+	}
+	if hook.roundTrips != 1 {
+		t.Errorf("expected no TIME call without WithServerTime, got %d round trips", hook.roundTrips)
+	}
+}
+
+func BenchmarkMiddlewareRedisHotPath(b *testing.B) {
+	store, _ := newHookedStore()
+	l := limiter.NewLimiter(store, map[string]config.ClientConfig{"c1": {Limit: 1 << 30, Window: time.Minute}})
+	mw := middleware.NewRateLimitMiddleware(l, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	handler := mw.Handler(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Client-ID", "c1")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler(httptest.NewRecorder(), req)
+	}
+}
+
+// initHook emulates SET NX, directly or through initScript, over a set of
+// existing keys.
+type initHook struct {
+	existing map[string]bool
+	scripts  int
+	// ttl and start are the last script's window.
+	ttl, start int64
+	serverTime time.Time
+}
+
+func (h *initHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *initHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		args := cmd.Args()
+		switch cmd.Name() {
+		case "time":
+			cmd.(*redis.TimeCmd).SetVal(h.serverTime)
+		case "evalsha":
+			// evalsha sha numkeys key startKey count ttl start
+			h.scripts++
+			key := args[3].(string)
+			h.ttl, h.start = args[6].(int64), args[7].(int64)
+			created := !h.existing[key]
+			h.existing[key] = true
+			cmd.(*redis.Cmd).SetVal(map[bool]int64{true: 1, false: 0}[created])
+		case "set":
+			// set key value px ttl nx
+			key := args[1].(string)
+			// go-redis reports the nil reply of a failed NX as false.
+			cmd.(*redis.BoolCmd).SetVal(!h.existing[key])
+			h.existing[key] = true
+		default:
+			return fmt.Errorf("unexpected command %s", cmd.Name())
+		}
+		return nil
+	}
+}
+
+func (h *initHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestSetIfAbsentCommands(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		opts        []Option
+		wantScripts int
+	}{
+		{"counter", nil, 2},
+		{"serialized", []Option{WithSerializer(JSONSerializer{})}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hook := &initHook{existing: map[string]bool{}}
+			client := redis.NewClient(&redis.Options{Addr: "redis.invalid:6379"})
+			client.AddHook(hook)
+			store := NewRedisStore(client, tc.opts...)
+
+			if created, err := store.SetIfAbsent(context.Background(), "rate:c1", 1, time.Minute); err != nil || !created {
+				t.Fatalf("expected the window to be created, got %v, %v", created, err)
+			}
+			if created, err := store.SetIfAbsent(context.Background(), "rate:c1", 1, time.Minute); err != nil || created {
+				t.Fatalf("expected the existing window to be kept, got %v, %v", created, err)
+			}
+			if hook.scripts != tc.wantScripts {
+				t.Errorf("expected %d script calls, got %d", tc.wantScripts, hook.scripts)
+			}
+		})
+	}
+}
+
+func TestRestoreKeepsExpiry(t *testing.T) {
+	server := time.Date(2030, 1, 1, 12, 0, 30, 0, time.UTC)
+	hook := &initHook{existing: map[string]bool{}, serverTime: server}
+	client := redis.NewClient(&redis.Options{Addr: "redis.invalid:6379"})
+	client.AddHook(hook)
+	store := NewRedisStore(client, WithServerTime(), WithStaggeredWindows())
+
+	expiry := server.Add(59*time.Minute + 500*time.Millisecond)
+	if created, err := store.Restore(context.Background(), "rate:c1", 4, expiry); err != nil || !created {
+		t.Fatalf("expected the window to be restored, got %v, %v", created, err)
+	}
+	if want := (59*time.Minute + 500*time.Millisecond).Milliseconds(); hook.ttl != want || hook.start != server.UnixMilli() {
+		t.Errorf("expected ttl %dms from now, got %dms from %d", want, hook.ttl, hook.start)
+	}
+	if created, err := store.Restore(context.Background(), "rate:c2", 4, server); err != nil || created {
+		t.Errorf("expected a passed expiry to be skipped, got %v, %v", created, err)
+	}
+}
+
+// scanHook answers SCAN with pages of keys, recording the MATCH pattern.
+type scanHook struct {
+	pages [][]string
+	match string
+}
+
+func (h *scanHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *scanHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() != "scan" {
+			return fmt.Errorf("unexpected command %s", cmd.Name())
+		}
+		// scan cursor match pattern count n
+		args := cmd.Args()
+		h.match = args[3].(string)
+		cursor := args[1].(uint64)
+		next := cursor + 1
+		if int(next) == len(h.pages) {
+			next = 0
+		}
+		cmd.(*redis.ScanCmd).SetVal(h.pages[cursor], next)
+		return nil
+	}
+}
+
+func (h *scanHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestKeys(t *testing.T) {
+	hook := &scanHook{pages: [][]string{
+		{"prod:rate:b", "ws:prod:rate:b"},
+		{"prod:rate:a", "prod:rate:b"},
+	}}
+	client := redis.NewClient(&redis.Options{Addr: "redis.invalid:6379"})
+	client.AddHook(hook)
+
+	keys, err := NewRedisStore(client).Keys(context.Background(), "prod:rate:")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "prod:rate:a" || keys[1] != "prod:rate:b" {
+		t.Fatalf("expected sorted, deduplicated counter keys, got %v", keys)
+	}
+	if hook.match != "prod:rate:*" {
+		t.Fatalf("unexpected match pattern %q", hook.match)
+	}
+
+	// Without a prefix, companion keys must not be mistaken for counters.
+	hook.pages = [][]string{{"rate:a", "ws:rate:a", "log:rate:b"}}
+	keys, err = NewRedisStore(client).Keys(context.Background(), "")
+	if err != nil || len(keys) != 1 || keys[0] != "rate:a" {
+		t.Fatalf("expected only the counter key, got %v, %v", keys, err)
+	}
+
+	if got := escapeGlob(`a*b?[c]\`); got != `a\*b\?\[c\]\\` {
+		t.Fatalf("unexpected escaped pattern %q", got)
+	}
+}
+
+// noScriptError is the reply to EVALSHA for a script Redis has not cached.
+type noScriptError struct{}
+
+func (noScriptError) Error() string { return "NOSCRIPT No matching script. Please use EVAL." }
+func (noScriptError) RedisError()   {}
+
+// incrementHook answers incrementScript, replying NOSCRIPT to the first
+// EVALSHA like a Redis whose script cache was flushed.
+type incrementHook struct {
+	commands []string
+	loaded   bool
+}
+
+func (h *incrementHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *incrementHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.commands = append(h.commands, cmd.Name())
+		switch cmd.Name() {
+		case "evalsha":
+			if !h.loaded {
+				cmd.SetErr(noScriptError{})
+				return cmd.Err()
+			}
+		case "eval":
+			h.loaded = true
+		default:
+			return fmt.Errorf("unexpected command %s", cmd.Name())
+		}
+
+		// eval(sha) script numkeys key n ttl
+		args := cmd.Args()
+		if args[2] != 1 {
+			return fmt.Errorf("expected one key, got %v", args[2])
+		}
+		cmd.(*redis.Cmd).SetVal([]interface{}{args[4], args[5]})
+		return nil
+	}
+}
+
+func (h *incrementHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		return fmt.Errorf("unexpected pipeline of %d commands", len(cmds))
+	}
+}
+
+func TestIncrementIsOneScript(t *testing.T) {
+	hook := &incrementHook{}
+	client := redis.NewClient(&redis.Options{Addr: "redis.invalid:6379"})
+	client.AddHook(hook)
+	store := NewRedisStore(client)
+
+	before := time.Now()
+	count, expiry, err := store.IncrementBy(context.Background(), "rate:c1", 3, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected count 3, got %d", count)
+	}
+	if expiry.Before(before.Add(time.Minute)) || expiry.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("expected expiry a window out, got %v", expiry.Sub(before))
+	}
+	if want := []string{"evalsha", "eval"}; fmt.Sprint(hook.commands) != fmt.Sprint(want) {
+		t.Fatalf("expected %v on a cold script cache, got %v", want, hook.commands)
+	}
+
+	hook.commands = nil
+	if _, _, err := store.Increment(context.Background(), "rate:c1", time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"evalsha"}; fmt.Sprint(hook.commands) != fmt.Sprint(want) {
+		t.Fatalf("expected a single EVALSHA once cached, got %v", hook.commands)
+	}
+}
+
+type ctxKey struct{}
+
+// ctxHook records the request ID carried by each command's context and fails
+// commands whose context is done, as a real connection would.
+type ctxHook struct {
+	scriptHook
+	seen []any
+}
+
+func (h *ctxHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	process := h.scriptHook.ProcessHook(next)
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.seen = append(h.seen, ctx.Value(ctxKey{}))
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return process(ctx, cmd)
+	}
+}
+
+func TestRequestContextReachesRedis(t *testing.T) {
+	hook := &ctxHook{scriptHook: scriptHook{counts: map[string]int64{}}}
+	client := redis.NewClient(&redis.Options{Addr: "redis.invalid:6379"})
+	client.AddHook(hook)
+	l := limiter.New(NewRedisStore(client), limiter.WithFailurePolicy(limiter.FailOpen))
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "r1"))
+	defer cancel()
+	if res, err := l.AllowRequest(limiter.Request{Client: "c1", Context: ctx}); err != nil || !res.Allowed {
+		t.Fatalf("unexpected result %+v, %v", res, err)
+	}
+	if len(hook.seen) != 1 || hook.seen[0] != "r1" {
+		t.Fatalf("expected the request's context on the command, got %v", hook.seen)
+	}
+
+	cancel()
+	if _, err := l.AllowRequest(limiter.Request{Client: "c1", Context: ctx}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled request to fail rather than fail open, got %v", err)
+	}
+
+	hook.seen = nil
+	if _, err := l.AllowResult("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(hook.seen) != 1 || hook.seen[0] != nil {
+		t.Fatalf("expected calls without a request context to run in the background, got %v", hook.seen)
+	}
+}
@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// Entry is the stored state of one rate limit window when a Serializer is
+// configured. WindowStart is in Unix milliseconds so non-Go consumers can
+// read it without time zone handling.
+type Entry struct {
+	Count       int64 `json:"count"`
+	WindowStart int64 `json:"window_start_ms"`
+}
+
+type Serializer interface {
+	Marshal(e Entry) ([]byte, error)
+	Unmarshal(data []byte) (Entry, error)
+}
+
+// JSONSerializer stores entries as {"count":N,"window_start_ms":T}.
+type JSONSerializer struct{}
+
+func (JSONSerializer) Marshal(e Entry) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (JSONSerializer) Unmarshal(data []byte) (Entry, error) {
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Entry{}, fmt.Errorf("decode json entry: %w", err)
+	}
+	return e, nil
+}
+
+// BinarySerializer stores entries as 16 bytes: count then window start, both
+// big-endian int64.
+type BinarySerializer struct{}
+
+const binaryEntrySize = 16
+
+func (BinarySerializer) Marshal(e Entry) ([]byte, error) {
+	buf := make([]byte, binaryEntrySize)
+	binary.BigEndian.PutUint64(buf[0:8], uint64(e.Count))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(e.WindowStart))
+	return buf, nil
+}
+
+func (BinarySerializer) Unmarshal(data []byte) (Entry, error) {
+	if len(data) != binaryEntrySize {
+		return Entry{}, fmt.Errorf("decode binary entry: expected %d bytes, got %d", binaryEntrySize, len(data))
+	}
+	return Entry{
+		Count:       int64(binary.BigEndian.Uint64(data[0:8])),
+		WindowStart: int64(binary.BigEndian.Uint64(data[8:16])),
+	}, nil
+}
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"testing"
+)
+
+func TestSerializerRoundTrip(t *testing.T) {
+	serializers := map[string]Serializer{
+		"json":   JSONSerializer{},
+		"binary": BinarySerializer{},
+	}
+	entries := []Entry{
+		{},
+		{Count: 1, WindowStart: 1729681800000},
+		{Count: 1 << 40, WindowStart: 1729681860123},
+		{Count: -1, WindowStart: -5},
+	}
+
+	for name, s := range serializers {
+		t.Run(name, func(t *testing.T) {
+			for _, want := range entries {
+				data, err := s.Marshal(want)
+				if err != nil {
+					t.Fatalf("marshal %+v: %v", want, err)
+				}
+				got, err := s.Unmarshal(data)
+				if err != nil {
+					t.Fatalf("unmarshal %+v: %v", want, err)
+				}
+				if got != want {
+					t.Errorf("round trip: expected %+v, got %+v", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSerializerFormat(t *testing.T) {
+	data, err := JSONSerializer{}.Marshal(Entry{Count: 3, WindowStart: 1729681800000})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(data) != `{"count":3,"window_start_ms":1729681800000}` {
+		t.Errorf("unexpected json format: %s", data)
+	}
+}
+
+func TestSerializerInvalidInput(t *testing.T) {
+	if _, err := (JSONSerializer{}).Unmarshal([]byte("42")); err == nil {
+		t.Error("expected json error for a legacy integer value")
+	}
+	if _, err := (BinarySerializer{}).Unmarshal([]byte("42")); err == nil {
+		t.Error("expected binary error for a short value")
+	}
+}
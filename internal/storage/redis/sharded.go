@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/redis/go-redis/v9"
+)
+
+// Shard is one Redis server of a ShardedStore. A shard with twice the Weight
+// of another receives about twice as many keys; weights below 1 count as 1.
+type Shard struct {
+	Client *redis.Client
+	Weight int
+}
+
+// ShardedStore spreads keys across several Redis servers. Each key always maps
+// to the same shard, so a client's counter and its window start stay together.
+// Keys are placed by weighted rendezvous hashing, so appending a shard only
+// moves keys onto the new shard.
+type ShardedStore struct {
+	shards  []*RedisStore
+	weights []float64
+}
+
+// NewShardedStore creates a store over shards, applying opts to every shard.
+// Placement depends on each shard's position, so all instances must list the
+// same shards in the same order with the same weights.
+func NewShardedStore(shards []Shard, opts ...Option) *ShardedStore {
+	s := &ShardedStore{
+		shards:  make([]*RedisStore, len(shards)),
+		weights: make([]float64, len(shards)),
+	}
+	for i, shard := range shards {
+		s.shards[i] = NewRedisStore(shard.Client, opts...)
+		s.weights[i] = float64(max(shard.Weight, 1))
+	}
+	return s
+}
+
+// shardIndex picks the shard with the highest weighted score for key.
+func (s *ShardedStore) shardIndex(key string) int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	kh := h.Sum64()
+
+	best, bestScore := 0, math.Inf(-1)
+	for i, w := range s.weights {
+		// Map the mixed hash to (0, 1) and score it as -w/ln(u), which picks
+		// each shard with probability proportional to its weight.
+		u := (float64(mix64(kh^(uint64(i)*0x9e3779b97f4a7c15))>>11) + 0.5) / (1 << 53)
+		if score := -w / math.Log(u); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+func (s *ShardedStore) shard(key string) *RedisStore {
+	return s.shards[s.shardIndex(key)]
+}
+
+// mix64 is the splitmix64 finalizer, spreading similar inputs apart.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func (s *ShardedStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	return s.shard(key).Increment(ctx, key, ttl)
+}
+
+func (s *ShardedStore) IncrementBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, error) {
+	return s.shard(key).IncrementBy(ctx, key, n, ttl)
+}
+
+func (s *ShardedStore) IncrementWithResult(ctx context.Context, key string, n int64, limit int, ttl time.Duration) (limiter.StoreDecision, error) {
+	return s.shard(key).IncrementWithResult(ctx, key, n, limit, ttl)
+}
+
+func (s *ShardedStore) AppendLog(ctx context.Context, key string, n, limit int64, window time.Duration) (int64, time.Time, bool, error) {
+	return s.shard(key).AppendLog(ctx, key, n, limit, window)
+}
+
+func (s *ShardedStore) CountLog(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	return s.shard(key).CountLog(ctx, key, window)
+}
+
+func (s *ShardedStore) TakeTokens(ctx context.Context, key string, n, capacity int64, rate float64, ttl time.Duration) (float64, bool, error) {
+	return s.shard(key).TakeTokens(ctx, key, n, capacity, rate, ttl)
+}
+
+func (s *ShardedStore) PeekTokens(ctx context.Context, key string, capacity int64, rate float64) (float64, error) {
+	return s.shard(key).PeekTokens(ctx, key, capacity, rate)
+}
+
+func (s *ShardedStore) ArriveGCRA(ctx context.Context, key string, n int64, interval, tolerance time.Duration) (time.Time, bool, error) {
+	return s.shard(key).ArriveGCRA(ctx, key, n, interval, tolerance)
+}
+
+func (s *ShardedStore) GetTAT(ctx context.Context, key string) (time.Time, error) {
+	return s.shard(key).GetTAT(ctx, key)
+}
+
+func (s *ShardedStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	return s.shard(key).Get(ctx, key)
+}
+
+func (s *ShardedStore) Delete(ctx context.Context, key string) error {
+	return s.shard(key).Delete(ctx, key)
+}
+
+func (s *ShardedStore) ResetKey(ctx context.Context, key string, ttl time.Duration) error {
+	return s.shard(key).ResetKey(ctx, key, ttl)
+}
+
+func (s *ShardedStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return s.shard(key).SetIfAbsent(ctx, key, count, ttl)
+}
+
+func (s *ShardedStore) Restore(ctx context.Context, key string, count int64, expiry time.Time) (bool, error) {
+	return s.shard(key).Restore(ctx, key, count, expiry)
+}
+
+// Keys lists matching keys on every shard.
+func (s *ShardedStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for _, shard := range s.shards {
+		shardKeys, err := shard.Keys(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, shardKeys...)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// GetMany reads each shard's keys in one pipeline per shard.
+func (s *ShardedStore) GetMany(ctx context.Context, keys []string) ([]limiter.StoreEntry, error) {
+	byShard := make(map[int][]int)
+	for i, key := range keys {
+		idx := s.shardIndex(key)
+		byShard[idx] = append(byShard[idx], i)
+	}
+
+	entries := make([]limiter.StoreEntry, len(keys))
+	for idx, positions := range byShard {
+		shardKeys := make([]string, len(positions))
+		for j, pos := range positions {
+			shardKeys[j] = keys[pos]
+		}
+		got, err := s.shards[idx].GetMany(ctx, shardKeys)
+		if err != nil {
+			return nil, err
+		}
+		for j, pos := range positions {
+			entries[pos] = got[j]
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// shardHook extends scriptHook with pipelined GET/PTTL reads of its counts,
+// standing in for one shard.
+type shardHook struct {
+	*scriptHook
+}
+
+func (h shardHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.roundTrips++
+		var firstErr error
+		for _, cmd := range cmds {
+			key := cmd.Args()[1].(string)
+			count, ok := h.counts[key]
+			switch c := cmd.(type) {
+			case *redis.StringCmd:
+				if !ok {
+					c.SetErr(redis.Nil)
+				} else {
+					c.SetVal(strconv.FormatInt(count, 10))
+				}
+			case *redis.DurationCmd:
+				c.SetVal(time.Minute)
+			default:
+				return fmt.Errorf("unexpected command %s", cmd.Name())
+			}
+			if err := cmd.Err(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+}
+
+func newShardedTestStore(weights ...int) (*ShardedStore, []*scriptHook) {
+	shards := make([]Shard, len(weights))
+	hooks := make([]*scriptHook, len(weights))
+	for i, w := range weights {
+		hooks[i] = &scriptHook{counts: map[string]int64{}}
+		client := redis.NewClient(&redis.Options{Addr: "redis.invalid:6379"})
+		client.AddHook(shardHook{hooks[i]})
+		shards[i] = Shard{Client: client, Weight: w}
+	}
+	return NewShardedStore(shards), hooks
+}
+
+func TestShardedStoreRoutesKeyToOneShard(t *testing.T) {
+	store, hooks := newShardedTestStore(1, 1, 1)
+
+	for _, key := range []string{"rate:c1", "rate:c2", "rate:c3", "rate:c4"} {
+		for i := 0; i < 5; i++ {
+			if _, err := store.IncrementWithResult(context.Background(), key, 1, 10, time.Minute); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		holders := 0
+		for i, h := range hooks {
+			if count := h.counts[key]; count != 0 {
+				holders++
+				if count != 5 || i != store.shardIndex(key) {
+					t.Errorf("%s: expected 5 hits on shard %d, got %d on shard %d", key, store.shardIndex(key), count, i)
+				}
+			}
+		}
+		if holders != 1 {
+			t.Errorf("%s: expected one shard to hold the counter, got %d", key, holders)
+		}
+	}
+}
+
+func TestShardedStoreDeterministic(t *testing.T) {
+	a, _ := newShardedTestStore(1, 2, 1)
+	b, _ := newShardedTestStore(1, 2, 1)
+
+	for i := 0; i < 1000; i++ {
+		key := "rate:client-" + strconv.Itoa(i)
+		if a.shardIndex(key) != b.shardIndex(key) {
+			t.Fatalf("%s: stores disagree on shard, %d vs %d", key, a.shardIndex(key), b.shardIndex(key))
+		}
+	}
+}
+
+func TestShardedStoreWeights(t *testing.T) {
+	store, _ := newShardedTestStore(1, 1, 2)
+
+	const keys = 20000
+	hits := make([]int, 3)
+	for i := 0; i < keys; i++ {
+		hits[store.shardIndex("rate:client-"+strconv.Itoa(i))]++
+	}
+
+	for i, want := range []float64{0.25, 0.25, 0.5} {
+		if got := float64(hits[i]) / keys; got < want-0.03 || got > want+0.03 {
+			t.Errorf("shard %d: expected about %.2f of keys, got %.3f", i, want, got)
+		}
+	}
+}
+
+func TestShardedStoreAppendOnlyMovesToNewShard(t *testing.T) {
+	before, _ := newShardedTestStore(1, 1, 1)
+	after, _ := newShardedTestStore(1, 1, 1, 1)
+
+	for i := 0; i < 1000; i++ {
+		key := "rate:client-" + strconv.Itoa(i)
+		if idx := after.shardIndex(key); idx != 3 && idx != before.shardIndex(key) {
+			t.Fatalf("%s: moved from shard %d to existing shard %d", key, before.shardIndex(key), idx)
+		}
+	}
+}
+
+func TestShardedStoreGetMany(t *testing.T) {
+	store, _ := newShardedTestStore(1, 1, 1)
+
+	keys := make([]string, 12)
+	for i := range keys {
+		keys[i] = "rate:client-" + strconv.Itoa(i)
+		for j := 0; j <= i; j++ {
+			store.IncrementWithResult(context.Background(), keys[i], 1, 100, time.Minute)
+		}
+	}
+
+	entries, err := store.GetMany(context.Background(), append(keys, "rate:missing"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range keys {
+		if entries[i].Count != int64(i+1) {
+			t.Errorf("%s: expected count %d in input order, got %d", keys[i], i+1, entries[i].Count)
+		}
+	}
+	if last := entries[len(keys)]; last.Count != 0 || !last.Expiry.IsZero() {
+		t.Errorf("expected a zero entry for the missing key, got %+v", last)
+	}
+}
@@ -0,0 +1,168 @@
+package writebehind
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+)
+
+const defaultBufferSize = 1024
+
+type op struct {
+	key string
+	n   int64
+	ttl time.Duration
+	// init mirrors a SetIfAbsent of n rather than an increment.
+	init bool
+}
+
+// WriteBehindStore decides from a fast store and mirrors every increment to a
+// durable store in the background, e.g. for billing. The durable copy is
+// eventually consistent: increments are dropped, and counted, when the buffer
+// is full, and durable errors are counted but not retried.
+type WriteBehindStore struct {
+	fast    limiter.Store
+	durable limiter.Store
+	ops     chan op
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	dropped atomic.Int64
+	failed  atomic.Int64
+}
+
+type Option func(*WriteBehindStore)
+
+// WithBufferSize sets how many increments may wait for the durable store
+// before new ones are dropped.
+func WithBufferSize(n int) Option {
+	return func(s *WriteBehindStore) {
+		s.ops = make(chan op, n)
+	}
+}
+
+func NewWriteBehindStore(fast, durable limiter.Store, opts ...Option) *WriteBehindStore {
+	s := &WriteBehindStore{
+		fast:    fast,
+		durable: durable,
+		ops:     make(chan op, defaultBufferSize),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	go s.run()
+
+	return s
+}
+
+// Increment adds one to key in the fast store under ctx. The mirrored write
+// to the durable store happens later and is not bound to ctx; the same holds
+// for IncrementBy and SetIfAbsent.
+func (s *WriteBehindStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	count, expiry, err := s.fast.Increment(ctx, key, ttl)
+	if err == nil {
+		s.enqueue(op{key: key, n: 1, ttl: ttl})
+	}
+	return count, expiry, err
+}
+
+func (s *WriteBehindStore) IncrementBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, error) {
+	count, expiry, err := incrementBy(ctx, s.fast, key, n, ttl)
+	if err == nil {
+		s.enqueue(op{key: key, n: n, ttl: ttl})
+	}
+	return count, expiry, err
+}
+
+// SetIfAbsent creates key in the fast store under ctx and, when it did,
+// mirrors the creation to the durable store.
+func (s *WriteBehindStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	created, err := s.fast.SetIfAbsent(ctx, key, count, ttl)
+	if err == nil && created {
+		s.enqueue(op{key: key, n: count, ttl: ttl, init: true})
+	}
+	return created, err
+}
+
+// Get reads from the fast store only.
+func (s *WriteBehindStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	return s.fast.Get(ctx, key)
+}
+
+// Dropped returns how many increments were not mirrored because the buffer
+// was full or the store was closed.
+func (s *WriteBehindStore) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Failed returns how many mirrored increments the durable store rejected.
+func (s *WriteBehindStore) Failed() int64 {
+	return s.failed.Load()
+}
+
+// Close stops accepting increments and waits until the buffered ones reach
+// the durable store. It is safe to call more than once.
+func (s *WriteBehindStore) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.ops)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+func (s *WriteBehindStore) enqueue(o op) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		s.dropped.Add(1)
+		return
+	}
+	select {
+	case s.ops <- o:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *WriteBehindStore) run() {
+	defer close(s.done)
+	ctx := context.Background()
+	for o := range s.ops {
+		var err error
+		if o.init {
+			_, err = s.durable.SetIfAbsent(ctx, o.key, o.n, o.ttl)
+		} else {
+			_, _, err = incrementBy(ctx, s.durable, o.key, o.n, o.ttl)
+		}
+		if err != nil {
+			s.failed.Add(1)
+		}
+	}
+}
+
+func incrementBy(ctx context.Context, store limiter.Store, key string, n int64, ttl time.Duration) (int64, time.Time, error) {
+	if cs, ok := store.(limiter.CostStore); ok {
+		return cs.IncrementBy(ctx, key, n, ttl)
+	}
+
+	var (
+		count  int64
+		expiry time.Time
+		err    error
+	)
+	for i := int64(0); i < n; i++ {
+		count, expiry, err = store.Increment(ctx, key, ttl)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+	return count, expiry, nil
+}
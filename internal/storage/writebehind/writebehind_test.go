@@ -0,0 +1,145 @@
+package writebehind
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+// gatedStore blocks every increment until gate is closed.
+type gatedStore struct {
+	*memory.MemoryStore
+	gate chan struct{}
+}
+
+func (g *gatedStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	<-g.gate
+	return g.MemoryStore.Increment(ctx, key, ttl)
+}
+
+func (g *gatedStore) IncrementBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, time.Time, error) {
+	<-g.gate
+	return g.MemoryStore.IncrementBy(ctx, key, n, ttl)
+}
+
+type failingStore struct{}
+
+func (failingStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, time.Time, error) {
+	return 0, time.Time{}, errors.New("durable store down")
+}
+
+func (failingStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	return 0, time.Time{}, errors.New("durable store down")
+}
+
+func (failingStore) SetIfAbsent(ctx context.Context, key string, count int64, ttl time.Duration) (bool, error) {
+	return false, errors.New("durable store down")
+}
+
+func TestDecisionsFromFastStore(t *testing.T) {
+	durable := &gatedStore{MemoryStore: memory.NewMemoryStore(), gate: make(chan struct{})}
+	s := NewWriteBehindStore(memory.NewMemoryStore(), durable)
+
+	cfgs := map[string]config.ClientConfig{"c1": {Limit: 3, Window: time.Minute}}
+	l := limiter.NewLimiter(s, cfgs)
+	for i := 0; i < 3; i++ {
+		if ok, _, _, err := l.Allow("c1"); !ok || err != nil {
+			t.Fatalf("request %d: expected allowed while durable store is blocked, got %v %v", i+1, ok, err)
+		}
+	}
+	if ok, _, _, _ := l.Allow("c1"); ok {
+		t.Fatal("expected fast store to enforce the limit")
+	}
+	if count, _, _ := durable.Get(context.Background(), "rate:c1"); count != 0 {
+		t.Fatalf("expected durable store not yet written, got %d", count)
+	}
+
+	close(durable.gate)
+	s.Close()
+
+	if count, _, _ := durable.Get(context.Background(), "rate:c1"); count != 4 {
+		t.Fatalf("expected durable store to reflect all increments after Close, got %d", count)
+	}
+	if s.Dropped() != 0 || s.Failed() != 0 {
+		t.Fatalf("expected nothing dropped or failed, got %d %d", s.Dropped(), s.Failed())
+	}
+}
+
+func TestDurableEventuallyConsistent(t *testing.T) {
+	durable := memory.NewMemoryStore()
+	s := NewWriteBehindStore(memory.NewMemoryStore(), durable)
+	defer s.Close()
+
+	s.Increment(context.Background(), "k", time.Minute)
+	s.IncrementBy(context.Background(), "k", 4, time.Minute)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if count, _, _ := durable.Get(context.Background(), "k"); count == 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected durable store to catch up")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSetIfAbsentMirrored(t *testing.T) {
+	durable := memory.NewMemoryStore()
+	s := NewWriteBehindStore(memory.NewMemoryStore(), durable)
+
+	if created, err := s.SetIfAbsent(context.Background(), "k", 3, time.Minute); !created || err != nil {
+		t.Fatalf("expected the key created, got %v %v", created, err)
+	}
+	if created, err := s.SetIfAbsent(context.Background(), "k", 9, time.Minute); created || err != nil {
+		t.Fatalf("expected the existing key kept, got %v %v", created, err)
+	}
+	s.Close()
+
+	if count, _, _ := durable.Get(context.Background(), "k"); count != 3 {
+		t.Fatalf("expected only the creation mirrored, got %d", count)
+	}
+}
+
+func TestBackpressureDrops(t *testing.T) {
+	durable := &gatedStore{MemoryStore: memory.NewMemoryStore(), gate: make(chan struct{})}
+	s := NewWriteBehindStore(memory.NewMemoryStore(), durable, WithBufferSize(2))
+
+	const total = 10
+	for i := 0; i < total; i++ {
+		if count, _, err := s.Increment(context.Background(), "k", time.Minute); err != nil || count != int64(i+1) {
+			t.Fatalf("increment %d: expected fast count %d, got %d %v", i+1, i+1, count, err)
+		}
+	}
+
+	close(durable.gate)
+	s.Close()
+
+	mirrored, _, _ := durable.Get(context.Background(), "k")
+	if s.Dropped() == 0 || mirrored+s.Dropped() != total {
+		t.Fatalf("expected mirrored (%d) plus dropped (%d) to equal %d", mirrored, s.Dropped(), total)
+	}
+}
+
+func TestCloseDrainsAndDropsLater(t *testing.T) {
+	s := NewWriteBehindStore(memory.NewMemoryStore(), failingStore{})
+	s.Increment(context.Background(), "k", time.Minute)
+	s.Close()
+	s.Close()
+
+	if s.Failed() != 1 {
+		t.Fatalf("expected durable failure counted, got %d", s.Failed())
+	}
+	if count, _, err := s.Increment(context.Background(), "k", time.Minute); err != nil || count != 2 {
+		t.Fatalf("expected fast store to keep serving after Close, got %d %v", count, err)
+	}
+	if s.Dropped() != 1 {
+		t.Fatalf("expected increment after Close dropped, got %d", s.Dropped())
+	}
+}
@@ -2,17 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/Dzaakk/rate-limiter/config"
 	"github.com/Dzaakk/rate-limiter/internal/handler"
+	"github.com/Dzaakk/rate-limiter/internal/kvconfig"
 	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/metrics"
 	"github.com/Dzaakk/rate-limiter/internal/middleware"
 	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
 	"github.com/Dzaakk/rate-limiter/internal/storage/redis"
@@ -24,16 +31,155 @@ func main() {
 		Level: slog.LevelInfo,
 	}))
 
-	store := initStorage(logger)
+	warnOnly := os.Getenv("CONFIG_VALIDATION") == "warn"
+	if err := validateClients(logger, config.Clients, config.DefaultConfig, warnOnly); err != nil {
+		log.Fatal(err)
+	}
+
+	// Metrics are on unless METRICS_ENABLED=false, e.g. where they are
+	// scraped another way.
+	var collector *metrics.Collector
+	if os.Getenv("METRICS_ENABLED") != "false" {
+		collector = metrics.NewCollector()
+	}
 
-	l := limiter.NewLimiter(store, config.Clients)
+	store := initStorage(logger, collector)
 
-	rateLimitMW := middleware.NewRateLimitMiddleware(l, logger)
+	opts := []limiter.Option{
+		limiter.WithConfigs(config.Clients),
+		limiter.WithLogger(logger),
+	}
+	if collector != nil {
+		opts = append(opts, limiter.WithMetrics(collector))
+	}
+
+	ns := os.Getenv("RATE_LIMIT_NAMESPACE")
+	if ns != "" {
+		logger.Info("namespacing rate limit keys", "namespace", ns)
+		opts = append(opts, limiter.WithNamespace(ns))
+	}
+
+	if threshold, _ := strconv.Atoi(os.Getenv("KEY_GROWTH_THRESHOLD")); threshold > 0 {
+		interval, err := time.ParseDuration(os.Getenv("KEY_GROWTH_INTERVAL"))
+		if err != nil || interval <= 0 {
+			interval = time.Minute
+		}
+		logger.Info("alerting on key growth", "threshold", threshold, "interval", interval)
+		opts = append(opts, limiter.WithKeyGrowthAlert(threshold, interval))
+	}
+
+	if name := os.Getenv("RATE_LIMIT_ALGORITHM"); name != "" {
+		algo, err := limiter.ParseAlgorithm(name)
+		if err != nil {
+			log.Fatalf("RATE_LIMIT_ALGORITHM: %v", err)
+		}
+		logger.Info("counting client budgets", "algorithm", algo)
+		opts = append(opts, limiter.WithAlgorithm(algo))
+	}
+
+	if spec := os.Getenv("RATE_LIMIT_KEY_DIMENSIONS"); spec != "" {
+		dims, err := limiter.ParseKeyDimensions(spec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		logger.Info("composing keys from dimensions", "dimensions", spec)
+		opts = append(opts, limiter.WithKeyDimensions(dims...))
+	}
+
+	historySize, _ := strconv.Atoi(os.Getenv("HISTORY_SIZE"))
+	if historySize > 0 {
+		logger.Info("decision history enabled", "size", historySize)
+		opts = append(opts, limiter.WithHistory(historySize))
+	}
+
+	var sinks []limiter.EventSink
+	if os.Getenv("DECISION_LOG") == "true" {
+		sinks = append(sinks, limiter.LogSink{Logger: logger})
+	}
+	if len(sinks) > 0 {
+		opts = append(opts, limiter.WithEventSink(limiter.NewMultiSink(sinks...)))
+	}
+
+	l := limiter.New(store, opts...)
+
+	if consulAddr := os.Getenv("CONSUL_ADDR"); consulAddr != "" {
+		prefix := os.Getenv("CONSUL_KV_PREFIX")
+		if prefix == "" {
+			prefix = "ratelimit/"
+		}
+		logger.Info("watching client config in consul", "addr", consulAddr, "prefix", prefix)
+		kv := &kvconfig.ConsulClient{Addr: consulAddr, Token: os.Getenv("CONSUL_TOKEN")}
+		provider := kvconfig.NewProvider(kv, prefix, l, logger)
+		defer provider.Close()
+	}
+
+	mwOpts := []middleware.Option{middleware.WithRequestID("")}
+	if token := os.Getenv("RATE_LIMIT_BYPASS_TOKEN"); token != "" {
+		mwOpts = append(mwOpts, middleware.WithBypassToken(token))
+	}
+	if docsURL := os.Getenv("RATE_LIMIT_DOCS_URL"); docsURL != "" {
+		mwOpts = append(mwOpts, middleware.WithDocsLink(docsURL))
+	}
+	if text := os.Getenv("RATE_LIMIT_RESPONSE_TEMPLATE"); text != "" {
+		tmpl, err := middleware.NewResponseTemplate(text, os.Getenv("RATE_LIMIT_RESPONSE_CONTENT_TYPE"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		mwOpts = append(mwOpts, middleware.WithResponseTemplate(tmpl))
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("RATE_LIMIT_NEGATIVE_CACHE")); err == nil && ttl > 0 {
+		logger.Info("caching denials locally", "ttl", ttl)
+		mwOpts = append(mwOpts, middleware.WithNegativeCache(ttl))
+	}
+	if os.Getenv("RATE_LIMIT_CLIENT_KEY") == "ip" {
+		var proxies []*net.IPNet
+		for _, cidr := range strings.Split(os.Getenv("RATE_LIMIT_TRUSTED_PROXIES"), ",") {
+			if cidr = strings.TrimSpace(cidr); cidr == "" {
+				continue
+			}
+			_, n, err := net.ParseCIDR(cidr)
+			if err != nil {
+				log.Fatalf("invalid RATE_LIMIT_TRUSTED_PROXIES entry %q: %v", cidr, err)
+			}
+			proxies = append(proxies, n)
+		}
+		mwOpts = append(mwOpts, middleware.WithKeyExtractor(middleware.ClientIPKeyExtractor(proxies)))
+	}
+	if v := initJWTVerifier(logger); v != nil {
+		mwOpts = append(mwOpts, middleware.WithJWT(v))
+	}
+	var shadowMetrics middleware.ShadowMetrics
+	if collector != nil {
+		mwOpts = append(mwOpts, middleware.WithMetrics(collector))
+		shadowMetrics = collector
+	}
+	if shadow := initShadowLimiter(store, ns, logger); shadow != nil {
+		mwOpts = append(mwOpts, middleware.WithShadowLimiter(shadow, shadowMetrics))
+	}
+
+	rateLimitMW := middleware.NewRateLimitMiddleware(l, logger, mwOpts...)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/hello", rateLimitMW.Handler(handler.HelloHandler))
 	mux.HandleFunc("/api/status", handler.StatusHandler)
 
+	if collector != nil {
+		collector.PublishExpvar("ratelimiter", l, store)
+		mux.Handle("/metrics", collector)
+	}
+
+	if adminToken := os.Getenv("ADMIN_TOKEN"); adminToken != "" {
+		if historySize > 0 {
+			mux.HandleFunc("/admin/history", handler.HistoryHandler(l, adminToken))
+		}
+		mux.HandleFunc("/admin/limits", handler.SetLimitHandler(l, adminToken))
+		mux.HandleFunc("/admin/config", handler.ConfigHandler(l, adminToken))
+		mux.HandleFunc("/admin/simulate", handler.SimulateHandler(adminToken))
+		if collector != nil {
+			mux.HandleFunc("/debug/vars", handler.DebugVarsHandler(adminToken))
+		}
+	}
+
 	httpServer := &http.Server{
 		Addr:         ":8080",
 		Handler:      mux,
@@ -50,11 +196,26 @@ func main() {
 		}
 	}()
 
+	// SIGUSR2 logs a snapshot of limiter state. Dumps run on their own
+	// goroutine so requests never wait on the store scan.
+	topN, _ := strconv.Atoi(os.Getenv("SNAPSHOT_TOP_N"))
+	if topN <= 0 {
+		topN = 10
+	}
+	dump := make(chan os.Signal, 1)
+	signal.Notify(dump, syscall.SIGUSR2)
+	go func() {
+		for range dump {
+			logSnapshot(logger, l, topN)
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	logger.Info("shutting down server...")
+	rateLimitMW.Drain(10 * time.Second)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -67,7 +228,95 @@ func main() {
 	logger.Info("server stopped")
 }
 
-func initStorage(logger *slog.Logger) limiter.Store {
+// validateClients logs every configured limit and checks them before any
+// traffic is served. Invalid entries are returned as an error, or only logged
+// when warnOnly is set.
+func validateClients(logger *slog.Logger, clients map[string]config.ClientConfig, def config.ClientConfig, warnOnly bool) error {
+	logger.Info("rate limit config", "clients", len(clients), "default", def.String())
+	for name, cfg := range clients {
+		logger.Info("client rate limit", "client", name, "limit", cfg.String())
+	}
+
+	err := config.Validate(clients)
+	if defErr := def.Validate(); defErr != nil {
+		err = errors.Join(fmt.Errorf("default config: %w", defErr), err)
+	}
+	if err == nil {
+		return nil
+	}
+	if warnOnly {
+		logger.Warn("invalid rate limit config", "error", err)
+		return nil
+	}
+	return fmt.Errorf("invalid rate limit config: %w", err)
+}
+
+// logSnapshot logs the busiest topN keys, the active key count and the
+// degraded state at Info.
+func logSnapshot(logger *slog.Logger, l *limiter.Limiter, topN int) {
+	snap, err := l.Snapshot(topN)
+	if err != nil {
+		logger.Error("rate limiter snapshot failed", "error", err)
+		return
+	}
+	logger.Info("rate limiter snapshot",
+		"active_keys", snap.ActiveKeys,
+		"degraded", snap.Degraded,
+		"top", snap.Top,
+	)
+}
+
+// initJWTVerifier keys requests by JWT claims when JWT_HMAC_SECRET or
+// JWT_JWKS_URL is set.
+func initJWTVerifier(logger *slog.Logger) *middleware.JWTVerifier {
+	var keys middleware.JWTKeyFunc
+	if secret := os.Getenv("JWT_HMAC_SECRET"); secret != "" {
+		keys = middleware.StaticJWTKey([]byte(secret))
+	} else if url := os.Getenv("JWT_JWKS_URL"); url != "" {
+		keys = middleware.JWKSKeys(url, &http.Client{Timeout: 5 * time.Second}, time.Hour)
+	} else {
+		return nil
+	}
+
+	tierClaim := os.Getenv("JWT_TIER_CLAIM")
+	if tierClaim == "" {
+		tierClaim = "tier"
+	}
+	cacheTTL, err := time.ParseDuration(os.Getenv("JWT_CACHE_TTL"))
+	if err != nil || cacheTTL <= 0 {
+		cacheTTL = time.Minute
+	}
+	logger.Info("limiting by JWT claims", "tier_claim", tierClaim, "cache_ttl", cacheTTL)
+	return middleware.NewJWTVerifier(keys, tierClaim, cacheTTL)
+}
+
+// initShadowLimiter builds a limiter applying the candidate SHADOW_LIMIT per
+// SHADOW_WINDOW to every client, counting under its own namespace so it never
+// touches live counters. It returns nil when no candidate is configured.
+func initShadowLimiter(store limiter.Store, ns string, logger *slog.Logger) *limiter.Limiter {
+	limit, _ := strconv.Atoi(os.Getenv("SHADOW_LIMIT"))
+	if limit <= 0 {
+		return nil
+	}
+	window, err := time.ParseDuration(os.Getenv("SHADOW_WINDOW"))
+	if err != nil || window <= 0 {
+		logger.Warn("invalid SHADOW_WINDOW, shadow limiter disabled", "window", os.Getenv("SHADOW_WINDOW"))
+		return nil
+	}
+
+	shadowNS := "shadow"
+	if ns != "" {
+		shadowNS = ns + ":shadow"
+	}
+	logger.Info("shadow limiter enabled", "limit", limit, "window", window, "namespace", shadowNS)
+	return limiter.New(store,
+		limiter.WithDefault(config.ClientConfig{Limit: limit, Window: window}),
+		limiter.WithNamespace(shadowNS),
+		limiter.WithLogger(logger),
+	)
+}
+
+func initStorage(logger *slog.Logger, collector *metrics.Collector) limiter.Store {
 	storageType := os.Getenv("STORAGE_TYPE")
 	if storageType == "" {
 		storageType = "memory"
@@ -78,29 +327,68 @@ func initStorage(logger *slog.Logger) limiter.Store {
 		return initRedisStorage(logger)
 	default:
 		logger.Info("using in-memory storage")
+		if collector != nil {
+			return memory.NewMemoryStore(memory.WithMetrics(collector))
+		}
 		return memory.NewMemoryStore()
 	}
 }
 
 func initRedisStorage(logger *slog.Logger) limiter.Store {
+	var opts []redis.Option
+	switch format := os.Getenv("REDIS_ENTRY_FORMAT"); format {
+	case "json":
+		opts = append(opts, redis.WithSerializer(redis.JSONSerializer{}))
+	case "binary":
+		opts = append(opts, redis.WithSerializer(redis.BinarySerializer{}))
+	case "", "counter":
+	default:
+		logger.Warn("unknown REDIS_ENTRY_FORMAT, using counter", "format", format)
+	}
+	if os.Getenv("REDIS_SERVER_TIME") == "true" {
+		logger.Info("using Redis server time for rate limit windows")
+		opts = append(opts, redis.WithServerTime())
+	}
+
+	if shardList := os.Getenv("REDIS_SHARDS"); shardList != "" {
+		var shards []redis.Shard
+		for _, spec := range strings.Split(shardList, ",") {
+			addr, weightStr, _ := strings.Cut(strings.TrimSpace(spec), "=")
+			weight := 1
+			if weightStr != "" {
+				w, err := strconv.Atoi(weightStr)
+				if err != nil || w < 1 {
+					log.Fatalf("invalid weight in REDIS_SHARDS entry %q", spec)
+				}
+				weight = w
+			}
+			shards = append(shards, redis.Shard{Client: connectRedis(logger, addr), Weight: weight})
+		}
+		logger.Info("sharding rate limit keys across Redis", "shards", len(shards))
+		return redis.NewShardedStore(shards, opts...)
+	}
+
 	redisAddr := os.Getenv("REDIS_ADDR")
 	if redisAddr == "" {
 		redisAddr = "localhost:6379"
 	}
+	return redis.NewRedisStore(connectRedis(logger, redisAddr), opts...)
+}
 
-	logger.Info("connecting to Redis", "addr", redisAddr)
+func connectRedis(logger *slog.Logger, addr string) *goredis.Client {
+	logger.Info("connecting to Redis", "addr", addr)
 	rdb := goredis.NewClient(&goredis.Options{
-		Addr: redisAddr,
+		Addr: addr,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
-		logger.Error("failed to connect to Redis", "error", err)
+		logger.Error("failed to connect to Redis", "error", err, "addr", addr)
 		log.Fatal(err)
 	}
 
-	logger.Info("successfully connected to Redis")
-	return redis.NewRedisStore(rdb)
+	logger.Info("successfully connected to Redis", "addr", addr)
+	return rdb
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dzaakk/rate-limiter/config"
+	"github.com/Dzaakk/rate-limiter/internal/limiter"
+	"github.com/Dzaakk/rate-limiter/internal/storage/memory"
+)
+
+func TestValidateClients(t *testing.T) {
+	clients := map[string]config.ClientConfig{
+		"good": {Limit: 5, Window: time.Minute},
+		"bad":  {Limit: 5},
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := validateClients(slog.New(slog.NewTextHandler(&buf, nil)), clients, config.DefaultConfig, false)
+		if err == nil || !strings.Contains(err.Error(), `client "bad"`) {
+			t.Fatalf("expected an error naming the bad client, got %v", err)
+		}
+		if !strings.Contains(buf.String(), "client=good") || !strings.Contains(buf.String(), "client=bad") {
+			t.Errorf("expected a summary of every client, got %s", buf.String())
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := validateClients(slog.New(slog.NewTextHandler(&buf, nil)), clients, config.DefaultConfig, true); err != nil {
+			t.Fatalf("expected warn mode to continue, got %v", err)
+		}
+		if !strings.Contains(buf.String(), "level=WARN") || !strings.Contains(buf.String(), `client \"bad\"`) {
+			t.Errorf("expected a warning naming the bad client, got %s", buf.String())
+		}
+	})
+
+	t.Run("invalid default", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := validateClients(slog.New(slog.NewTextHandler(&buf, nil)), nil, config.ClientConfig{Limit: 5}, false)
+		if err == nil || !strings.Contains(err.Error(), "default config") {
+			t.Fatalf("expected the default config to be checked, got %v", err)
+		}
+	})
+}
+
+func TestLogSnapshot(t *testing.T) {
+	l := limiter.New(memory.NewMemoryStore())
+	for _, client := range []string{"a", "a", "a", "b"} {
+		l.AllowResult(client)
+	}
+
+	var buf bytes.Buffer
+	logSnapshot(slog.New(slog.NewJSONHandler(&buf, nil)), l, 1)
+
+	var entry struct {
+		Level      string `json:"level"`
+		Msg        string `json:"msg"`
+		ActiveKeys int    `json:"active_keys"`
+		Degraded   bool   `json:"degraded"`
+		Top        []struct {
+			Key   string `json:"key"`
+			Count int64  `json:"count"`
+		} `json:"top"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected one JSON log line, got %q: %v", buf.String(), err)
+	}
+	if entry.Level != "INFO" || entry.Msg != "rate limiter snapshot" || entry.ActiveKeys != 2 || entry.Degraded {
+		t.Fatalf("unexpected snapshot entry %+v", entry)
+	}
+	if len(entry.Top) != 1 || entry.Top[0].Key != "rate:a" || entry.Top[0].Count != 3 {
+		t.Fatalf("expected the busiest key only, got %+v", entry.Top)
+	}
+}